@@ -11,20 +11,34 @@ import (
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"github.com/neilberkman/ccrider/internal/core/config"
 	"github.com/neilberkman/ccrider/internal/core/db"
 	"github.com/neilberkman/ccrider/internal/core/importer"
+	"github.com/neilberkman/ccrider/internal/core/llm"
 	"github.com/neilberkman/ccrider/internal/core/search"
+	"github.com/neilberkman/ccrider/internal/core/watcher"
 )
 
 // SearchSessionsArgs defines arguments for the search_sessions tool
 type SearchSessionsArgs struct {
-	Query            string `json:"query" jsonschema:"description=Search term to match against message content,required"`
+	Query            string `json:"query" jsonschema:"description=Search query (phrases, OR, NOT, prefix* and field filters),required"`
+	Mode             string `json:"mode,omitempty" jsonschema:"description=auto (default), text, code or regex"`
 	Limit            int    `json:"limit,omitempty" jsonschema:"description=Max number of sessions to return (default: 10)"`
+	Offset           int    `json:"offset,omitempty" jsonschema:"description=Number of sessions to skip, for paging (use next_offset from the previous page)"`
 	Project          string `json:"project,omitempty" jsonschema:"description=Filter by project path"`
 	CurrentSessionID string `json:"current_session_id,omitempty" jsonschema:"description=Current session ID to search within (searches only this session)"`
 	ExcludeCurrent   bool   `json:"exclude_current,omitempty" jsonschema:"description=Exclude current session from results (searches only other sessions)"`
-	AfterDate        string `json:"after_date,omitempty" jsonschema:"description=Only sessions updated after this date (ISO 8601 format, e.g. 2025-01-01)"`
-	BeforeDate       string `json:"before_date,omitempty" jsonschema:"description=Only sessions updated before this date (ISO 8601 format)"`
+	AfterDate        string `json:"after_date,omitempty" jsonschema:"description=Only matches on or after this date (ISO 8601 format, e.g. 2025-01-01)"`
+	BeforeDate       string `json:"before_date,omitempty" jsonschema:"description=Only matches before this date (ISO 8601 format)"`
+}
+
+// SemanticSearchArgs defines arguments for the semantic_search tool
+type SemanticSearchArgs struct {
+	Query   string `json:"query" jsonschema:"description=What to look for, in plain words (field filters apply),required"`
+	Limit   int    `json:"limit,omitempty" jsonschema:"description=Max number of sessions to return (default: 10)"`
+	Offset  int    `json:"offset,omitempty" jsonschema:"description=Number of sessions to skip, for paging"`
+	Project string `json:"project,omitempty" jsonschema:"description=Filter by project path"`
+	Hybrid  *bool  `json:"hybrid,omitempty" jsonschema:"description=Blend in keyword relevance (default: true)"`
 }
 
 // GetSessionDetailArgs defines arguments for the get_session_detail tool
@@ -52,6 +66,7 @@ type SessionMatch struct {
 // MatchSnippet represents a message match within a session
 type MatchSnippet struct {
 	MessageType string `json:"message_type"`
+	Tool        string `json:"tool,omitempty"` // Set when the match is in a tool call's input or output
 	Snippet     string `json:"snippet"`
 	Sequence    int    `json:"sequence"`
 }
@@ -64,9 +79,20 @@ type SessionDetail struct {
 	CreatedAt        string          `json:"created_at"`
 	UpdatedAt        string          `json:"updated_at"`
 	MessageCount     int             `json:"message_count"`
+	Model            string          `json:"model,omitempty"`
+	InputTokens      int             `json:"input_tokens,omitempty"`
+	OutputTokens     int             `json:"output_tokens,omitempty"`
 	FirstMessage     *MessageDetail  `json:"first_message,omitempty"`
 	LastMessage      *MessageDetail  `json:"last_message,omitempty"`
 	MatchingMessages []MessageDetail `json:"matching_messages,omitempty"`
+
+	// Structured LLM summary, when the session has been summarized with one
+	Goal         string   `json:"goal,omitempty"`
+	Outcome      string   `json:"outcome,omitempty"` // done, abandoned or blocked
+	Decisions    []string `json:"decisions,omitempty"`
+	OpenTODOs    []string `json:"open_todos,omitempty"`
+	CommandsRun  []string `json:"commands_run,omitempty"`
+	FilesChanged []string `json:"files_changed,omitempty"`
 }
 
 // MessageDetail represents a single message in a session
@@ -107,12 +133,17 @@ func StartServer(dbPath string) error {
 
 	// Register search_sessions tool
 	searchTool := mcp.NewTool("search_sessions",
-		mcp.WithDescription("Search Claude Code sessions for a query string across all message content. Can search current session only, exclude current session, or search all sessions. Supports date and project filtering."),
+		mcp.WithDescription("Search Claude Code sessions for a query string across all message content and tool call inputs and outputs (commands, compiler errors, test output). Can search current session only, exclude current session, or search all sessions. Supports date and project filtering."),
 		mcp.WithString("query",
 			mcp.Required(),
-			mcp.Description("Search term to match against message content")),
+			mcp.Description(`Search query. Terms are ANDed; supports "exact phrases", OR, NOT or -term, prefix*, parentheses, and field filters project:, branch:, role:user|assistant, tool:Bash, file:path, issue:ENA-1234, model:, after:date, before:date, code:identifier. tool:Bash "kubectl apply" matches only inside Bash calls; tool:Edit file:auth.go alone lists the Edit calls on that file`)),
+		mcp.WithString("mode",
+			mcp.Description("How to match the query: 'text' (natural language, with stemming), 'code' (exact identifiers like handleAuthCallback or user_id), 'regex' (the query is a Go regular expression, e.g. 'ENA-\\d{4}.*rollback'), or 'auto' (default: code when the query looks like an identifier)"),
+			mcp.Enum("auto", "text", "code", "regex")),
 		mcp.WithNumber("limit",
 			mcp.Description("Max number of sessions to return (default: 10)")),
+		mcp.WithNumber("offset",
+			mcp.Description("Number of sessions to skip, for paging. Pass next_offset from the previous response to get the next page.")),
 		mcp.WithString("project",
 			mcp.Description("Filter by project path")),
 		mcp.WithString("current_session_id",
@@ -120,15 +151,32 @@ func StartServer(dbPath string) error {
 		mcp.WithBoolean("exclude_current",
 			mcp.Description("If true, excludes current session from results (searches only other sessions). Requires current_session_id to be set.")),
 		mcp.WithString("after_date",
-			mcp.Description("Only sessions updated after this date (ISO 8601 format, e.g. '2025-01-01' or '2025-01-08T10:00:00Z')")),
+			mcp.Description("Only matches on or after this date (ISO 8601 format, e.g. '2025-01-01' or '2025-01-08T10:00:00Z')")),
 		mcp.WithString("before_date",
-			mcp.Description("Only sessions updated before this date (ISO 8601 format)")),
+			mcp.Description("Only matches before this date (ISO 8601 format)")),
 	)
 	s.AddTool(searchTool, makeSearchSessionsHandler(database))
 
+	// Register semantic_search tool
+	semanticTool := mcp.NewTool("semantic_search",
+		mcp.WithDescription("Search Claude Code sessions by meaning rather than exact words, using embeddings of message text. Finds related discussions that search_sessions would miss because they are worded differently. By default blends in keyword relevance (hybrid)."),
+		mcp.WithString("query",
+			mcp.Required(),
+			mcp.Description("What to look for, in plain words (e.g. 'why the deploy to production failed'). Field filters like project:, role:, after:date work as in search_sessions; boolean operators are ignored.")),
+		mcp.WithNumber("limit",
+			mcp.Description("Max number of sessions to return (default: 10)")),
+		mcp.WithNumber("offset",
+			mcp.Description("Number of sessions to skip, for paging. Pass next_offset from the previous response to get the next page.")),
+		mcp.WithString("project",
+			mcp.Description("Filter by project path")),
+		mcp.WithBoolean("hybrid",
+			mcp.Description("If true (default), rank by both meaning and keyword (bm25) relevance; if false, by meaning only")),
+	)
+	s.AddTool(semanticTool, makeSemanticSearchHandler(database))
+
 	// Register get_session_detail tool
 	detailTool := mcp.NewTool("get_session_detail",
-		mcp.WithDescription("Retrieve session info with first message, last message, and optionally matching messages for a specific Claude Code session"),
+		mcp.WithDescription("Retrieve session info with first message, last message, and optionally matching messages for a specific Claude Code session. Summarized sessions also include their goal, outcome (done/abandoned/blocked), key decisions, open TODOs, commands run and files changed - check open_todos before resuming work"),
 		mcp.WithString("session_id",
 			mcp.Required(),
 			mcp.Description("Session UUID to retrieve")),
@@ -152,6 +200,11 @@ func StartServer(dbPath string) error {
 
 // syncDatabase ensures the database is up-to-date before running tool queries
 func syncDatabase(ctx context.Context, database *db.DB) error {
+	// A running `ccrider watch` already keeps the database current
+	if watcher.IsRunning(database.Path()) {
+		return nil
+	}
+
 	// Get Claude Code projects directory
 	home, err := os.UserHomeDir()
 	if err != nil {
@@ -168,6 +221,11 @@ func syncDatabase(ctx context.Context, database *db.DB) error {
 	return nil
 }
 
+// markdownBold marks matched terms in snippets
+func markdownBold(s string) string {
+	return "**" + s + "**"
+}
+
 func makeSearchSessionsHandler(database *db.DB) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 		// Sync database before running query (fast incremental check)
@@ -187,66 +245,132 @@ func makeSearchSessionsHandler(database *db.DB) func(context.Context, mcp.CallTo
 			limit = 10
 		}
 
+		mode, err := search.ParseMode(args.Mode)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
 		// Convert MCP args to core filters
 		coreFilters := search.SearchFilters{
 			Query:            args.Query,
+			Mode:             mode,
 			ProjectPath:      args.Project,
 			CurrentSessionID: args.CurrentSessionID,
 			ExcludeCurrent:   args.ExcludeCurrent,
 			AfterDate:        args.AfterDate,
 			BeforeDate:       args.BeforeDate,
+			Limit:            limit,
+			Offset:           args.Offset,
+			MatchLimit:       3, // Limit to 3 matches per session for display
 		}
 
 		// Call core search with filters (business logic in core)
-		coreResults, err := search.SearchWithFilters(database, coreFilters)
+		page, err := search.SearchWithFilters(database, coreFilters)
 		if err != nil {
 			return mcp.NewToolResultError(fmt.Sprintf("search failed: %v", err)), nil
 		}
 
-		// Convert core types to MCP types (interface concern - presentation)
-		var results []SessionMatch
-		for _, coreSession := range coreResults {
-			result := SessionMatch{
-				SessionID: coreSession.SessionID,
-				Summary:   coreSession.SessionSummary,
-				Project:   coreSession.ProjectPath,
-				UpdatedAt: coreSession.UpdatedAt,
-				Matches:   []MatchSnippet{},
-			}
-
-			// Limit to 3 matches per session for display (interface concern)
-			matchLimit := 3
-			if len(coreSession.Matches) > matchLimit {
-				coreSession.Matches = coreSession.Matches[:matchLimit]
-			}
+		return searchResult(page), nil
+	}
+}
 
-			for _, match := range coreSession.Matches {
-				result.Matches = append(result.Matches, MatchSnippet{
-					MessageType: "message",
-					Snippet:     match.MessageText,
-					Sequence:    0,
-				})
-			}
+// makeSemanticSearchHandler embeds messages added since the last call, then
+// searches by meaning
+func makeSemanticSearchHandler(database *db.DB) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return func(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if err := syncDatabase(ctx, database); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("sync failed: %v", err)), nil
+		}
 
-			result.MatchCount = len(result.Matches)
-			results = append(results, result)
+		var args SemanticSearchArgs
+		argsBytes, _ := json.Marshal(request.Params.Arguments)
+		if err := json.Unmarshal(argsBytes, &args); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid arguments: %v", err)), nil
+		}
 
-			// Apply pagination limit (interface concern)
-			if len(results) >= limit {
-				break
-			}
+		// Set defaults (interface concern - pagination)
+		limit := args.Limit
+		if limit == 0 {
+			limit = 10
 		}
+		hybrid := args.Hybrid == nil || *args.Hybrid
 
-		// Return results as JSON (interface concern - protocol)
-		resultJSON, err := json.Marshal(map[string]interface{}{
-			"sessions": results,
+		cfg, err := config.Load()
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("failed to load config: %v", err)), nil
+		}
+		embedder, err := llm.NewEmbedder(llm.EmbedderConfig{
+			Provider: cfg.Embeddings.Provider,
+			Model:    cfg.Embeddings.Model,
+			URL:      cfg.Embeddings.URL,
 		})
 		if err != nil {
-			return mcp.NewToolResultError(fmt.Sprintf("failed to marshal results: %v", err)), nil
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if _, err := search.IndexEmbeddings(ctx, database, embedder, nil); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("embedding failed: %v", err)), nil
 		}
 
-		return mcp.NewToolResultText(string(resultJSON)), nil
+		page, err := search.SearchSemantic(ctx, database, embedder, search.SearchFilters{
+			Query:       args.Query,
+			ProjectPath: args.Project,
+			Limit:       limit,
+			Offset:      args.Offset,
+			MatchLimit:  3, // Limit to 3 matches per session for display
+		}, hybrid)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("search failed: %v", err)), nil
+		}
+
+		return searchResult(page), nil
+	}
+}
+
+// searchResult converts a page of core search results to the JSON response
+// shared by the search tools
+func searchResult(page *search.SessionSearchPage) *mcp.CallToolResult {
+	// Convert core types to MCP types (interface concern - presentation)
+	results := []SessionMatch{}
+	for _, coreSession := range page.Sessions {
+		result := SessionMatch{
+			SessionID:  coreSession.SessionID,
+			Summary:    coreSession.SessionSummary,
+			Project:    coreSession.ProjectPath,
+			UpdatedAt:  coreSession.UpdatedAt,
+			MatchCount: coreSession.MatchCount,
+			Matches:    []MatchSnippet{},
+		}
+
+		for _, match := range coreSession.Matches {
+			snippet := MatchSnippet{
+				MessageType: "message",
+				Snippet:     search.Highlight(match.MessageText, markdownBold),
+				Sequence:    0,
+			}
+			if match.ToolName != "" {
+				snippet.MessageType = "tool"
+				snippet.Tool = match.ToolName
+			}
+			result.Matches = append(result.Matches, snippet)
+		}
+
+		results = append(results, result)
+	}
+
+	// Return results as JSON (interface concern - protocol)
+	response := map[string]interface{}{
+		"sessions": results,
+		"total":    page.Total,
 	}
+	if page.NextOffset > 0 {
+		response["next_offset"] = page.NextOffset
+	}
+	resultJSON, err := json.Marshal(response)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to marshal results: %v", err))
+	}
+
+	return mcp.NewToolResultText(string(resultJSON))
 }
 
 func makeGetSessionDetailHandler(database *db.DB) func(context.Context, mcp.CallToolRequest) (*mcp.CallToolResult, error) {
@@ -276,11 +400,31 @@ func makeGetSessionDetailHandler(database *db.DB) func(context.Context, mcp.Call
 			UpdatedAt:    coreDetail.UpdatedAt.Format("2006-01-02 15:04:05"),
 			CreatedAt:    coreDetail.UpdatedAt.Format("2006-01-02 15:04:05"), // Use UpdatedAt as fallback
 			MessageCount: coreDetail.MessageCount,
+			Model:        coreDetail.Model,
+			InputTokens:  coreDetail.Usage.InputTokens,
+			OutputTokens: coreDetail.Usage.OutputTokens,
+		}
+		if d := coreDetail.SummaryDetails; d != nil {
+			session.Goal = d.Goal
+			session.Outcome = d.Outcome
+			session.Decisions = d.Decisions
+			session.OpenTODOs = d.TODOs
+			session.CommandsRun = d.Commands
+			session.FilesChanged = d.FilesChanged
+		}
+
+		// Tool-only turns have no text to show, and abandoned branches aren't
+		// part of the conversation (interface concern - presentation)
+		var messages []db.SessionMessage
+		for _, msg := range coreDetail.Messages {
+			if !msg.IsToolOnly && msg.Branch == 0 {
+				messages = append(messages, msg)
+			}
 		}
 
 		// Extract first and last messages (interface concern - presentation)
-		if len(coreDetail.Messages) > 0 {
-			first := coreDetail.Messages[0]
+		if len(messages) > 0 {
+			first := messages[0]
 			session.FirstMessage = &MessageDetail{
 				Type:      first.Type,
 				Content:   first.Content,
@@ -288,12 +432,12 @@ func makeGetSessionDetailHandler(database *db.DB) func(context.Context, mcp.Call
 				Sequence:  0,
 			}
 
-			last := coreDetail.Messages[len(coreDetail.Messages)-1]
+			last := messages[len(messages)-1]
 			session.LastMessage = &MessageDetail{
 				Type:      last.Type,
 				Content:   last.Content,
 				Timestamp: last.Timestamp.Format("2006-01-02 15:04:05"),
-				Sequence:  len(coreDetail.Messages) - 1,
+				Sequence:  len(messages) - 1,
 			}
 		}
 
@@ -301,7 +445,7 @@ func makeGetSessionDetailHandler(database *db.DB) func(context.Context, mcp.Call
 		if args.SearchQuery != "" {
 			session.MatchingMessages = []MessageDetail{}
 			queryLower := strings.ToLower(args.SearchQuery)
-			for i, msg := range coreDetail.Messages {
+			for i, msg := range messages {
 				if strings.Contains(strings.ToLower(msg.Content), queryLower) {
 					session.MatchingMessages = append(session.MatchingMessages, MessageDetail{
 						Type:      msg.Type,
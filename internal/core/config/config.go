@@ -16,12 +16,48 @@ IMPORTANT: This session has been inactive for {{time_since}}. Before proceeding:
 
 type Config struct {
 	ResumePromptTemplate string
-	TerminalCommand      string   // Custom command to spawn terminal (optional)
-	ClaudeFlags          []string // Additional flags to pass to claude --resume
+	TerminalCommand      string                // Custom command to spawn terminal (optional)
+	ClaudeFlags          []string              // Additional flags to pass to claude --resume
+	Pricing              map[string]ModelPrice // Per-model prices for usage reports, keyed by model ID prefix
+	Embeddings           EmbeddingsConfig      // Embedder for semantic search
+	LLM                  LLMConfig             // Provider for ccrider summarize
+}
+
+// ModelPrice is the price of a model in USD per million tokens
+type ModelPrice struct {
+	Input      float64 `toml:"input"`
+	Output     float64 `toml:"output"`
+	CacheRead  float64 `toml:"cache_read"`
+	CacheWrite float64 `toml:"cache_write"`
+}
+
+// EmbeddingsConfig selects the embedder for semantic search ([embeddings]
+// in config.toml). The default, "hash", needs no model and works offline.
+type EmbeddingsConfig struct {
+	Provider string `toml:"provider"` // "hash" or "ollama"
+	Model    string `toml:"model"`    // Ollama model (default nomic-embed-text)
+	URL      string `toml:"url"`      // Ollama server (default http://localhost:11434)
+}
+
+// LLMConfig selects the LLM provider for summaries ([llm] in config.toml).
+// The default, "bedrock", uses AWS credentials.
+type LLMConfig struct {
+	Provider string `toml:"provider"` // "bedrock", "anthropic", "openai" or "ollama"
+	Model    string `toml:"model"`    // Model ID (each provider has a default)
+	URL      string `toml:"url"`      // API base URL (OpenAI-compatible server, Ollama, proxy)
+	APIKey   string `toml:"api_key"`  // Anthropic or OpenAI key (default from environment)
+	Region   string `toml:"region"`   // Bedrock AWS region
+	Profile  string `toml:"profile"`  // Bedrock AWS profile
+
+	Concurrency       int `toml:"concurrency"`         // Sessions summarized at once (default 4)
+	RequestsPerMinute int `toml:"requests_per_minute"` // Rate limit on LLM requests (default none)
 }
 
 type tomlConfig struct {
-	ClaudeFlags []string `toml:"claude_flags"`
+	ClaudeFlags []string              `toml:"claude_flags"`
+	Pricing     map[string]ModelPrice `toml:"pricing"`
+	Embeddings  EmbeddingsConfig      `toml:"embeddings"`
+	LLM         LLMConfig             `toml:"llm"`
 }
 
 // Load reads config from ~/.config/ccrider/
@@ -45,6 +81,9 @@ func Load() (*Config, error) {
 		var tc tomlConfig
 		if _, err := toml.DecodeFile(tomlPath, &tc); err == nil {
 			cfg.ClaudeFlags = tc.ClaudeFlags
+			cfg.Pricing = tc.Pricing
+			cfg.Embeddings = tc.Embeddings
+			cfg.LLM = tc.LLM
 		}
 	}
 
@@ -13,10 +13,28 @@ import (
 // DB wraps a SQLite database connection
 type DB struct {
 	conn *sql.DB
+	path string
 }
 
 // New creates a new database connection and initializes schema
 func New(dbPath string) (*DB, error) {
+	db, err := Open(dbPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the schema, or bring an existing database up to date
+	if err := db.Migrate(); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	return db, nil
+}
+
+// Open opens a database connection without creating or migrating the schema,
+// for inspecting a database as-is (e.g. `ccrider db migrate --status`)
+func Open(dbPath string) (*DB, error) {
 	// Ensure parent directory exists
 	dbDir := filepath.Dir(dbPath)
 	if err := os.MkdirAll(dbDir, 0755); err != nil {
@@ -35,21 +53,7 @@ func New(dbPath string) (*DB, error) {
 	conn.SetMaxIdleConns(1)
 	conn.SetConnMaxLifetime(time.Hour)
 
-	db := &DB{conn: conn}
-
-	// Initialize schema
-	if err := db.initSchema(); err != nil {
-		_ = conn.Close()
-		return nil, fmt.Errorf("failed to initialize schema: %w", err)
-	}
-
-	// Run migrations for existing databases
-	if err := db.migrate(); err != nil {
-		_ = conn.Close()
-		return nil, fmt.Errorf("failed to run migrations: %w", err)
-	}
-
-	return db, nil
+	return &DB{conn: conn, path: dbPath}, nil
 }
 
 // Close closes the database connection
@@ -57,6 +61,11 @@ func (db *DB) Close() error {
 	return db.conn.Close()
 }
 
+// Path returns the database file path
+func (db *DB) Path() string {
+	return db.path
+}
+
 // Begin starts a new transaction
 func (db *DB) Begin() (*sql.Tx, error) {
 	return db.conn.Begin()
@@ -96,6 +105,7 @@ type SessionSummary struct {
 	MessageCount   int
 	TokensApprox   int
 	ChunkSummaries []ChunkSummary
+	Details        *SummaryDetails // nil if the model gave no structured summary
 }
 
 // ChunkSummary represents a summary of a message chunk
@@ -132,7 +142,13 @@ func (db *DB) SaveSessionSummary(summary SessionSummary) error {
 		return fmt.Errorf("upsert summary: %w", err)
 	}
 
-	// Insert/update chunk summaries
+	// Insert/update chunk summaries, dropping any left from a longer
+	// chunking (or all of them for a summary made in one pass)
+	_, err = tx.Exec(`DELETE FROM summary_chunks WHERE session_id = ? AND chunk_index >= ?`,
+		summary.SessionID, len(summary.ChunkSummaries))
+	if err != nil {
+		return fmt.Errorf("delete stale chunks: %w", err)
+	}
 	for _, chunk := range summary.ChunkSummaries {
 		_, err = tx.Exec(`
 			INSERT INTO summary_chunks
@@ -149,6 +165,10 @@ func (db *DB) SaveSessionSummary(summary SessionSummary) error {
 		}
 	}
 
+	if err := saveSummaryDetails(tx, summary.SessionID, summary.Details); err != nil {
+		return err
+	}
+
 	// Also update the sessions table for backwards compat
 	_, err = tx.Exec(`
 		UPDATE sessions
@@ -193,7 +213,14 @@ func (db *DB) GetSessionSummary(sessionID int64) (*SessionSummary, error) {
 		}
 		s.ChunkSummaries = append(s.ChunkSummaries, c)
 	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
 
+	s.Details, err = db.GetSummaryDetails(sessionID)
+	if err != nil {
+		return nil, err
+	}
 	return &s, nil
 }
 
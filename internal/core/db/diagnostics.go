@@ -0,0 +1,39 @@
+package db
+
+import "time"
+
+// ImportDiagnostic is a malformed line skipped while importing a session file
+type ImportDiagnostic struct {
+	FilePath   string
+	Line       int
+	ByteOffset int64
+	Error      string
+	ImportedAt time.Time
+}
+
+// ListImportDiagnostics returns every recorded skipped line, grouped by file
+// and in line order
+func (db *DB) ListImportDiagnostics() ([]ImportDiagnostic, error) {
+	rows, err := db.Query(`
+		SELECT d.file_path, d.line_number, d.byte_offset, d.error, l.imported_at
+		FROM import_diagnostics d
+		JOIN import_log l ON l.id = d.import_log_id
+		ORDER BY d.file_path, d.line_number
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var diagnostics []ImportDiagnostic
+	for rows.Next() {
+		var d ImportDiagnostic
+		if err := rows.Scan(&d.FilePath, &d.Line, &d.ByteOffset, &d.Error, &d.ImportedAt); err != nil {
+			return nil, err
+		}
+		diagnostics = append(diagnostics, d)
+	}
+	return diagnostics, rows.Err()
+}
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+)
+
+// UnembeddedMessage is a message with text but no stored embeddings from a
+// given embedder
+type UnembeddedMessage struct {
+	ID   int64
+	Text string
+}
+
+// MessageEmbedding is the vector of one chunk of a message's text. Start and
+// Length are in characters (not bytes), so the chunk is
+// substr(text_content, Start+1, Length).
+type MessageEmbedding struct {
+	MessageID  int64
+	ChunkIndex int
+	Start      int
+	Length     int
+	Vector     []float32
+}
+
+// ListUnembeddedMessages returns up to limit messages with text and no
+// embeddings from embedder, with IDs above afterID, in ID order
+func (db *DB) ListUnembeddedMessages(embedder string, afterID int64, limit int) ([]UnembeddedMessage, error) {
+	rows, err := db.Query(`
+		SELECT m.id, m.text_content
+		FROM messages m
+		WHERE m.id > ?
+			AND COALESCE(m.text_content, '') != ''
+			AND NOT EXISTS (
+				SELECT 1 FROM message_embeddings e
+				WHERE e.message_id = m.id AND e.embedder = ?
+			)
+		ORDER BY m.id
+		LIMIT ?
+	`, afterID, embedder, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var messages []UnembeddedMessage
+	for rows.Next() {
+		var m UnembeddedMessage
+		if err := rows.Scan(&m.ID, &m.Text); err != nil {
+			return nil, err
+		}
+		messages = append(messages, m)
+	}
+	return messages, rows.Err()
+}
+
+// CountUnembeddedMessages counts messages with text and no embeddings from
+// embedder
+func (db *DB) CountUnembeddedMessages(embedder string) (int, error) {
+	var count int
+	err := db.QueryRow(`
+		SELECT COUNT(*)
+		FROM messages m
+		WHERE COALESCE(m.text_content, '') != ''
+			AND NOT EXISTS (
+				SELECT 1 FROM message_embeddings e
+				WHERE e.message_id = m.id AND e.embedder = ?
+			)
+	`, embedder).Scan(&count)
+	return count, err
+}
+
+// SaveMessageEmbeddings stores chunk embeddings from embedder, replacing any
+// existing ones for the same chunks
+func (db *DB) SaveMessageEmbeddings(embedder string, embeddings []MessageEmbedding) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, e := range embeddings {
+		_, err = tx.Exec(`
+			INSERT INTO message_embeddings (message_id, chunk_index, chunk_start, chunk_length, embedder, dimensions, vector)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(message_id, chunk_index, embedder) DO UPDATE SET
+				chunk_start = excluded.chunk_start,
+				chunk_length = excluded.chunk_length,
+				dimensions = excluded.dimensions,
+				vector = excluded.vector,
+				created_at = CURRENT_TIMESTAMP
+		`, e.MessageID, e.ChunkIndex, e.Start, e.Length, embedder, len(e.Vector), EncodeVector(e.Vector))
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// DeleteEmbeddings removes every stored embedding from embedder
+func (db *DB) DeleteEmbeddings(embedder string) error {
+	_, err := db.Exec(`DELETE FROM message_embeddings WHERE embedder = ?`, embedder)
+	return err
+}
+
+// EncodeVector packs a vector as little-endian float32s for storage
+func EncodeVector(v []float32) []byte {
+	buf := make([]byte, 4*len(v))
+	for i, f := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(f))
+	}
+	return buf
+}
+
+// DecodeVector unpacks a vector stored by EncodeVector
+func DecodeVector(buf []byte) ([]float32, error) {
+	if len(buf)%4 != 0 {
+		return nil, fmt.Errorf("invalid vector length %d", len(buf))
+	}
+	v := make([]float32, len(buf)/4)
+	for i := range v {
+		v[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	return v, nil
+}
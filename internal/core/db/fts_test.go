@@ -270,3 +270,66 @@ func TestFTSTriggers(t *testing.T) {
 		t.Errorf("Expected 0 FTS entries after delete, got %d", count)
 	}
 }
+
+func TestToolFTSTriggers(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Remove(tmpfile.Name()) }()
+	_ = tmpfile.Close()
+
+	database, err := New(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	result, err := database.Exec(`
+		INSERT INTO sessions (session_id, project_path, created_at, updated_at)
+		VALUES ('s', '/test', datetime('now'), datetime('now'))
+	`)
+	if err != nil {
+		t.Fatalf("Failed to insert session: %v", err)
+	}
+	sessionID, _ := result.LastInsertId()
+	result, err = database.Exec(`
+		INSERT INTO messages (uuid, session_id, type, timestamp, sequence)
+		VALUES ('m', ?, 'assistant', datetime('now'), 1)
+	`, sessionID)
+	if err != nil {
+		t.Fatalf("Failed to insert message: %v", err)
+	}
+	messageID, _ := result.LastInsertId()
+
+	// The output arrives later, as an update
+	result, err = database.Exec(`INSERT INTO tool_uses (message_id, tool_name, tool_id, input) VALUES (?, 'Bash', 't1', ?)`,
+		messageID, `{"command":"go test ./..."}`)
+	if err != nil {
+		t.Fatalf("Failed to insert tool use: %v", err)
+	}
+	if _, err := database.Exec(`UPDATE tool_uses SET output = 'FAIL: TestParse_user_id' WHERE tool_id = 't1'`); err != nil {
+		t.Fatalf("Failed to update tool use: %v", err)
+	}
+
+	count := func(match string) int {
+		var n int
+		if err := database.QueryRow(`SELECT COUNT(*) FROM tool_uses_fts WHERE tool_uses_fts MATCH ?`, match).Scan(&n); err != nil {
+			t.Fatalf("FTS query failed: %v", err)
+		}
+		return n
+	}
+	if count(`input:"go test"`) != 1 {
+		t.Error("Expected the input to be indexed")
+	}
+	if count(`output:TestParse_user_id`) != 1 {
+		t.Error("Expected the updated output to be indexed, with identifiers whole")
+	}
+
+	if _, err := database.Exec(`DELETE FROM tool_uses`); err != nil {
+		t.Fatal(err)
+	}
+	if count(`"go test"`) != 0 {
+		t.Error("Expected the deleted tool use to be removed from the index")
+	}
+}
@@ -1,24 +1,221 @@
 package db
 
-// migrate runs any needed migrations for existing databases
-func (db *DB) migrate() error {
-	// Migration 1: Add llm_summary columns to sessions table
-	if err := db.migration001AddLLMSummaryColumns(); err != nil {
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Migration is a numbered schema change. A database's PRAGMA user_version is
+// the number of the last migration applied to it.
+type Migration struct {
+	Version     int
+	Description string
+	up          func(tx *sql.Tx) error
+}
+
+// migrations is the schema history, in order. Every schema change must be made
+// both in initSchema (which builds new databases at the latest version) and
+// as a new migration appended here (which brings existing databases forward).
+//
+// Migrations 1-3 predate user_version tracking, so they check whether an older
+// ccrider already applied them.
+var migrations = []Migration{
+	{1, "Add llm_summary columns to sessions", migration001AddLLMSummaryColumns},
+	{2, "Create hierarchical summarization tables", migration002CreateSummaryTables},
+	{3, "Flag tool-only messages (now stored instead of dropped)", migration003AddToolOnlyColumn},
+	{4, "Add import_state table and tool_uses.tool_id index", migration004AddImportState},
+	{5, "Add import_diagnostics table for skipped malformed lines", migration005AddImportDiagnostics},
+	{6, "Add model and token usage to messages and sessions", migration006AddTokenUsage},
+	{7, "Link subagent (sidechain) messages to the Task calls that spawned them", migration007AddAgentIDs},
+	{8, "Split user and assistant text in messages_fts and index session summaries for bm25 ranking", migration008RankedSearch},
+	{9, "Keep underscores in identifiers in messages_fts_code", migration009CodeTokens},
+	{10, "Add message_embeddings table for semantic search", migration010AddMessageEmbeddings},
+	{11, "Index tool inputs and outputs in tool_uses_fts", migration011ToolSearch},
+	{12, "Add saved_searches and search_history tables", migration012SavedSearches},
+	{13, "Add summary_jobs tables for resumable summarize runs", migration013SummaryJobs},
+	{14, "Add summary_details tables for structured summaries", migration014SummaryDetails},
+	{15, "Keep array-form tool results out of message text (indexed as tool output)", migration015ToolResultText},
+	{16, "Delete old text from messages_fts_code on message updates and deletes", migration016CodeIndexTriggers},
+}
+
+// SchemaVersion returns the newest schema version this build understands
+func SchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// ErrSchemaTooNew is returned when opening a database that a newer ccrider
+// has migrated past SchemaVersion
+var ErrSchemaTooNew = errors.New("database schema is newer than this version of ccrider supports")
+
+// MigrationStatus describes where a database stands relative to this build
+type MigrationStatus struct {
+	CurrentVersion int
+	LatestVersion  int
+	Pending        []Migration
+}
+
+// userVersion reads PRAGMA user_version
+func (db *DB) userVersion() (int, error) {
+	var version int
+	if err := db.conn.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// checkVersion refuses databases from a newer ccrider
+func (db *DB) checkVersion() (int, error) {
+	version, err := db.userVersion()
+	if err != nil {
+		return 0, err
+	}
+	if version > SchemaVersion() {
+		return version, fmt.Errorf("%w (database version %d, supported up to %d) - upgrade ccrider",
+			ErrSchemaTooNew, version, SchemaVersion())
+	}
+	return version, nil
+}
+
+// Status reports the database's schema version and the migrations it still needs
+func (db *DB) Status() (*MigrationStatus, error) {
+	version, err := db.userVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MigrationStatus{CurrentVersion: version, LatestVersion: SchemaVersion()}
+	fresh, err := db.isFresh()
+	if err != nil {
+		return nil, err
+	}
+	if fresh {
+		// A new database is created at the latest version in one step
+		return status, nil
+	}
+	for _, m := range migrations {
+		if m.Version > version {
+			status.Pending = append(status.Pending, m)
+		}
+	}
+	return status, nil
+}
+
+// Migrate brings the database up to SchemaVersion(). A new database gets the
+// full schema in one transaction; an existing one runs each pending migration
+// in its own transaction, bumping user_version as part of it, so a failure
+// never leaves a half-applied migration behind.
+func (db *DB) Migrate() error {
+	return db.migrate(false)
+}
+
+// DryRunMigrate runs every pending migration and rolls them back, verifying
+// they would apply cleanly without changing the database
+func (db *DB) DryRunMigrate() error {
+	return db.migrate(true)
+}
+
+func (db *DB) migrate(dryRun bool) error {
+	version, err := db.checkVersion()
+	if err != nil {
 		return err
 	}
 
-	// Migration 2: Create hierarchical summarization tables
-	if err := db.migration002CreateSummaryTables(); err != nil {
+	fresh, err := db.isFresh()
+	if err != nil {
 		return err
 	}
 
+	var pending []Migration
+	for _, m := range migrations {
+		if m.Version > version {
+			pending = append(pending, m)
+		}
+	}
+	if !fresh && len(pending) == 0 {
+		return nil
+	}
+
+	// A dry run applies everything in one transaction so each migration sees
+	// the ones before it, then rolls it all back
+	var dryRunTx *sql.Tx
+	if dryRun {
+		dryRunTx, err = db.conn.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer func() {
+			_ = dryRunTx.Rollback()
+		}()
+	}
+	inTx := func(fn func(tx *sql.Tx) error) error {
+		if dryRunTx != nil {
+			return fn(dryRunTx)
+		}
+		return db.inTx(fn)
+	}
+
+	if fresh {
+		return inTx(func(tx *sql.Tx) error {
+			if err := initSchema(tx); err != nil {
+				return fmt.Errorf("failed to initialize schema: %w", err)
+			}
+			return setUserVersion(tx, SchemaVersion())
+		})
+	}
+
+	for _, m := range pending {
+		err := inTx(func(tx *sql.Tx) error {
+			if err := m.up(tx); err != nil {
+				return err
+			}
+			return setUserVersion(tx, m.Version)
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d (%s) failed: %w", m.Version, m.Description, err)
+		}
+	}
 	return nil
 }
 
-// migration001AddLLMSummaryColumns adds basic llm_summary columns to sessions
-func (db *DB) migration001AddLLMSummaryColumns() error {
+// isFresh reports whether the database has no ccrider schema yet
+func (db *DB) isFresh() (bool, error) {
 	var count int
 	err := db.conn.QueryRow(`
+		SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='sessions'
+	`).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+	return count == 0, nil
+}
+
+// inTx runs fn in a transaction, committing only if it succeeds
+func (db *DB) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func setUserVersion(tx *sql.Tx, version int) error {
+	// PRAGMA doesn't take bound parameters
+	_, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", version))
+	return err
+}
+
+// migration001AddLLMSummaryColumns adds basic llm_summary columns to sessions
+func migration001AddLLMSummaryColumns(tx *sql.Tx) error {
+	var count int
+	err := tx.QueryRow(`
 		SELECT COUNT(*) FROM pragma_table_info('sessions') WHERE name='llm_summary'
 	`).Scan(&count)
 	if err != nil {
@@ -26,11 +223,11 @@ func (db *DB) migration001AddLLMSummaryColumns() error {
 	}
 
 	if count == 0 {
-		_, err = db.conn.Exec(`ALTER TABLE sessions ADD COLUMN llm_summary TEXT`)
+		_, err = tx.Exec(`ALTER TABLE sessions ADD COLUMN llm_summary TEXT`)
 		if err != nil {
 			return err
 		}
-		_, err = db.conn.Exec(`ALTER TABLE sessions ADD COLUMN llm_summary_at DATETIME`)
+		_, err = tx.Exec(`ALTER TABLE sessions ADD COLUMN llm_summary_at DATETIME`)
 		if err != nil {
 			return err
 		}
@@ -39,7 +236,7 @@ func (db *DB) migration001AddLLMSummaryColumns() error {
 }
 
 // migration002CreateSummaryTables creates the hierarchical summarization tables
-func (db *DB) migration002CreateSummaryTables() error {
+func migration002CreateSummaryTables(tx *sql.Tx) error {
 	schema := `
 	-- Session summaries (progressive summarization)
 	CREATE TABLE IF NOT EXISTS session_summaries (
@@ -107,6 +304,475 @@ func (db *DB) migration002CreateSummaryTables() error {
 	CREATE INDEX IF NOT EXISTS idx_session_files_session ON session_files(session_id);
 	`
 
-	_, err := db.conn.Exec(schema)
+	_, err := tx.Exec(schema)
+	return err
+}
+
+// migration003AddToolOnlyColumn adds messages.is_tool_only. Databases created
+// before this dropped tool-only entries, so every session is flagged (NULL
+// file_mtime) for a full re-import on the next sync to fill in the gaps.
+func migration003AddToolOnlyColumn(tx *sql.Tx) error {
+	var count int
+	err := tx.QueryRow(`
+		SELECT COUNT(*) FROM pragma_table_info('messages') WHERE name='is_tool_only'
+	`).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		_, err = tx.Exec(`ALTER TABLE messages ADD COLUMN is_tool_only BOOLEAN DEFAULT 0`)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`UPDATE sessions SET file_mtime = NULL`)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migration004AddImportState adds the per-file incremental sync position and
+// the tool_id index used to attach tool results across syncs
+func migration004AddImportState(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS import_state (
+		file_path TEXT PRIMARY KEY,
+		session_id TEXT NOT NULL,
+		byte_offset INTEGER NOT NULL,
+		line_count INTEGER NOT NULL,
+		prefix_hash TEXT NOT NULL,
+		file_size INTEGER NOT NULL,
+		file_mtime DATETIME NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_tool_uses_tool_id ON tool_uses(tool_id);
+	`)
 	return err
 }
+
+// migration005AddImportDiagnostics records malformed lines the parser skipped
+func migration005AddImportDiagnostics(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS import_diagnostics (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		import_log_id INTEGER NOT NULL,
+		file_path TEXT NOT NULL,
+		line_number INTEGER NOT NULL,
+		byte_offset INTEGER NOT NULL,
+		error TEXT NOT NULL,
+		FOREIGN KEY (import_log_id) REFERENCES import_log(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_import_diagnostics_file_path ON import_diagnostics(file_path);
+	`)
+	return err
+}
+
+// migration006AddTokenUsage adds per-message model and token usage with
+// per-session totals. Import positions are cleared so the next sync rereads
+// every file and fills usage in for messages already stored; the FTS update
+// trigger is narrowed to text changes so that backfill doesn't reindex.
+func migration006AddTokenUsage(tx *sql.Tx) error {
+	columns := []string{
+		`ALTER TABLE messages ADD COLUMN model TEXT`,
+		`ALTER TABLE messages ADD COLUMN api_message_id TEXT`,
+		`ALTER TABLE messages ADD COLUMN input_tokens INTEGER DEFAULT 0`,
+		`ALTER TABLE messages ADD COLUMN output_tokens INTEGER DEFAULT 0`,
+		`ALTER TABLE messages ADD COLUMN cache_read_tokens INTEGER DEFAULT 0`,
+		`ALTER TABLE messages ADD COLUMN cache_creation_tokens INTEGER DEFAULT 0`,
+		`ALTER TABLE sessions ADD COLUMN model TEXT`,
+		`ALTER TABLE sessions ADD COLUMN input_tokens INTEGER DEFAULT 0`,
+		`ALTER TABLE sessions ADD COLUMN output_tokens INTEGER DEFAULT 0`,
+		`ALTER TABLE sessions ADD COLUMN cache_read_tokens INTEGER DEFAULT 0`,
+		`ALTER TABLE sessions ADD COLUMN cache_creation_tokens INTEGER DEFAULT 0`,
+		`CREATE INDEX IF NOT EXISTS idx_messages_api_message_id ON messages(api_message_id)`,
+		// Backfilling usage updates messages; only text changes need reindexing
+		`DROP TRIGGER IF EXISTS messages_au`,
+		`CREATE TRIGGER messages_au AFTER UPDATE OF text_content ON messages BEGIN
+			UPDATE messages_fts SET text_content = new.text_content WHERE rowid = new.id;
+			UPDATE messages_fts_code SET text_content = new.text_content WHERE rowid = new.id;
+		END`,
+		`DELETE FROM import_state`,
+	}
+	for _, stmt := range columns {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migration007AddAgentIDs records which subagent run each sidechain message
+// belongs to, and which run each Task tool call spawned. Agent IDs only exist
+// in the session files, so import positions are cleared for a full reread.
+func migration007AddAgentIDs(tx *sql.Tx) error {
+	stmts := []string{
+		`ALTER TABLE messages ADD COLUMN agent_id TEXT`,
+		`ALTER TABLE tool_uses ADD COLUMN agent_id TEXT`,
+		`CREATE INDEX IF NOT EXISTS idx_messages_agent_id ON messages(agent_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_tool_uses_agent_id ON tool_uses(agent_id)`,
+		`DELETE FROM import_state`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migration008RankedSearch rebuilds messages_fts with user and assistant text
+// in separate columns, and adds sessions_fts over session summaries, so search
+// can rank with column-weighted bm25()
+func migration008RankedSearch(tx *sql.Tx) error {
+	stmts := []string{
+		`DROP TRIGGER IF EXISTS messages_ai`,
+		`DROP TRIGGER IF EXISTS messages_ad`,
+		`DROP TRIGGER IF EXISTS messages_au`,
+		`DROP TABLE IF EXISTS messages_fts`,
+		`CREATE VIEW IF NOT EXISTS messages_fts_content AS
+		SELECT
+			id,
+			CASE WHEN type = 'user' THEN text_content END AS user_text,
+			CASE WHEN type = 'user' THEN NULL ELSE text_content END AS assistant_text
+		FROM messages`,
+		`CREATE VIRTUAL TABLE messages_fts USING fts5(
+			user_text,
+			assistant_text,
+			content=messages_fts_content,
+			content_rowid=id,
+			tokenize='porter unicode61'
+		)`,
+		`INSERT INTO messages_fts(messages_fts) VALUES ('rebuild')`,
+		`CREATE VIRTUAL TABLE IF NOT EXISTS sessions_fts USING fts5(
+			summary,
+			tokenize='porter unicode61'
+		)`,
+		ftsTriggers,
+		`INSERT INTO sessions_fts(rowid, summary)
+		SELECT s.id, ` + sessionSummaryText + `
+		FROM sessions s LEFT JOIN session_summaries ss ON ss.session_id = s.id`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migration009CodeTokens rebuilds messages_fts_code so identifiers like
+// user_id are single tokens and code search matches them exactly
+func migration009CodeTokens(tx *sql.Tx) error {
+	stmts := []string{
+		`DROP TABLE IF EXISTS messages_fts_code`,
+		`CREATE VIRTUAL TABLE messages_fts_code USING fts5(
+			text_content,
+			content=messages,
+			content_rowid=id,
+			tokenize="unicode61 tokenchars '_'"
+		)`,
+		`INSERT INTO messages_fts_code(messages_fts_code) VALUES ('rebuild')`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migration010AddMessageEmbeddings stores per-chunk message embeddings for
+// semantic search. They are built on demand (ccrider embed), so none are
+// created here.
+func migration010AddMessageEmbeddings(tx *sql.Tx) error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS message_embeddings (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			message_id INTEGER NOT NULL,
+			chunk_index INTEGER NOT NULL,
+			chunk_start INTEGER NOT NULL,
+			chunk_length INTEGER NOT NULL,
+			embedder TEXT NOT NULL,
+			dimensions INTEGER NOT NULL,
+			vector BLOB NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+			UNIQUE(message_id, chunk_index, embedder)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_message_embeddings_embedder ON message_embeddings(embedder)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migration011ToolSearch indexes tool call inputs and outputs so search can
+// match inside tool blocks (commands, compiler errors, test output)
+func migration011ToolSearch(tx *sql.Tx) error {
+	stmts := []string{
+		toolUsesFTS,
+		`INSERT INTO tool_uses_fts(tool_uses_fts) VALUES ('rebuild')`,
+		toolFTSTriggers,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migration012SavedSearches adds named saved searches and search history
+func migration012SavedSearches(tx *sql.Tx) error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS saved_searches (
+			name TEXT PRIMARY KEY,
+			query TEXT NOT NULL,
+			mode TEXT NOT NULL DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS search_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			query TEXT NOT NULL,
+			mode TEXT NOT NULL DEFAULT '',
+			searched_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migration013SummaryJobs adds job state for resumable summarize runs
+func migration013SummaryJobs(tx *sql.Tx) error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS summary_jobs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			params TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS summary_job_items (
+			job_id INTEGER NOT NULL,
+			session_id INTEGER NOT NULL,
+			position INTEGER NOT NULL,
+			status TEXT NOT NULL DEFAULT 'pending',
+			error TEXT,
+			PRIMARY KEY (job_id, session_id),
+			FOREIGN KEY (job_id) REFERENCES summary_jobs(id) ON DELETE CASCADE,
+			FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+		)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migration014SummaryDetails adds the structured fields of LLM summaries
+func migration014SummaryDetails(tx *sql.Tx) error {
+	stmts := []string{
+		`CREATE TABLE IF NOT EXISTS summary_details (
+			session_id INTEGER PRIMARY KEY,
+			goal TEXT,
+			outcome TEXT,
+			FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS summary_detail_items (
+			session_id INTEGER NOT NULL,
+			kind TEXT NOT NULL,
+			position INTEGER NOT NULL,
+			text TEXT NOT NULL,
+			PRIMARY KEY (session_id, kind, position),
+			FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+		)`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migration015ToolResultText removes tool output from the text of user
+// messages with array-form tool_result blocks. Earlier imports copied it there
+// as well as into tool_uses.output, so search matched it twice. The message
+// text is rebuilt from its text blocks; messages left without any become
+// tool-only, and their stale embeddings are dropped. messages_fts_code is
+// rebuilt since the update trigger before migration 16 left old text indexed.
+func migration015ToolResultText(tx *sql.Tx) error {
+	stmts := []string{
+		`CREATE TEMP TABLE tool_result_messages AS
+		SELECT id, session_id FROM messages
+		WHERE type = 'user'
+			AND json_valid(content)
+			AND json_type(content, '$.content') = 'array'
+			AND EXISTS (
+				SELECT 1 FROM json_each(messages.content, '$.content') b
+				WHERE json_extract(b.value, '$.type') = 'tool_result'
+					AND json_type(b.value, '$.content') = 'array'
+			)`,
+		`DELETE FROM message_embeddings WHERE message_id IN (SELECT id FROM tool_result_messages)`,
+		`UPDATE messages SET text_content = COALESCE((
+			SELECT GROUP_CONCAT(json_extract(b.value, '$.text') || char(10), '')
+			FROM json_each(messages.content, '$.content') b
+			WHERE json_extract(b.value, '$.type') = 'text'
+		), '')
+		WHERE id IN (SELECT id FROM tool_result_messages)`,
+		`UPDATE messages SET is_tool_only = 1
+		WHERE id IN (SELECT id FROM tool_result_messages) AND TRIM(text_content) = ''`,
+		`UPDATE sessions SET message_count = (
+			SELECT COUNT(*) FROM messages WHERE messages.session_id = sessions.id AND ` + VisibleMessageCondition + `
+		)
+		WHERE id IN (SELECT session_id FROM tool_result_messages)`,
+		`DROP TABLE tool_result_messages`,
+		`INSERT INTO messages_fts_code(messages_fts_code) VALUES ('rebuild')`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migration016CodeIndexTriggers recreates the delete and update triggers so
+// they remove the old text from messages_fts_code, an external-content table
+// that can't delete by rowid alone, and rebuilds the index to drop tokens the
+// old triggers left behind
+func migration016CodeIndexTriggers(tx *sql.Tx) error {
+	stmts := []string{
+		`DROP TRIGGER IF EXISTS messages_ad`,
+		`DROP TRIGGER IF EXISTS messages_au`,
+		ftsTriggers,
+		`INSERT INTO messages_fts_code(messages_fts_code) VALUES ('rebuild')`,
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sessionSummaryText is the text sessions_fts indexes for a session s with
+// session_summaries row ss: every summary it has
+const sessionSummaryText = `TRIM(COALESCE(s.summary, '') || ' ' || COALESCE(s.llm_summary, '') || ' ' || COALESCE(ss.one_line_summary, ''))`
+
+// ftsTriggers keeps the FTS tables in sync. messages_fts and messages_fts_code
+// take the old values for deletes since their content (messages, or a view
+// over it) has already changed.
+// Shared by initSchema and migration 8.
+const ftsTriggers = `
+	CREATE TRIGGER IF NOT EXISTS messages_ai AFTER INSERT ON messages BEGIN
+		INSERT INTO messages_fts(rowid, user_text, assistant_text) VALUES (
+			new.id,
+			CASE WHEN new.type = 'user' THEN new.text_content END,
+			CASE WHEN new.type = 'user' THEN NULL ELSE new.text_content END
+		);
+		INSERT INTO messages_fts_code(rowid, text_content) VALUES (new.id, new.text_content);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS messages_ad AFTER DELETE ON messages BEGIN
+		INSERT INTO messages_fts(messages_fts, rowid, user_text, assistant_text) VALUES (
+			'delete',
+			old.id,
+			CASE WHEN old.type = 'user' THEN old.text_content END,
+			CASE WHEN old.type = 'user' THEN NULL ELSE old.text_content END
+		);
+		INSERT INTO messages_fts_code(messages_fts_code, rowid, text_content) VALUES ('delete', old.id, old.text_content);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS messages_au AFTER UPDATE OF text_content ON messages BEGIN
+		INSERT INTO messages_fts(messages_fts, rowid, user_text, assistant_text) VALUES (
+			'delete',
+			old.id,
+			CASE WHEN old.type = 'user' THEN old.text_content END,
+			CASE WHEN old.type = 'user' THEN NULL ELSE old.text_content END
+		);
+		INSERT INTO messages_fts(rowid, user_text, assistant_text) VALUES (
+			new.id,
+			CASE WHEN new.type = 'user' THEN new.text_content END,
+			CASE WHEN new.type = 'user' THEN NULL ELSE new.text_content END
+		);
+		INSERT INTO messages_fts_code(messages_fts_code, rowid, text_content) VALUES ('delete', old.id, old.text_content);
+		INSERT INTO messages_fts_code(rowid, text_content) VALUES (new.id, new.text_content);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS sessions_fts_ai AFTER INSERT ON sessions BEGIN
+		INSERT INTO sessions_fts(rowid, summary)
+		SELECT s.id, ` + sessionSummaryText + `
+		FROM sessions s LEFT JOIN session_summaries ss ON ss.session_id = s.id
+		WHERE s.id = new.id;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS sessions_fts_au AFTER UPDATE OF summary, llm_summary ON sessions BEGIN
+		DELETE FROM sessions_fts WHERE rowid = new.id;
+		INSERT INTO sessions_fts(rowid, summary)
+		SELECT s.id, ` + sessionSummaryText + `
+		FROM sessions s LEFT JOIN session_summaries ss ON ss.session_id = s.id
+		WHERE s.id = new.id;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS sessions_fts_ad AFTER DELETE ON sessions BEGIN
+		DELETE FROM sessions_fts WHERE rowid = old.id;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS session_summaries_fts_ai AFTER INSERT ON session_summaries BEGIN
+		DELETE FROM sessions_fts WHERE rowid = new.session_id;
+		INSERT INTO sessions_fts(rowid, summary)
+		SELECT s.id, ` + sessionSummaryText + `
+		FROM sessions s LEFT JOIN session_summaries ss ON ss.session_id = s.id
+		WHERE s.id = new.session_id;
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS session_summaries_fts_au AFTER UPDATE OF one_line_summary ON session_summaries BEGIN
+		DELETE FROM sessions_fts WHERE rowid = new.session_id;
+		INSERT INTO sessions_fts(rowid, summary)
+		SELECT s.id, ` + sessionSummaryText + `
+		FROM sessions s LEFT JOIN session_summaries ss ON ss.session_id = s.id
+		WHERE s.id = new.session_id;
+	END;
+`
+
+// toolUsesFTS indexes tool inputs and outputs. Without stemming they'd miss
+// "failing" for "failed"; keeping underscores keeps identifiers whole.
+// Shared by initSchema and migration 11.
+const toolUsesFTS = `
+	CREATE VIRTUAL TABLE IF NOT EXISTS tool_uses_fts USING fts5(
+		input,
+		output,
+		content=tool_uses,
+		content_rowid=id,
+		tokenize="porter unicode61 tokenchars '_'"
+	)`
+
+// toolFTSTriggers keeps tool_uses_fts in sync. Outputs arrive in a later
+// message than their call, so updates re-index the row.
+const toolFTSTriggers = `
+	CREATE TRIGGER IF NOT EXISTS tool_uses_ai AFTER INSERT ON tool_uses BEGIN
+		INSERT INTO tool_uses_fts(rowid, input, output) VALUES (new.id, new.input, new.output);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS tool_uses_ad AFTER DELETE ON tool_uses BEGIN
+		INSERT INTO tool_uses_fts(tool_uses_fts, rowid, input, output) VALUES ('delete', old.id, old.input, old.output);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS tool_uses_au AFTER UPDATE OF input, output ON tool_uses BEGIN
+		INSERT INTO tool_uses_fts(tool_uses_fts, rowid, input, output) VALUES ('delete', old.id, old.input, old.output);
+		INSERT INTO tool_uses_fts(rowid, input, output) VALUES (new.id, new.input, new.output);
+	END;
+`
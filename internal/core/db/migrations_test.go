@@ -0,0 +1,354 @@
+package db
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+// legacyDB creates a database as a pre-user_version ccrider left it: the
+// baseline schema at version 0, before any of the later migrations
+func legacyDB(t *testing.T) string {
+	t.Helper()
+
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Remove(tmpfile.Name()) })
+	_ = tmpfile.Close()
+
+	schema, err := os.ReadFile("testdata/schema_v0.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	database, err := Open(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	if _, err := database.Exec(string(schema)); err != nil {
+		t.Fatalf("failed to create legacy schema: %v", err)
+	}
+	_, err = database.Exec(`INSERT INTO sessions (session_id, project_path, file_mtime) VALUES ('legacy', '/tmp', '2025-01-01 00:00:00')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return tmpfile.Name()
+}
+
+func TestMigrate_NewDatabase(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Remove(tmpfile.Name()) }()
+	_ = tmpfile.Close()
+
+	database, err := New(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	status, err := database.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.CurrentVersion != SchemaVersion() || len(status.Pending) != 0 {
+		t.Errorf("Expected new database at version %d with nothing pending, got %+v", SchemaVersion(), status)
+	}
+}
+
+func TestMigrate_LegacyDatabase(t *testing.T) {
+	path := legacyDB(t)
+
+	database, err := New(path)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	version, err := database.userVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != SchemaVersion() {
+		t.Errorf("Expected version %d after migrating, got %d", SchemaVersion(), version)
+	}
+
+	var count int
+	err = database.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('messages') WHERE name='is_tool_only'`).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Error("Expected messages.is_tool_only to be added")
+	}
+
+	err = database.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='import_state'`).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Error("Expected import_state to be created")
+	}
+
+	err = database.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('sessions') WHERE name IN ('model', 'input_tokens', 'output_tokens', 'cache_read_tokens', 'cache_creation_tokens')`).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 5 {
+		t.Errorf("Expected sessions model and token columns to be added, got %d of 5", count)
+	}
+
+	err = database.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('messages_fts') WHERE name IN ('user_text', 'assistant_text')`).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("Expected messages_fts to be rebuilt with user and assistant columns, got %d of 2", count)
+	}
+
+	err = database.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='message_embeddings'`).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Error("Expected message_embeddings to be created")
+	}
+
+	err = database.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name='tool_uses_fts'`).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Error("Expected tool_uses_fts to be created")
+	}
+
+	for _, table := range []string{"saved_searches", "search_history", "summary_jobs", "summary_job_items", "summary_details", "summary_detail_items"} {
+		err = database.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name=?`, table).Scan(&count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != 1 {
+			t.Errorf("Expected %s to be created", table)
+		}
+	}
+
+	// Migration 3 flags existing sessions for a full re-import
+	var mtimeSet bool
+	err = database.QueryRow(`SELECT file_mtime IS NOT NULL FROM sessions WHERE session_id = 'legacy'`).Scan(&mtimeSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mtimeSet {
+		t.Error("Expected file_mtime to be cleared by migration 3")
+	}
+}
+
+func TestMigrate_DryRun(t *testing.T) {
+	path := legacyDB(t)
+
+	database, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	status, err := database.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Pending) != SchemaVersion() {
+		t.Fatalf("Expected %d pending migrations, got %d", SchemaVersion(), len(status.Pending))
+	}
+
+	if err := database.DryRunMigrate(); err != nil {
+		t.Fatalf("DryRunMigrate() error = %v", err)
+	}
+
+	// Nothing changed
+	version, err := database.userVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != 0 {
+		t.Errorf("Expected version 0 after dry run, got %d", version)
+	}
+	var count int
+	err = database.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('messages') WHERE name='is_tool_only'`).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Error("Expected dry run to leave messages.is_tool_only absent")
+	}
+}
+
+func TestNew_SchemaTooNew(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Remove(tmpfile.Name()) }()
+	_ = tmpfile.Close()
+
+	database, err := New(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := database.Exec("PRAGMA user_version = 9999"); err != nil {
+		t.Fatal(err)
+	}
+	_ = database.Close()
+
+	_, err = New(tmpfile.Name())
+	if !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("New() error = %v, want ErrSchemaTooNew", err)
+	}
+}
+
+func TestMigration015_ToolResultText(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Remove(tmpfile.Name()) }()
+	_ = tmpfile.Close()
+
+	database, err := New(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	// Messages as earlier imports stored them, with array-form tool output
+	// copied into the text
+	result, err := database.Exec(`
+		INSERT INTO sessions (session_id, project_path, message_count, created_at, updated_at)
+		VALUES ('s1', '/test', 3, datetime('now'), datetime('now'))
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionID, _ := result.LastInsertId()
+	messages := []struct {
+		uuid, content, text string
+	}{
+		{"result", `{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":[{"type":"text","text":"defmodule Repo"}]}]}`, "defmodule Repo\n"},
+		{"mixed", `{"role":"user","content":[{"type":"tool_result","tool_use_id":"t2","content":[{"type":"text","text":"defmodule Accounts"}]},{"type":"text","text":"Now rename it"}]}`, "defmodule Accounts\nNow rename it\n"},
+		{"typed", `{"role":"user","content":"defmodule is a macro"}`, "defmodule is a macro"},
+	}
+	for i, m := range messages {
+		if _, err := database.Exec(`
+			INSERT INTO messages (uuid, session_id, type, sender, content, text_content, timestamp, sequence)
+			VALUES (?, ?, 'user', 'human', ?, ?, datetime('now'), ?)
+		`, m.uuid, sessionID, m.content, m.text, i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := database.inTx(migration015ToolResultText); err != nil {
+		t.Fatalf("migration015ToolResultText() error = %v", err)
+	}
+
+	want := map[string]struct {
+		text     string
+		toolOnly bool
+	}{
+		"result": {"", true},
+		"mixed":  {"Now rename it\n", false},
+		"typed":  {"defmodule is a macro", false},
+	}
+	for uuid, w := range want {
+		var text string
+		var toolOnly bool
+		if err := database.QueryRow(`SELECT text_content, is_tool_only FROM messages WHERE uuid = ?`, uuid).Scan(&text, &toolOnly); err != nil {
+			t.Fatal(err)
+		}
+		if text != w.text || toolOnly != w.toolOnly {
+			t.Errorf("%s = %q (tool-only %v), want %q (tool-only %v)", uuid, text, toolOnly, w.text, w.toolOnly)
+		}
+	}
+
+	var count int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM messages_fts WHERE messages_fts MATCH 'defmodule'`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("Expected only the typed message to match in messages_fts, got %d", count)
+	}
+	if err := database.QueryRow(`SELECT COUNT(*) FROM messages_fts_code WHERE messages_fts_code MATCH 'defmodule'`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("Expected only the typed message to match in messages_fts_code, got %d", count)
+	}
+	if err := database.QueryRow(`SELECT message_count FROM sessions WHERE id = ?`, sessionID).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("Expected message_count 2, got %d", count)
+	}
+}
+
+func TestFTSTriggers_CodeIndex(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Remove(tmpfile.Name()) }()
+	_ = tmpfile.Close()
+
+	database, err := New(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	result, err := database.Exec(`
+		INSERT INTO sessions (session_id, project_path, message_count, created_at, updated_at)
+		VALUES ('s1', '/test', 1, datetime('now'), datetime('now'))
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionID, _ := result.LastInsertId()
+	if _, err := database.Exec(`
+		INSERT INTO messages (uuid, session_id, type, sender, content, text_content, timestamp, sequence)
+		VALUES ('m1', ?, 'user', 'human', '', 'old_name', datetime('now'), 0)
+	`, sessionID); err != nil {
+		t.Fatal(err)
+	}
+
+	codeMatches := func(query string) int {
+		t.Helper()
+		var count int
+		if err := database.QueryRow(`SELECT COUNT(*) FROM messages_fts_code WHERE messages_fts_code MATCH ?`, query).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	// Updates replace the old tokens rather than adding to them
+	if _, err := database.Exec(`UPDATE messages SET text_content = 'new_name' WHERE uuid = 'm1'`); err != nil {
+		t.Fatal(err)
+	}
+	if got := codeMatches("old_name"); got != 0 {
+		t.Errorf("old_name matches after update = %d, want 0", got)
+	}
+	if got := codeMatches("new_name"); got != 1 {
+		t.Errorf("new_name matches after update = %d, want 1", got)
+	}
+
+	if _, err := database.Exec(`DELETE FROM messages WHERE uuid = 'm1'`); err != nil {
+		t.Fatal(err)
+	}
+	if got := codeMatches("new_name"); got != 0 {
+		t.Errorf("new_name matches after delete = %d, want 0", got)
+	}
+}
@@ -1,6 +1,10 @@
 package db
 
-func (db *DB) initSchema() error {
+import "database/sql"
+
+// initSchema creates any missing tables, indexes and triggers. It only adds
+// objects that don't exist yet; changes to existing tables are migrations.
+func initSchema(tx *sql.Tx) error {
 	schema := `
 	-- Sessions table
 	CREATE TABLE IF NOT EXISTS sessions (
@@ -21,7 +25,12 @@ func (db *DB) initSchema() error {
 		last_synced_at DATETIME,
 		file_hash TEXT,
 		file_size INTEGER,
-		file_mtime DATETIME
+		file_mtime DATETIME,
+		model TEXT,                      -- Model of the most recent assistant response
+		input_tokens INTEGER DEFAULT 0,  -- Token usage rolled up from messages
+		output_tokens INTEGER DEFAULT 0,
+		cache_read_tokens INTEGER DEFAULT 0,
+		cache_creation_tokens INTEGER DEFAULT 0
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_sessions_session_id ON sessions(session_id);
@@ -42,9 +51,17 @@ func (db *DB) initSchema() error {
 		timestamp DATETIME,
 		sequence INTEGER,
 		is_sidechain BOOLEAN,
+		is_tool_only BOOLEAN DEFAULT 0,
 		cwd TEXT,
 		git_branch TEXT,
 		version TEXT,
+		model TEXT,                      -- Assistant messages: model and API token usage
+		api_message_id TEXT,             -- API response ID, shared by the entries it was split into
+		input_tokens INTEGER DEFAULT 0,  -- Usage is stored on the first entry of each response only
+		output_tokens INTEGER DEFAULT 0,
+		cache_read_tokens INTEGER DEFAULT 0,
+		cache_creation_tokens INTEGER DEFAULT 0,
+		agent_id TEXT,                   -- Sidechain messages: the subagent run they belong to
 		FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
 	);
 
@@ -52,6 +69,8 @@ func (db *DB) initSchema() error {
 	CREATE INDEX IF NOT EXISTS idx_messages_session_id ON messages(session_id);
 	CREATE INDEX IF NOT EXISTS idx_messages_parent_uuid ON messages(parent_uuid);
 	CREATE INDEX IF NOT EXISTS idx_messages_timestamp ON messages(timestamp);
+	CREATE INDEX IF NOT EXISTS idx_messages_api_message_id ON messages(api_message_id);
+	CREATE INDEX IF NOT EXISTS idx_messages_agent_id ON messages(agent_id);
 
 	-- Tool uses table
 	CREATE TABLE IF NOT EXISTS tool_uses (
@@ -61,12 +80,15 @@ func (db *DB) initSchema() error {
 		tool_id TEXT,
 		input TEXT,
 		output TEXT,
+		agent_id TEXT,                   -- Task calls: the subagent run this call spawned
 		created_at DATETIME,
 		FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE
 	);
 
 	CREATE INDEX IF NOT EXISTS idx_tool_uses_message_id ON tool_uses(message_id);
 	CREATE INDEX IF NOT EXISTS idx_tool_uses_tool_name ON tool_uses(tool_name);
+	CREATE INDEX IF NOT EXISTS idx_tool_uses_tool_id ON tool_uses(tool_id);
+	CREATE INDEX IF NOT EXISTS idx_tool_uses_agent_id ON tool_uses(agent_id);
 
 	-- Import log table
 	CREATE TABLE IF NOT EXISTS import_log (
@@ -82,40 +104,207 @@ func (db *DB) initSchema() error {
 
 	CREATE INDEX IF NOT EXISTS idx_import_log_file_hash ON import_log(file_hash);
 
+	-- Malformed lines skipped while importing (see import_log status 'partial')
+	CREATE TABLE IF NOT EXISTS import_diagnostics (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		import_log_id INTEGER NOT NULL,
+		file_path TEXT NOT NULL,
+		line_number INTEGER NOT NULL,
+		byte_offset INTEGER NOT NULL,
+		error TEXT NOT NULL,
+		FOREIGN KEY (import_log_id) REFERENCES import_log(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_import_diagnostics_file_path ON import_diagnostics(file_path);
+
+	-- Per-file position for incremental sync (resume parsing at byte_offset)
+	CREATE TABLE IF NOT EXISTS import_state (
+		file_path TEXT PRIMARY KEY,
+		session_id TEXT NOT NULL,
+		byte_offset INTEGER NOT NULL,    -- End of the last complete line imported
+		line_count INTEGER NOT NULL,     -- Lines before byte_offset (sequence numbers continue from here)
+		prefix_hash TEXT NOT NULL,       -- Sampled hash of bytes before byte_offset, to detect rewrites
+		file_size INTEGER NOT NULL,
+		file_mtime DATETIME NOT NULL,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Session summaries (progressive summarization)
+	CREATE TABLE IF NOT EXISTS session_summaries (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		session_id INTEGER NOT NULL UNIQUE,
+		one_line_summary TEXT,           -- Short summary for lists (10-15 words)
+		full_summary TEXT,               -- Detailed summary (2-4 paragraphs)
+		summary_version INTEGER DEFAULT 1, -- Increment when session extends
+		last_message_count INTEGER DEFAULT 0, -- Track if session has new messages
+		tokens_approx INTEGER,           -- Token count for context planning
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_session_summaries_session ON session_summaries(session_id);
+
+	-- Summary chunks (for long sessions - progressive chunking)
+	CREATE TABLE IF NOT EXISTS summary_chunks (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		session_id INTEGER NOT NULL,
+		chunk_index INTEGER NOT NULL,    -- Which chunk (0, 1, 2...)
+		message_start INTEGER,           -- Start message sequence
+		message_end INTEGER,             -- End message sequence
+		summary TEXT,                    -- Summary of this chunk
+		tokens_approx INTEGER,           -- Token count
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE,
+		UNIQUE(session_id, chunk_index)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_summary_chunks_session ON summary_chunks(session_id, chunk_index);
+
+	-- Extracted issue IDs for instant lookups
+	CREATE TABLE IF NOT EXISTS session_issues (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		session_id INTEGER NOT NULL,
+		issue_id TEXT NOT NULL,          -- e.g., "ENA-6530", "PROJ-1234"
+		issue_id_lower TEXT NOT NULL,    -- Lowercase for case-insensitive search
+		first_mention_seq INTEGER,       -- First message sequence mentioning it
+		last_mention_seq INTEGER,        -- Last message sequence mentioning it
+		mention_count INTEGER DEFAULT 1, -- How many times mentioned
+		FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE,
+		UNIQUE(session_id, issue_id_lower)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_session_issues_lookup ON session_issues(issue_id_lower);
+	CREATE INDEX IF NOT EXISTS idx_session_issues_session ON session_issues(session_id);
+
+	-- Extracted file paths for file-based lookups
+	CREATE TABLE IF NOT EXISTS session_files (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		session_id INTEGER NOT NULL,
+		file_path TEXT NOT NULL,         -- Files mentioned/modified
+		file_name TEXT NOT NULL,         -- Just the filename for easier search
+		mention_count INTEGER DEFAULT 1, -- How many times mentioned
+		first_mention_seq INTEGER,       -- First message sequence
+		last_mention_seq INTEGER,        -- Last message sequence
+		FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE,
+		UNIQUE(session_id, file_path)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_session_files_path ON session_files(file_path);
+	CREATE INDEX IF NOT EXISTS idx_session_files_name ON session_files(file_name);
+	CREATE INDEX IF NOT EXISTS idx_session_files_session ON session_files(session_id);
+
+	-- Embeddings of message text for semantic search, one per chunk of a
+	-- message per embedder (vectors from different embedders aren't comparable)
+	CREATE TABLE IF NOT EXISTS message_embeddings (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		message_id INTEGER NOT NULL,
+		chunk_index INTEGER NOT NULL,    -- Which chunk of the message (0, 1, 2...)
+		chunk_start INTEGER NOT NULL,    -- Chunk offset in text_content, in characters
+		chunk_length INTEGER NOT NULL,   -- Chunk length, in characters
+		embedder TEXT NOT NULL,          -- e.g., "hash-384", "ollama:nomic-embed-text"
+		dimensions INTEGER NOT NULL,
+		vector BLOB NOT NULL,            -- Little-endian float32s
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE,
+		UNIQUE(message_id, chunk_index, embedder)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_message_embeddings_embedder ON message_embeddings(embedder);
+
+	-- Named searches to rerun (ccrider search @name)
+	CREATE TABLE IF NOT EXISTS saved_searches (
+		name TEXT PRIMARY KEY,
+		query TEXT NOT NULL,             -- Query language, filters included
+		mode TEXT NOT NULL DEFAULT '',   -- "", "regex", "semantic" or "hybrid"
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Recent searches, newest last (trimmed to searchHistoryLimit)
+	CREATE TABLE IF NOT EXISTS search_history (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		query TEXT NOT NULL,
+		mode TEXT NOT NULL DEFAULT '',
+		searched_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Unfinished ccrider summarize runs, so an interrupted run can resume
+	CREATE TABLE IF NOT EXISTS summary_jobs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		params TEXT NOT NULL,            -- Options the run was started with
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS summary_job_items (
+		job_id INTEGER NOT NULL,
+		session_id INTEGER NOT NULL,
+		position INTEGER NOT NULL,       -- Order sessions were selected in
+		status TEXT NOT NULL DEFAULT 'pending', -- pending, done, skipped or failed
+		error TEXT,
+		PRIMARY KEY (job_id, session_id),
+		FOREIGN KEY (job_id) REFERENCES summary_jobs(id) ON DELETE CASCADE,
+		FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+	);
+
+	-- Structured part of LLM summaries: what the session set out to do, how
+	-- it ended, and lists of decisions, open TODOs, commands and files
+	CREATE TABLE IF NOT EXISTS summary_details (
+		session_id INTEGER PRIMARY KEY,
+		goal TEXT,
+		outcome TEXT,                    -- done, abandoned or blocked ('' if unclear)
+		FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+	);
+
+	CREATE TABLE IF NOT EXISTS summary_detail_items (
+		session_id INTEGER NOT NULL,
+		kind TEXT NOT NULL,              -- decision, todo, command or file
+		position INTEGER NOT NULL,
+		text TEXT NOT NULL,
+		PRIMARY KEY (session_id, kind, position),
+		FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+	);
+
 	-- FTS5 tables for full-text search
-	-- Natural language search with porter stemming
+	-- Natural language search with porter stemming. User and assistant text
+	-- are separate columns so bm25() can weight them differently.
+	CREATE VIEW IF NOT EXISTS messages_fts_content AS
+	SELECT
+		id,
+		CASE WHEN type = 'user' THEN text_content END AS user_text,
+		CASE WHEN type = 'user' THEN NULL ELSE text_content END AS assistant_text
+	FROM messages;
+
 	CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts USING fts5(
-		text_content,
-		content=messages,
+		user_text,
+		assistant_text,
+		content=messages_fts_content,
 		content_rowid=id,
 		tokenize='porter unicode61'
 	);
 
-	-- Code search without stemming (preserves symbols, camelCase)
+	-- Code search without stemming (preserves camelCase and snake_case identifiers)
 	CREATE VIRTUAL TABLE IF NOT EXISTS messages_fts_code USING fts5(
 		text_content,
 		content=messages,
 		content_rowid=id,
-		tokenize='unicode61'
+		tokenize="unicode61 tokenchars '_'"
 	);
 
+	-- Session summaries, for ranking sessions whose summary matches
+	CREATE VIRTUAL TABLE IF NOT EXISTS sessions_fts USING fts5(
+		summary,
+		tokenize='porter unicode61'
+	);
+
+	-- Tool call inputs and outputs (commands, errors, test output)
+	` + toolUsesFTS + `;
+
 	-- Triggers to keep FTS in sync
-	CREATE TRIGGER IF NOT EXISTS messages_ai AFTER INSERT ON messages BEGIN
-		INSERT INTO messages_fts(rowid, text_content) VALUES (new.id, new.text_content);
-		INSERT INTO messages_fts_code(rowid, text_content) VALUES (new.id, new.text_content);
-	END;
-
-	CREATE TRIGGER IF NOT EXISTS messages_ad AFTER DELETE ON messages BEGIN
-		DELETE FROM messages_fts WHERE rowid = old.id;
-		DELETE FROM messages_fts_code WHERE rowid = old.id;
-	END;
-
-	CREATE TRIGGER IF NOT EXISTS messages_au AFTER UPDATE ON messages BEGIN
-		UPDATE messages_fts SET text_content = new.text_content WHERE rowid = new.id;
-		UPDATE messages_fts_code SET text_content = new.text_content WHERE rowid = new.id;
-	END;
+	` + ftsTriggers + `
+	` + toolFTSTriggers + `
 	`
 
-	_, err := db.conn.Exec(schema)
+	_, err := tx.Exec(schema)
 	return err
 }
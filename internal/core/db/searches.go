@@ -0,0 +1,142 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// searchHistoryLimit is how many recent searches are kept
+const searchHistoryLimit = 200
+
+// SavedSearch is a named search query. Query is in the search query
+// language, filters included; Mode is how it is matched ("" for the query
+// language, or "regex", "semantic" or "hybrid").
+type SavedSearch struct {
+	Name      string
+	Query     string
+	Mode      string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// SearchHistoryEntry is a search that was run
+type SearchHistoryEntry struct {
+	Query      string
+	Mode       string
+	SearchedAt time.Time
+}
+
+// SaveSearch saves a named search, replacing any saved under the same name
+func (db *DB) SaveSearch(name, query, mode string) error {
+	_, err := db.Exec(`
+		INSERT INTO saved_searches (name, query, mode)
+		VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			query = excluded.query,
+			mode = excluded.mode,
+			updated_at = CURRENT_TIMESTAMP
+	`, name, query, mode)
+	return err
+}
+
+// GetSavedSearch returns the search saved under name, or nil if there is none
+func (db *DB) GetSavedSearch(name string) (*SavedSearch, error) {
+	var s SavedSearch
+	err := db.QueryRow(`
+		SELECT name, query, mode, created_at, updated_at
+		FROM saved_searches WHERE name = ?
+	`, name).Scan(&s.Name, &s.Query, &s.Mode, &s.CreatedAt, &s.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// ListSavedSearches returns every saved search, by name
+func (db *DB) ListSavedSearches() ([]SavedSearch, error) {
+	rows, err := db.Query(`
+		SELECT name, query, mode, created_at, updated_at
+		FROM saved_searches ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var searches []SavedSearch
+	for rows.Next() {
+		var s SavedSearch
+		if err := rows.Scan(&s.Name, &s.Query, &s.Mode, &s.CreatedAt, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		searches = append(searches, s)
+	}
+	return searches, rows.Err()
+}
+
+// DeleteSavedSearch deletes a saved search, reporting whether it existed
+func (db *DB) DeleteSavedSearch(name string) (bool, error) {
+	result, err := db.Exec(`DELETE FROM saved_searches WHERE name = ?`, name)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// AddSearchHistory records a search as the most recent, moving it up if it
+// was run before, and drops the oldest beyond searchHistoryLimit
+func (db *DB) AddSearchHistory(query, mode string) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	stmts := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`DELETE FROM search_history WHERE query = ? AND mode = ?`, []interface{}{query, mode}},
+		{`INSERT INTO search_history (query, mode) VALUES (?, ?)`, []interface{}{query, mode}},
+		{`DELETE FROM search_history WHERE id NOT IN (
+			SELECT id FROM search_history ORDER BY id DESC LIMIT ?
+		)`, []interface{}{searchHistoryLimit}},
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt.query, stmt.args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ListSearchHistory returns up to limit recent searches, most recent first
+func (db *DB) ListSearchHistory(limit int) ([]SearchHistoryEntry, error) {
+	rows, err := db.Query(`
+		SELECT query, mode, searched_at
+		FROM search_history ORDER BY id DESC LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var entries []SearchHistoryEntry
+	for rows.Next() {
+		var e SearchHistoryEntry
+		if err := rows.Scan(&e.Query, &e.Mode, &e.SearchedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,114 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestSavedSearches(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Remove(tmpfile.Name()) }()
+	_ = tmpfile.Close()
+
+	database, err := New(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	if err := database.SaveSearch("flaky", "flaky test", ""); err != nil {
+		t.Fatalf("SaveSearch() error = %v", err)
+	}
+	if err := database.SaveSearch("rollback", "migration rollback project:billing", ""); err != nil {
+		t.Fatalf("SaveSearch() error = %v", err)
+	}
+	// Saving under the same name replaces it
+	if err := database.SaveSearch("flaky", `flaky \w+Test`, "regex"); err != nil {
+		t.Fatalf("SaveSearch() error = %v", err)
+	}
+
+	s, err := database.GetSavedSearch("flaky")
+	if err != nil {
+		t.Fatalf("GetSavedSearch() error = %v", err)
+	}
+	if s == nil || s.Query != `flaky \w+Test` || s.Mode != "regex" {
+		t.Errorf("Expected the replaced search, got %+v", s)
+	}
+	if s, err := database.GetSavedSearch("missing"); s != nil || err != nil {
+		t.Errorf("Expected nil for a missing search, got %+v, %v", s, err)
+	}
+
+	searches, err := database.ListSavedSearches()
+	if err != nil {
+		t.Fatalf("ListSavedSearches() error = %v", err)
+	}
+	if len(searches) != 2 || searches[0].Name != "flaky" || searches[1].Name != "rollback" {
+		t.Errorf("Expected flaky and rollback, got %+v", searches)
+	}
+
+	if deleted, err := database.DeleteSavedSearch("flaky"); !deleted || err != nil {
+		t.Errorf("DeleteSavedSearch() = %v, %v", deleted, err)
+	}
+	if deleted, _ := database.DeleteSavedSearch("flaky"); deleted {
+		t.Error("Expected a second delete to find nothing")
+	}
+}
+
+func TestSearchHistory(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Remove(tmpfile.Name()) }()
+	_ = tmpfile.Close()
+
+	database, err := New(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	for _, q := range []string{"one", "two", "one", "three"} {
+		if err := database.AddSearchHistory(q, ""); err != nil {
+			t.Fatalf("AddSearchHistory() error = %v", err)
+		}
+	}
+	if err := database.AddSearchHistory("one", "regex"); err != nil {
+		t.Fatalf("AddSearchHistory() error = %v", err)
+	}
+
+	entries, err := database.ListSearchHistory(10)
+	if err != nil {
+		t.Fatalf("ListSearchHistory() error = %v", err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Query+"/"+e.Mode)
+	}
+	// Rerunning a search moves it up rather than repeating it
+	if fmt.Sprint(got) != "[one/regex three/ one/ two/]" {
+		t.Errorf("History = %v", got)
+	}
+
+	// Only the most recent are kept
+	for i := 0; i < searchHistoryLimit+10; i++ {
+		if err := database.AddSearchHistory(fmt.Sprintf("q%d", i), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var count int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM search_history`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != searchHistoryLimit {
+		t.Errorf("Expected %d entries kept, got %d", searchHistoryLimit, count)
+	}
+	entries, _ = database.ListSearchHistory(1)
+	if len(entries) != 1 || entries[0].Query != fmt.Sprintf("q%d", searchHistoryLimit+9) {
+		t.Errorf("Expected the latest search first, got %+v", entries)
+	}
+}
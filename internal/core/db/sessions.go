@@ -1,15 +1,24 @@
 package db
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 )
 
+// VisibleMessageCondition is the SQL condition for messages shown to users.
+// Tool-only turns and metadata entries (system, file-history-snapshot) are
+// stored so the conversation chain is complete, but they have no text and
+// are hidden from counts, lists and exports.
+const VisibleMessageCondition = "TRIM(COALESCE(text_content, '')) != ''"
+
 // Session represents a session returned from ListSessions
 type Session struct {
 	SessionID    string
 	Summary      string
 	ProjectPath  string
 	LastCwd      string // Last working directory from messages
+	Branch       string // Git branch the session started on
 	MessageCount int
 	UpdatedAt    time.Time
 	CreatedAt    time.Time
@@ -69,12 +78,13 @@ func (db *DB) ListSessions(projectPath string) ([]Session, error) {
 				s.cwd,
 				s.project_path
 			) as last_cwd,
-			(SELECT COUNT(*) FROM messages WHERE session_id = s.id) as actual_message_count,
+			COALESCE(s.git_branch, ''),
+			(SELECT COUNT(*) FROM messages WHERE session_id = s.id AND ` + VisibleMessageCondition + `) as actual_message_count,
 			s.updated_at,
 			s.created_at
 		FROM sessions s
 		LEFT JOIN session_summaries ss ON s.id = ss.session_id
-		WHERE (SELECT COUNT(*) FROM messages WHERE session_id = s.id) > 0
+		WHERE (SELECT COUNT(*) FROM messages WHERE session_id = s.id AND ` + VisibleMessageCondition + `) > 0
 		  -- Exclude sessions with no meaningful content
 		  AND NOT (
 			  -- Has bad/empty summary
@@ -119,6 +129,7 @@ func (db *DB) ListSessions(projectPath string) ([]Session, error) {
 			&s.Summary,
 			&s.ProjectPath,
 			&s.LastCwd,
+			&s.Branch,
 			&s.MessageCount,
 			&s.UpdatedAt,
 			&s.CreatedAt,
@@ -139,7 +150,7 @@ func (db *DB) GetSessionLaunchInfo(sessionID string) (*Session, string, error) {
 			s.session_id,
 			COALESCE(s.summary, ''),
 			s.project_path,
-			(SELECT COUNT(*) FROM messages WHERE session_id = s.id) as actual_message_count,
+			(SELECT COUNT(*) FROM messages WHERE session_id = s.id AND ` + VisibleMessageCondition + `) as actual_message_count,
 			s.updated_at,
 			s.created_at,
 			COALESCE(
@@ -178,10 +189,11 @@ func (db *DB) GetSessionDetail(sessionID string) (*SessionDetail, error) {
 	// First get the session metadata
 	query := `
 		SELECT
+			id,
 			session_id,
 			COALESCE(summary, ''),
 			project_path,
-			(SELECT COUNT(*) FROM messages WHERE session_id = s.id) as message_count,
+			(SELECT COUNT(*) FROM messages WHERE session_id = s.id AND ` + VisibleMessageCondition + `) as message_count,
 			COALESCE(
 				(SELECT cwd FROM messages
 				 WHERE session_id = s.id
@@ -191,34 +203,60 @@ func (db *DB) GetSessionDetail(sessionID string) (*SessionDetail, error) {
 				 ORDER BY sequence DESC LIMIT 1),
 				s.project_path
 			) as last_cwd,
-			updated_at
+			created_at,
+			updated_at,
+			COALESCE(model, ''),
+			COALESCE(input_tokens, 0),
+			COALESCE(output_tokens, 0),
+			COALESCE(cache_read_tokens, 0),
+			COALESCE(cache_creation_tokens, 0)
 		FROM sessions s
 		WHERE session_id = ?
 	`
 
 	var detail SessionDetail
+	var id int64
 	err := db.QueryRow(query, sessionID).Scan(
+		&id,
 		&detail.SessionID,
 		&detail.Summary,
 		&detail.ProjectPath,
 		&detail.MessageCount,
 		&detail.LastCwd,
+		&detail.CreatedAt,
 		&detail.UpdatedAt,
+		&detail.Model,
+		&detail.Usage.InputTokens,
+		&detail.Usage.OutputTokens,
+		&detail.Usage.CacheReadTokens,
+		&detail.Usage.CacheCreationTokens,
 	)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get all messages for this session
+	detail.SummaryDetails, err = db.GetSummaryDetails(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get all messages for this session (visible ones plus tool-only turns,
+	// which callers collapse or skip; metadata entries are left out)
 	messagesQuery := `
 		SELECT
-			type,
-			sender,
-			text_content,
-			timestamp
-		FROM messages
-		WHERE session_id = (SELECT id FROM sessions WHERE session_id = ?)
-		ORDER BY sequence ASC
+			m.uuid,
+			m.type,
+			m.sender,
+			m.text_content,
+			m.timestamp,
+			COALESCE(m.is_tool_only, 0),
+			COALESCE((SELECT GROUP_CONCAT(tool_name, ',') FROM tool_uses WHERE message_id = m.id), ''),
+			COALESCE(m.is_sidechain, 0),
+			COALESCE(m.agent_id, '')
+		FROM messages m
+		WHERE m.session_id = (SELECT id FROM sessions WHERE session_id = ?)
+		  AND (m.is_tool_only = 1 OR ` + VisibleMessageCondition + `)
+		ORDER BY m.sequence ASC
 	`
 
 	rows, err := db.Query(messagesQuery, sessionID)
@@ -227,16 +265,120 @@ func (db *DB) GetSessionDetail(sessionID string) (*SessionDetail, error) {
 	}
 	defer func() { _ = rows.Close() }()
 
+	// Subagent (sidechain) messages are grouped into their runs rather than
+	// interleaved with the main conversation
+	var subagents []*SubagentRun
+	subagentsByID := make(map[string]*SubagentRun)
+
 	for rows.Next() {
 		var msg SessionMessage
-		err := rows.Scan(&msg.Type, &msg.Sender, &msg.Content, &msg.Timestamp)
+		var toolNames, agentID string
+		var isSidechain bool
+		err := rows.Scan(&msg.UUID, &msg.Type, &msg.Sender, &msg.Content, &msg.Timestamp, &msg.IsToolOnly, &toolNames, &isSidechain, &agentID)
 		if err != nil {
 			return nil, err
 		}
-		detail.Messages = append(detail.Messages, msg)
+		if toolNames != "" {
+			msg.ToolNames = strings.Split(toolNames, ",")
+		}
+		if !isSidechain {
+			detail.Messages = append(detail.Messages, msg)
+			continue
+		}
+
+		msg.Active = true
+		run, ok := subagentsByID[agentID]
+		if !ok {
+			run = &SubagentRun{AgentID: agentID}
+			subagentsByID[agentID] = run
+			subagents = append(subagents, run)
+		}
+		run.Messages = append(run.Messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	// Nest each run under the message whose Task call spawned it
+	if len(subagents) > 0 {
+		links, err := db.subagentLinks(sessionID)
+		if err != nil {
+			return nil, err
+		}
+		linked := make(map[string]bool)
+		for i := range detail.Messages {
+			for _, link := range links[detail.Messages[i].UUID] {
+				run, ok := subagentsByID[link.AgentID]
+				if !ok || linked[link.AgentID] {
+					continue
+				}
+				run.ToolUseID = link.ToolUseID
+				run.Description = link.Description
+				run.SubagentType = link.SubagentType
+				detail.Messages[i].Subagents = append(detail.Messages[i].Subagents, *run)
+				linked[link.AgentID] = true
+			}
+		}
+		for _, run := range subagents {
+			if !linked[run.AgentID] {
+				detail.UnlinkedSubagents = append(detail.UnlinkedSubagents, *run)
+			}
+		}
+	}
+
+	// Mark which messages are on the active path and which were abandoned
+	tree, err := db.GetConversationTree(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range detail.Messages {
+		if node, ok := tree.Nodes[detail.Messages[i].UUID]; ok {
+			detail.Messages[i].Active = node.Active
+			detail.Messages[i].Branch = node.Branch
+		}
 	}
 
-	return &detail, rows.Err()
+	return &detail, nil
+}
+
+// subagentLinks returns the Task calls in a session that spawned a subagent
+// run, keyed by the uuid of the message making the call
+func (db *DB) subagentLinks(sessionID string) (map[string][]SubagentRun, error) {
+	rows, err := db.Query(`
+		SELECT m.uuid, COALESCE(t.tool_id, ''), t.agent_id, COALESCE(t.input, '')
+		FROM tool_uses t
+		JOIN messages m ON m.id = t.message_id
+		WHERE m.session_id = (SELECT id FROM sessions WHERE session_id = ?)
+		  AND t.agent_id IS NOT NULL
+		ORDER BY t.id
+	`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	links := make(map[string][]SubagentRun)
+	for rows.Next() {
+		var uuid, input string
+		var run SubagentRun
+		if err := rows.Scan(&uuid, &run.ToolUseID, &run.AgentID, &input); err != nil {
+			return nil, err
+		}
+
+		var taskInput struct {
+			Description  string `json:"description"`
+			SubagentType string `json:"subagent_type"`
+		}
+		if json.Unmarshal([]byte(input), &taskInput) == nil {
+			run.Description = taskInput.Description
+			run.SubagentType = taskInput.SubagentType
+		}
+		links[uuid] = append(links[uuid], run)
+	}
+	return links, rows.Err()
 }
 
 // SessionDetail represents full session information including messages
@@ -246,14 +388,52 @@ type SessionDetail struct {
 	ProjectPath  string
 	MessageCount int
 	LastCwd      string // Last working directory from messages
+	CreatedAt    time.Time
 	UpdatedAt    time.Time
+	Model        string           // Model of the most recent assistant response
+	Usage        TokenUsage       // Token usage summed over the session
+	Messages     []SessionMessage // Main conversation; subagent runs are nested in it
+
+	// Structured LLM summary (goal, outcome, open TODOs...), nil if the
+	// session hasn't been summarized with one
+	SummaryDetails *SummaryDetails
+
+	// Subagent runs whose Task call couldn't be found
+	UnlinkedSubagents []SubagentRun
+}
+
+// SubagentRun is the transcript of a subagent launched by a Task call
+type SubagentRun struct {
+	AgentID      string
+	ToolUseID    string // The Task call that spawned it, if known
+	Description  string // From the Task call's input
+	SubagentType string
 	Messages     []SessionMessage
 }
 
+// TokenUsage holds API token counts for a message, session or report row
+type TokenUsage struct {
+	InputTokens         int
+	OutputTokens        int
+	CacheReadTokens     int
+	CacheCreationTokens int
+}
+
+// Total returns the sum of all token counts
+func (u TokenUsage) Total() int {
+	return u.InputTokens + u.OutputTokens + u.CacheReadTokens + u.CacheCreationTokens
+}
+
 // SessionMessage represents a single message in a session
 type SessionMessage struct {
-	Type      string
-	Sender    string
-	Content   string
-	Timestamp time.Time
+	UUID       string
+	Type       string
+	Sender     string
+	Content    string
+	Timestamp  time.Time
+	IsToolOnly bool          // No text, only tool_use/tool_result blocks
+	ToolNames  []string      // Tools invoked by this message, if any
+	Active     bool          // On the active path (see ConversationTree)
+	Branch     int           // 0 on the active path, otherwise which abandoned branch
+	Subagents  []SubagentRun // Subagent runs spawned by this message's Task calls
 }
@@ -14,6 +14,7 @@ type Stats struct {
 	NewestSession          time.Time
 	MostActiveProject      string
 	MostActiveProjectCount int
+	Usage                  TokenUsage
 }
 
 // GetStats returns comprehensive database statistics
@@ -38,6 +39,19 @@ func (db *DB) GetStats() (*Stats, error) {
 		return nil, err
 	}
 
+	// Token usage (rolled up per session at import)
+	err = db.QueryRow(`
+		SELECT
+			COALESCE(SUM(input_tokens), 0),
+			COALESCE(SUM(output_tokens), 0),
+			COALESCE(SUM(cache_read_tokens), 0),
+			COALESCE(SUM(cache_creation_tokens), 0)
+		FROM sessions
+	`).Scan(&stats.Usage.InputTokens, &stats.Usage.OutputTokens, &stats.Usage.CacheReadTokens, &stats.Usage.CacheCreationTokens)
+	if err != nil {
+		return nil, err
+	}
+
 	// Date range (only if we have sessions)
 	if stats.TotalSessions > 0 {
 		var minCreated, maxUpdated sql.NullString
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Summary outcomes: how a session ended
+const (
+	OutcomeDone      = "done"
+	OutcomeAbandoned = "abandoned"
+	OutcomeBlocked   = "blocked"
+)
+
+// Kinds of summary_detail_items rows
+const (
+	detailDecision = "decision"
+	detailTODO     = "todo"
+	detailCommand  = "command"
+	detailFile     = "file"
+)
+
+// SummaryDetails is the structured part of an LLM summary
+type SummaryDetails struct {
+	Goal         string   // What the session set out to do
+	Outcome      string   // OutcomeDone, OutcomeAbandoned, OutcomeBlocked, or "" if unclear
+	Decisions    []string // Key decisions made
+	TODOs        []string // Work left unfinished
+	Commands     []string // Notable commands run
+	FilesChanged []string
+}
+
+// lists returns the details' lists by summary_detail_items kind
+func (d *SummaryDetails) lists() map[string]*[]string {
+	return map[string]*[]string{
+		detailDecision: &d.Decisions,
+		detailTODO:     &d.TODOs,
+		detailCommand:  &d.Commands,
+		detailFile:     &d.FilesChanged,
+	}
+}
+
+// saveSummaryDetails replaces a session's summary details, deleting them
+// if details is nil
+func saveSummaryDetails(tx *sql.Tx, sessionID int64, details *SummaryDetails) error {
+	if _, err := tx.Exec(`DELETE FROM summary_details WHERE session_id = ?`, sessionID); err != nil {
+		return fmt.Errorf("delete summary details: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM summary_detail_items WHERE session_id = ?`, sessionID); err != nil {
+		return fmt.Errorf("delete summary details: %w", err)
+	}
+	if details == nil {
+		return nil
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO summary_details (session_id, goal, outcome) VALUES (?, ?, ?)
+	`, sessionID, details.Goal, details.Outcome)
+	if err != nil {
+		return fmt.Errorf("insert summary details: %w", err)
+	}
+	for kind, list := range details.lists() {
+		for i, text := range *list {
+			_, err := tx.Exec(`
+				INSERT INTO summary_detail_items (session_id, kind, position, text)
+				VALUES (?, ?, ?, ?)
+			`, sessionID, kind, i, text)
+			if err != nil {
+				return fmt.Errorf("insert summary %s: %w", kind, err)
+			}
+		}
+	}
+	return nil
+}
+
+// GetSummaryDetails returns the structured summary of a session (sessions.id),
+// or nil if it has none
+func (db *DB) GetSummaryDetails(sessionID int64) (*SummaryDetails, error) {
+	var d SummaryDetails
+	err := db.QueryRow(`
+		SELECT COALESCE(goal, ''), COALESCE(outcome, '')
+		FROM summary_details WHERE session_id = ?
+	`, sessionID).Scan(&d.Goal, &d.Outcome)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT kind, text FROM summary_detail_items
+		WHERE session_id = ?
+		ORDER BY kind, position
+	`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	lists := d.lists()
+	for rows.Next() {
+		var kind, text string
+		if err := rows.Scan(&kind, &text); err != nil {
+			return nil, err
+		}
+		if list, ok := lists[kind]; ok {
+			*list = append(*list, text)
+		}
+	}
+	return &d, rows.Err()
+}
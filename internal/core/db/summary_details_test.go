@@ -0,0 +1,76 @@
+package db
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestSummaryDetails(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Remove(tmpfile.Name()) }()
+	_ = tmpfile.Close()
+
+	database, err := New(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	result, err := database.Exec(`
+		INSERT INTO sessions (session_id, project_path, created_at, updated_at)
+		VALUES ('s1', '/test/project', datetime('now'), datetime('now'))
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, _ := result.LastInsertId()
+
+	details := &SummaryDetails{
+		Goal:         "Add OAuth login",
+		Outcome:      OutcomeBlocked,
+		Decisions:    []string{"Use PKCE", "Store tokens in the keychain"},
+		TODOs:        []string{"Handle token refresh"},
+		Commands:     []string{"go test ./auth/..."},
+		FilesChanged: []string{"auth/oauth.go", "auth/oauth_test.go"},
+	}
+	if err := database.SaveSessionSummary(SessionSummary{
+		SessionID: id, OneLine: "OAuth login", Full: "OAuth login with PKCE.", Version: 1, Details: details,
+	}); err != nil {
+		t.Fatalf("SaveSessionSummary() error = %v", err)
+	}
+
+	summary, err := database.GetSessionSummary(id)
+	if err != nil {
+		t.Fatalf("GetSessionSummary() error = %v", err)
+	}
+	if !reflect.DeepEqual(summary.Details, details) {
+		t.Errorf("GetSessionSummary().Details = %+v, want %+v", summary.Details, details)
+	}
+
+	detail, err := database.GetSessionDetail("s1")
+	if err != nil {
+		t.Fatalf("GetSessionDetail() error = %v", err)
+	}
+	if !reflect.DeepEqual(detail.SummaryDetails, details) {
+		t.Errorf("GetSessionDetail().SummaryDetails = %+v, want %+v", detail.SummaryDetails, details)
+	}
+
+	// A summary without structured fields replaces the old ones
+	if err := database.SaveSessionSummary(SessionSummary{SessionID: id, OneLine: "OAuth login", Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := database.GetSummaryDetails(id); got != nil || err != nil {
+		t.Errorf("GetSummaryDetails() = %+v, %v, want none", got, err)
+	}
+	var items int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM summary_detail_items`).Scan(&items); err != nil {
+		t.Fatal(err)
+	}
+	if items != 0 {
+		t.Errorf("%d detail items left after replacing the summary", items)
+	}
+}
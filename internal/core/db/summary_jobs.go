@@ -0,0 +1,127 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Summary job item statuses
+const (
+	JobItemPending = "pending"
+	JobItemDone    = "done"
+	JobItemSkipped = "skipped" // Nothing to summarize
+	JobItemFailed  = "failed"
+)
+
+// SummaryJob is an unfinished summarize run. A job only exists while its
+// run is in progress or was interrupted; finishing the run deletes it.
+type SummaryJob struct {
+	ID        int64
+	Params    string // Options the run was started with
+	Total     int    // Sessions selected for the run
+	Remaining int    // Sessions not yet done or skipped
+}
+
+// SummaryJobItem is a session still to be summarized by a job
+type SummaryJobItem struct {
+	SessionID   int64 // sessions.id
+	SessionUUID string
+	ProjectPath string
+}
+
+// FindSummaryJob returns the unfinished job started with params, or nil if
+// there is none
+func (db *DB) FindSummaryJob(params string) (*SummaryJob, error) {
+	var job SummaryJob
+	err := db.QueryRow(`
+		SELECT j.id, j.params,
+			(SELECT COUNT(*) FROM summary_job_items WHERE job_id = j.id),
+			(SELECT COUNT(*) FROM summary_job_items WHERE job_id = j.id AND status IN (?, ?))
+		FROM summary_jobs j
+		WHERE j.params = ?
+		ORDER BY j.id DESC LIMIT 1
+	`, JobItemPending, JobItemFailed, params).Scan(&job.ID, &job.Params, &job.Total, &job.Remaining)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// CreateSummaryJob starts a job over sessions (sessions.id, in the order to
+// summarize them), replacing any unfinished job
+func (db *DB) CreateSummaryJob(params string, sessionIDs []int64) (*SummaryJob, error) {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`DELETE FROM summary_jobs`); err != nil {
+		return nil, err
+	}
+	result, err := tx.Exec(`INSERT INTO summary_jobs (params) VALUES (?)`, params)
+	if err != nil {
+		return nil, err
+	}
+	jobID, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	for i, id := range sessionIDs {
+		if _, err := tx.Exec(`
+			INSERT OR IGNORE INTO summary_job_items (job_id, session_id, position)
+			VALUES (?, ?, ?)
+		`, jobID, id, i); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &SummaryJob{ID: jobID, Params: params, Total: len(sessionIDs), Remaining: len(sessionIDs)}, nil
+}
+
+// ListSummaryJobItems returns the job's sessions that are pending or
+// failed, in order
+func (db *DB) ListSummaryJobItems(jobID int64) ([]SummaryJobItem, error) {
+	rows, err := db.Query(`
+		SELECT s.id, s.session_id, s.project_path
+		FROM summary_job_items i
+		JOIN sessions s ON s.id = i.session_id
+		WHERE i.job_id = ? AND i.status IN (?, ?)
+		ORDER BY i.position
+	`, jobID, JobItemPending, JobItemFailed)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var items []SummaryJobItem
+	for rows.Next() {
+		var item SummaryJobItem
+		if err := rows.Scan(&item.SessionID, &item.SessionUUID, &item.ProjectPath); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// SetSummaryJobItemStatus records how a job's session went
+func (db *DB) SetSummaryJobItemStatus(jobID, sessionID int64, status, errMsg string) error {
+	_, err := db.Exec(`
+		UPDATE summary_job_items SET status = ?, error = NULLIF(?, '')
+		WHERE job_id = ? AND session_id = ?
+	`, status, errMsg, jobID, sessionID)
+	return err
+}
+
+// DeleteSummaryJob deletes a job once its run has finished
+func (db *DB) DeleteSummaryJob(jobID int64) error {
+	_, err := db.Exec(`DELETE FROM summary_jobs WHERE id = ?`, jobID)
+	return err
+}
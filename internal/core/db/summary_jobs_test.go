@@ -0,0 +1,90 @@
+package db
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSummaryJobs(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Remove(tmpfile.Name()) }()
+	_ = tmpfile.Close()
+
+	database, err := New(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	var ids []int64
+	for _, uuid := range []string{"s1", "s2", "s3"} {
+		result, err := database.Exec(`
+			INSERT INTO sessions (session_id, project_path, created_at, updated_at)
+			VALUES (?, '/test/project', datetime('now'), datetime('now'))
+		`, uuid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, _ := result.LastInsertId()
+		ids = append(ids, id)
+	}
+
+	if job, err := database.FindSummaryJob("limit=3"); job != nil || err != nil {
+		t.Fatalf("FindSummaryJob() = %+v, %v, want no job", job, err)
+	}
+
+	job, err := database.CreateSummaryJob("limit=3", []int64{ids[2], ids[0], ids[1]})
+	if err != nil {
+		t.Fatalf("CreateSummaryJob() error = %v", err)
+	}
+
+	// An interrupted run: one done, one failed, one not reached
+	if err := database.SetSummaryJobItemStatus(job.ID, ids[2], JobItemDone, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.SetSummaryJobItemStatus(job.ID, ids[0], JobItemFailed, "throttled"); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := database.FindSummaryJob("limit=3")
+	if err != nil || found == nil {
+		t.Fatalf("FindSummaryJob() = %+v, %v", found, err)
+	}
+	if found.ID != job.ID || found.Total != 3 || found.Remaining != 2 {
+		t.Errorf("FindSummaryJob() = %+v, want job %d with 2 of 3 left", found, job.ID)
+	}
+	if other, _ := database.FindSummaryJob("limit=10"); other != nil {
+		t.Errorf("Expected no job for other options, got %+v", other)
+	}
+
+	items, err := database.ListSummaryJobItems(job.ID)
+	if err != nil {
+		t.Fatalf("ListSummaryJobItems() error = %v", err)
+	}
+	if len(items) != 2 || items[0].SessionUUID != "s1" || items[1].SessionUUID != "s2" {
+		t.Errorf("ListSummaryJobItems() = %+v, want s1 then s2", items)
+	}
+
+	// A new run replaces the unfinished one
+	if _, err := database.CreateSummaryJob("limit=10", ids); err != nil {
+		t.Fatal(err)
+	}
+	if old, _ := database.FindSummaryJob("limit=3"); old != nil {
+		t.Errorf("Expected the old job to be replaced, got %+v", old)
+	}
+
+	next, _ := database.FindSummaryJob("limit=10")
+	if err := database.DeleteSummaryJob(next.ID); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM summary_job_items`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("Expected items deleted with the job, %d left", count)
+	}
+}
@@ -0,0 +1,209 @@
+package db
+
+import (
+	"database/sql"
+	"sort"
+)
+
+// ConversationNode is one message in a session's conversation tree
+type ConversationNode struct {
+	UUID        string
+	ParentUUID  string
+	Sequence    int
+	IsSidechain bool
+	Children    []*ConversationNode // In sequence order
+	Active      bool                // On the active path (sidechains always count as active)
+	Branch      int                 // 0 on the active path, otherwise which abandoned branch (1, 2, ...)
+}
+
+// ConversationTree is a session's messages linked by parent_uuid. Editing
+// an earlier prompt (Esc in Claude Code) forks the conversation: the old
+// replies stay in the file as an abandoned branch, and the conversation
+// continues from the new prompt.
+type ConversationTree struct {
+	Roots      []*ConversationNode // Messages whose parent isn't in this session
+	Nodes      map[string]*ConversationNode
+	ActiveLeaf string // Last message on the active path
+	Branches   int    // Number of abandoned branches
+}
+
+// GetConversationTree reconstructs the conversation tree for a session
+func (db *DB) GetConversationTree(sessionID string) (*ConversationTree, error) {
+	var sessionDBID int64
+	var leafUUID sql.NullString
+	err := db.QueryRow("SELECT id, leaf_uuid FROM sessions WHERE session_id = ?", sessionID).Scan(&sessionDBID, &leafUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`
+		SELECT uuid, COALESCE(parent_uuid, ''), COALESCE(sequence, 0), COALESCE(is_sidechain, 0)
+		FROM messages
+		WHERE session_id = ?
+		ORDER BY sequence
+	`, sessionDBID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var nodes []ConversationNode
+	for rows.Next() {
+		var n ConversationNode
+		if err := rows.Scan(&n.UUID, &n.ParentUUID, &n.Sequence, &n.IsSidechain); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return BuildConversationTree(nodes, leafUUID.String), nil
+}
+
+// BuildConversationTree links messages into a tree and marks the active path.
+//
+// Each root starts a separate chain (e.g. the continuation of a resumed
+// session, or a sidechain). The active path of the chain containing leafUUID
+// ends at the latest message descending from it, since the session may have
+// continued after the summary naming leafUUID was written; other chains end at
+// their latest message.
+func BuildConversationTree(messages []ConversationNode, leafUUID string) *ConversationTree {
+	tree := &ConversationTree{Nodes: make(map[string]*ConversationNode)}
+
+	nodes := make([]*ConversationNode, 0, len(messages))
+	for i := range messages {
+		n := messages[i]
+		n.Children = nil
+		n.Active = false
+		n.Branch = 0
+		if _, dup := tree.Nodes[n.UUID]; dup {
+			continue
+		}
+		tree.Nodes[n.UUID] = &n
+		nodes = append(nodes, &n)
+	}
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Sequence < nodes[j].Sequence
+	})
+
+	for _, n := range nodes {
+		parent, ok := tree.Nodes[n.ParentUUID]
+		if ok && parent != n {
+			parent.Children = append(parent.Children, n)
+		} else {
+			tree.Roots = append(tree.Roots, n)
+		}
+	}
+
+	var mainLeaf *ConversationNode
+	mainHasLeaf := false
+	for _, root := range tree.Roots {
+		chain := descendants(root)
+
+		start := chain
+		hasLeaf := containsNode(chain, tree.Nodes[leafUUID])
+		if hasLeaf {
+			start = descendants(tree.Nodes[leafUUID])
+		}
+		leaf := latestNode(start)
+
+		for n := leaf; n != nil && !n.Active; n = tree.Nodes[n.ParentUUID] {
+			n.Active = true
+		}
+
+		// The main chain is the one leafUUID names, otherwise the latest
+		if hasLeaf {
+			mainLeaf = leaf
+			mainHasLeaf = true
+		} else if !mainHasLeaf && !leaf.IsSidechain && (mainLeaf == nil || leaf.Sequence > mainLeaf.Sequence) {
+			mainLeaf = leaf
+		}
+
+		// Sidechains aren't abandoned branches, just not part of the main chain
+		for _, n := range chain {
+			if n.IsSidechain {
+				n.Active = true
+			}
+		}
+	}
+	if mainLeaf != nil {
+		tree.ActiveLeaf = mainLeaf.UUID
+	}
+
+	// Number abandoned branches in the order they were started
+	var branchStarts []*ConversationNode
+	for _, n := range nodes {
+		if n.Active {
+			for _, child := range n.Children {
+				if !child.Active {
+					branchStarts = append(branchStarts, child)
+				}
+			}
+		}
+	}
+	sort.SliceStable(branchStarts, func(i, j int) bool {
+		return branchStarts[i].Sequence < branchStarts[j].Sequence
+	})
+	for i, start := range branchStarts {
+		for _, n := range descendants(start) {
+			if !n.Active {
+				n.Branch = i + 1
+			}
+		}
+	}
+	tree.Branches = len(branchStarts)
+
+	return tree
+}
+
+// descendants returns a node and everything below it
+func descendants(root *ConversationNode) []*ConversationNode {
+	var result []*ConversationNode
+	seen := make(map[*ConversationNode]bool)
+	stack := []*ConversationNode{root}
+	for len(stack) > 0 {
+		n := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		result = append(result, n)
+		stack = append(stack, n.Children...)
+	}
+	return result
+}
+
+// latestNode returns the last-written main-chain message, falling back to
+// sidechain messages when that's all there is
+func latestNode(nodes []*ConversationNode) *ConversationNode {
+	var latest, latestAny *ConversationNode
+	for _, n := range nodes {
+		if latestAny == nil || n.Sequence > latestAny.Sequence {
+			latestAny = n
+		}
+		if !n.IsSidechain && (latest == nil || n.Sequence > latest.Sequence) {
+			latest = n
+		}
+	}
+	if latest == nil {
+		return latestAny
+	}
+	return latest
+}
+
+func containsNode(nodes []*ConversationNode, target *ConversationNode) bool {
+	if target == nil {
+		return false
+	}
+	for _, n := range nodes {
+		if n == target {
+			return true
+		}
+	}
+	return false
+}
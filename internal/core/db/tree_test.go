@@ -0,0 +1,97 @@
+package db
+
+import "testing"
+
+func TestBuildConversationTree(t *testing.T) {
+	// u1 -> a1 -> u2 -> a2 (abandoned when u2 was edited)
+	//          -> u3 -> a3 -> u4 (abandoned when u4 was edited)
+	//                      -> u5 -> a5
+	messages := []ConversationNode{
+		{UUID: "u1", Sequence: 1},
+		{UUID: "a1", ParentUUID: "u1", Sequence: 2},
+		{UUID: "u2", ParentUUID: "a1", Sequence: 3},
+		{UUID: "a2", ParentUUID: "u2", Sequence: 4},
+		{UUID: "u3", ParentUUID: "a1", Sequence: 5},
+		{UUID: "a3", ParentUUID: "u3", Sequence: 6},
+		{UUID: "u4", ParentUUID: "a3", Sequence: 7},
+		{UUID: "u5", ParentUUID: "a3", Sequence: 8},
+		{UUID: "a5", ParentUUID: "u5", Sequence: 9},
+	}
+
+	tree := BuildConversationTree(messages, "")
+
+	if tree.ActiveLeaf != "a5" {
+		t.Errorf("ActiveLeaf = %q, want a5", tree.ActiveLeaf)
+	}
+	if len(tree.Roots) != 1 || tree.Roots[0].UUID != "u1" {
+		t.Errorf("Expected a single root u1, got %d roots", len(tree.Roots))
+	}
+	if tree.Branches != 2 {
+		t.Errorf("Branches = %d, want 2", tree.Branches)
+	}
+
+	wantBranch := map[string]int{"u1": 0, "a1": 0, "u2": 1, "a2": 1, "u3": 0, "a3": 0, "u4": 2, "u5": 0, "a5": 0}
+	for uuid, branch := range wantBranch {
+		node := tree.Nodes[uuid]
+		if node.Branch != branch || node.Active != (branch == 0) {
+			t.Errorf("%s: Branch = %d, Active = %v, want branch %d", uuid, node.Branch, node.Active, branch)
+		}
+	}
+
+	if children := tree.Nodes["a1"].Children; len(children) != 2 || children[0].UUID != "u2" || children[1].UUID != "u3" {
+		t.Errorf("Expected a1 children [u2 u3] in sequence order")
+	}
+}
+
+func TestBuildConversationTree_LeafUUID(t *testing.T) {
+	messages := []ConversationNode{
+		{UUID: "u1", Sequence: 1},
+		{UUID: "a1", ParentUUID: "u1", Sequence: 2},
+		{UUID: "u2", ParentUUID: "u1", Sequence: 3},
+		{UUID: "a2", ParentUUID: "u2", Sequence: 4},
+	}
+
+	// leafUUID picks the branch even when it isn't the latest
+	tree := BuildConversationTree(messages, "a1")
+	if tree.ActiveLeaf != "a1" || tree.Nodes["u2"].Branch != 1 {
+		t.Errorf("ActiveLeaf = %q, u2 branch = %d, want a1 and 1", tree.ActiveLeaf, tree.Nodes["u2"].Branch)
+	}
+
+	// A stale leafUUID (the session continued after the summary) follows on to
+	// the latest message below it
+	tree = BuildConversationTree(messages, "u2")
+	if tree.ActiveLeaf != "a2" || tree.Nodes["a1"].Branch != 1 {
+		t.Errorf("ActiveLeaf = %q, a1 branch = %d, want a2 and 1", tree.ActiveLeaf, tree.Nodes["a1"].Branch)
+	}
+}
+
+func TestBuildConversationTree_SeparateChains(t *testing.T) {
+	messages := []ConversationNode{
+		{UUID: "u1", ParentUUID: "from-another-session", Sequence: 1},
+		{UUID: "a1", ParentUUID: "u1", Sequence: 2},
+		{UUID: "s1", IsSidechain: true, Sequence: 3},
+		{UUID: "s2", ParentUUID: "s1", IsSidechain: true, Sequence: 4},
+		{UUID: "s3", ParentUUID: "s1", IsSidechain: true, Sequence: 5},
+		{UUID: "snapshot", Sequence: 6},
+		{UUID: "u2", Sequence: 7},
+		{UUID: "a2", ParentUUID: "u2", Sequence: 8},
+	}
+
+	tree := BuildConversationTree(messages, "")
+
+	if len(tree.Roots) != 4 {
+		t.Errorf("Expected 4 roots, got %d", len(tree.Roots))
+	}
+	if tree.ActiveLeaf != "a2" {
+		t.Errorf("ActiveLeaf = %q, want a2", tree.ActiveLeaf)
+	}
+	// Earlier chains and sidechains aren't abandoned branches
+	if tree.Branches != 0 {
+		t.Errorf("Branches = %d, want 0", tree.Branches)
+	}
+	for uuid, node := range tree.Nodes {
+		if !node.Active {
+			t.Errorf("Expected %s to be active", uuid)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+)
+
+// MessageUsage is the token usage recorded for one assistant response
+type MessageUsage struct {
+	SessionID   string
+	ProjectPath string
+	GitBranch   string
+	Model       string
+	Timestamp   time.Time
+	Usage       TokenUsage
+}
+
+// ListMessageUsage returns every assistant message that recorded token usage,
+// oldest first, optionally filtered by project path (substring match) and to
+// responses at or after since and before until (zero times for no limit)
+func (db *DB) ListMessageUsage(projectPath string, since, until time.Time) ([]MessageUsage, error) {
+	query := `
+		SELECT
+			s.session_id,
+			s.project_path,
+			COALESCE(NULLIF(m.git_branch, ''), s.git_branch, ''),
+			COALESCE(m.model, ''),
+			m.timestamp,
+			m.input_tokens,
+			m.output_tokens,
+			m.cache_read_tokens,
+			m.cache_creation_tokens
+		FROM messages m
+		JOIN sessions s ON s.id = m.session_id
+		WHERE m.input_tokens + m.output_tokens + m.cache_read_tokens + m.cache_creation_tokens > 0
+	`
+	var args []interface{}
+	if projectPath != "" {
+		query += " AND s.project_path LIKE ?"
+		args = append(args, "%"+projectPath+"%")
+	}
+	// Timestamps are stored in UTC as "2006-01-02 15:04:05..." strings, so
+	// bounds in the same form compare correctly as text
+	if !since.IsZero() {
+		query += " AND m.timestamp >= ?"
+		args = append(args, since.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if !until.IsZero() {
+		query += " AND m.timestamp < ?"
+		args = append(args, until.UTC().Format("2006-01-02 15:04:05"))
+	}
+	query += " ORDER BY m.timestamp"
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	var usage []MessageUsage
+	for rows.Next() {
+		var u MessageUsage
+		var timestamp sql.NullTime
+		err := rows.Scan(
+			&u.SessionID,
+			&u.ProjectPath,
+			&u.GitBranch,
+			&u.Model,
+			&timestamp,
+			&u.Usage.InputTokens,
+			&u.Usage.OutputTokens,
+			&u.Usage.CacheReadTokens,
+			&u.Usage.CacheCreationTokens,
+		)
+		if err != nil {
+			return nil, err
+		}
+		u.Timestamp = timestamp.Time
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
+
+// Add returns the sum of two usages
+func (u TokenUsage) Add(other TokenUsage) TokenUsage {
+	return TokenUsage{
+		InputTokens:         u.InputTokens + other.InputTokens,
+		OutputTokens:        u.OutputTokens + other.OutputTokens,
+		CacheReadTokens:     u.CacheReadTokens + other.CacheReadTokens,
+		CacheCreationTokens: u.CacheCreationTokens + other.CacheCreationTokens,
+	}
+}
@@ -0,0 +1,134 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/neilberkman/ccrider/internal/core/db"
+)
+
+// Markdown renders a session as a markdown document. Subagent transcripts
+// are nested under the message that launched them in collapsible
+// <details> blocks.
+func Markdown(database *db.DB, sessionID string) (string, error) {
+	detail, err := database.GetSessionDetail(sessionID)
+	if err != nil {
+		return "", fmt.Errorf("session not found: %w", err)
+	}
+
+	var b strings.Builder
+
+	// Header
+	b.WriteString("# ")
+	b.WriteString(detail.Summary)
+	b.WriteString("\n\n")
+
+	// Metadata
+	b.WriteString("**Session ID:** `")
+	b.WriteString(detail.SessionID)
+	b.WriteString("`  \n")
+	b.WriteString("**Project:** `")
+	b.WriteString(detail.ProjectPath)
+	b.WriteString("`  \n")
+	b.WriteString("**Created:** ")
+	b.WriteString(formatTimestamp(detail.CreatedAt))
+	b.WriteString("  \n")
+	b.WriteString("**Updated:** ")
+	b.WriteString(formatTimestamp(detail.UpdatedAt))
+	b.WriteString("  \n")
+	b.WriteString("**Messages:** ")
+	b.WriteString(fmt.Sprintf("%d", detail.MessageCount))
+	b.WriteString("\n\n")
+	b.WriteString("---\n\n")
+
+	// Messages
+	for _, msg := range detail.Messages {
+		// Tool-only turns are skipped unless they launched a subagent
+		if msg.Type == "summary" || (msg.IsToolOnly && len(msg.Subagents) == 0) {
+			continue
+		}
+
+		if !msg.IsToolOnly {
+			writeMessage(&b, msg)
+		}
+		for _, run := range msg.Subagents {
+			writeSubagent(&b, run)
+		}
+
+		b.WriteString("---\n\n")
+	}
+
+	// Subagent runs whose Task call wasn't found go at the end
+	for _, run := range detail.UnlinkedSubagents {
+		writeSubagent(&b, run)
+	}
+
+	return b.String(), nil
+}
+
+func writeMessage(b *strings.Builder, msg db.SessionMessage) {
+	// Determine label
+	label := strings.ToUpper(msg.Type)
+	if msg.Sender != "" {
+		label = strings.ToUpper(msg.Sender)
+	}
+
+	// Message header
+	b.WriteString("**")
+	b.WriteString(label)
+	b.WriteString("**")
+	b.WriteString(" _")
+	b.WriteString(formatTimestamp(msg.Timestamp))
+	b.WriteString("_\n\n")
+
+	// Content (no truncation)
+	if msg.Content != "" {
+		b.WriteString(msg.Content)
+		b.WriteString("\n\n")
+	}
+}
+
+func writeSubagent(b *strings.Builder, run db.SubagentRun) {
+	b.WriteString("<details>\n<summary>")
+	b.WriteString(SubagentTitle(run))
+	b.WriteString("</summary>\n\n")
+
+	for _, msg := range run.Messages {
+		if !msg.IsToolOnly {
+			writeMessage(b, msg)
+		}
+	}
+
+	b.WriteString("</details>\n\n")
+}
+
+// SubagentTitle describes a subagent run in one line, e.g.
+// "Subagent (Explore): Find config loading - 12 messages"
+func SubagentTitle(run db.SubagentRun) string {
+	title := "Subagent"
+	if run.SubagentType != "" {
+		title += " (" + run.SubagentType + ")"
+	}
+	if run.Description != "" {
+		title += ": " + run.Description
+	}
+
+	count := 0
+	for _, msg := range run.Messages {
+		if !msg.IsToolOnly {
+			count++
+		}
+	}
+	if count == 1 {
+		return title + " - 1 message"
+	}
+	return fmt.Sprintf("%s - %d messages", title, count)
+}
+
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("Jan 02, 2006 15:04:05")
+}
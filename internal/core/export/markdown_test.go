@@ -0,0 +1,76 @@
+package export
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/neilberkman/ccrider/internal/core/db"
+	"github.com/neilberkman/ccrider/internal/core/importer"
+	"github.com/neilberkman/ccrider/pkg/ccsessions"
+)
+
+func TestMarkdown_Subagents(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Remove(tmpfile.Name()) }()
+	_ = tmpfile.Close()
+
+	database, err := db.New(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = database.Close() }()
+
+	imp := importer.New(database)
+	for _, file := range []string{"task-session.jsonl", "agent-task.jsonl"} {
+		session, err := ccsessions.ParseFile("../../../pkg/ccsessions/testdata/" + file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := imp.ImportSession(session); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	markdown, err := Markdown(database, "task-session")
+	if err != nil {
+		t.Fatalf("Markdown() error = %v", err)
+	}
+
+	if !strings.HasPrefix(markdown, "# Find config loading\n") {
+		t.Errorf("Expected summary heading, got %q", markdown[:40])
+	}
+	if !strings.Contains(markdown, "<summary>Subagent (Explore): Find config loading - 2 messages</summary>") {
+		t.Error("Expected the Explore run in a <details> block")
+	}
+
+	// The subagent's transcript sits inside its block, after the message
+	// that launched it
+	details := strings.Index(markdown, "<details>")
+	prompt := strings.Index(markdown, "Search for where config is loaded")
+	if details < 0 || prompt < details || strings.Count(markdown, "</details>") != 2 {
+		t.Errorf("Expected 2 nested transcripts, got:\n%s", markdown)
+	}
+}
+
+func TestMarkdown_NotFound(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Remove(tmpfile.Name()) }()
+	_ = tmpfile.Close()
+
+	database, err := db.New(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = database.Close() }()
+
+	if _, err := Markdown(database, "no-such-session"); err == nil {
+		t.Error("Expected error for unknown session")
+	}
+}
@@ -0,0 +1,203 @@
+// Package fuzzy ranks candidates against a typed pattern, fzf-style: the
+// pattern's characters must appear in order, and matches at word starts and
+// in unbroken runs score higher than scattered ones.
+package fuzzy
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Scores follow fzf's: each matched character scores scoreMatch plus a bonus
+// for where it falls, and gaps between matched characters cost a little
+const (
+	scoreMatch        = 16
+	scoreGapStart     = -3
+	scoreGapExtension = -1
+
+	bonusBoundary    = 8 // Start of the text or of a word (after a space, /, -, _, ...)
+	bonusCamel       = 7 // camelCase hump or letter-to-digit change
+	bonusConsecutive = 4 // Least bonus for continuing a run of matches
+
+	// The first character of the pattern counts its bonus double, so
+	// "fb" prefers foo_bar to afoo_bar
+	bonusFirstCharMultiplier = 2
+)
+
+// noMatch marks a pattern position that can't end at a text position
+const noMatch = -1 << 30
+
+// Result is how a pattern matched one candidate
+type Result struct {
+	Index     int   // Candidate's position in the input
+	Score     int   // Higher is better
+	Positions []int // Matched rune offsets in the candidate, ascending
+}
+
+// Match matches pattern against text. Each space-separated word of the
+// pattern must appear in text as a subsequence; the score is the sum of the
+// words' best alignments. Words ignore case unless they contain an uppercase
+// letter.
+func Match(pattern, text string) (Result, bool) {
+	words := strings.Fields(pattern)
+	runes := []rune(text)
+	bonuses := charBonuses(runes)
+
+	var result Result
+	seen := map[int]bool{}
+	for _, word := range words {
+		score, positions, ok := matchWord([]rune(word), runes, bonuses)
+		if !ok {
+			return Result{}, false
+		}
+		result.Score += score
+		for _, p := range positions {
+			if !seen[p] {
+				seen[p] = true
+				result.Positions = append(result.Positions, p)
+			}
+		}
+	}
+	sort.Ints(result.Positions)
+	return result, true
+}
+
+// Rank matches pattern against each candidate and returns the matches, best
+// first. Ties keep their input order, so candidates sorted by recency stay
+// that way. An empty pattern matches every candidate.
+func Rank(pattern string, candidates []string) []Result {
+	var results []Result
+	for i, candidate := range candidates {
+		if r, ok := Match(pattern, candidate); ok {
+			r.Index = i
+			results = append(results, r)
+		}
+	}
+	sort.SliceStable(results, func(a, b int) bool {
+		return results[a].Score > results[b].Score
+	})
+	return results
+}
+
+// matchWord finds the best-scoring alignment of word in text
+func matchWord(word, text []rune, bonuses []int) (int, []int, bool) {
+	caseSensitive := false
+	for _, r := range word {
+		if unicode.IsUpper(r) {
+			caseSensitive = true
+			break
+		}
+	}
+	equal := func(w, t rune) bool {
+		if caseSensitive {
+			return w == t
+		}
+		return w == unicode.ToLower(t)
+	}
+
+	// Cheap rejection before the full alignment
+	i := 0
+	for _, t := range text {
+		if i < len(word) && equal(word[i], t) {
+			i++
+		}
+	}
+	if i < len(word) {
+		return 0, nil, false
+	}
+
+	// score[i][j] is the best score for word[:i+1] with word[i] matched at
+	// text[j]; from[i][j] is where word[i-1] matched in that alignment, and
+	// run[i][j] the bonus carried by the run of matches ending there
+	n := len(text)
+	score := make([][]int, len(word))
+	from := make([][]int, len(word))
+	run := make([][]int, len(word))
+	for i := range word {
+		score[i] = make([]int, n)
+		from[i] = make([]int, n)
+		run[i] = make([]int, n)
+		for j := range score[i] {
+			score[i][j] = noMatch
+		}
+	}
+
+	for j, t := range text {
+		if equal(word[0], t) {
+			score[0][j] = scoreMatch + bonuses[j]*bonusFirstCharMultiplier
+			run[0][j] = bonuses[j]
+		}
+	}
+	for i := 1; i < len(word); i++ {
+		// Best alignment of word[:i] ending at least two characters back,
+		// with the gap penalty up to the current position
+		gapBest, gapFrom := noMatch, -1
+		for j := i; j < n; j++ {
+			if gapBest != noMatch {
+				gapBest += scoreGapExtension
+			}
+			if k := j - 2; k >= 0 && score[i-1][k] != noMatch && score[i-1][k]+scoreGapStart > gapBest {
+				gapBest, gapFrom = score[i-1][k]+scoreGapStart, k
+			}
+			if !equal(word[i], text[j]) {
+				continue
+			}
+
+			if gapBest != noMatch {
+				score[i][j] = gapBest + scoreMatch + bonuses[j]
+				from[i][j] = gapFrom
+				run[i][j] = bonuses[j]
+			}
+			if prev := score[i-1][j-1]; prev != noMatch {
+				bonus := max(run[i-1][j-1], bonuses[j], bonusConsecutive)
+				if s := prev + scoreMatch + bonus; s >= score[i][j] {
+					score[i][j] = s
+					from[i][j] = j - 1
+					run[i][j] = bonus
+				}
+			}
+		}
+	}
+
+	last := len(word) - 1
+	best, end := noMatch, -1
+	for j := range text {
+		if score[last][j] > best {
+			best, end = score[last][j], j
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+
+	positions := make([]int, len(word))
+	for i := last; i >= 0; i-- {
+		positions[i] = end
+		end = from[i][end]
+	}
+	return best, positions, true
+}
+
+// charBonuses scores where each character of text falls
+func charBonuses(text []rune) []int {
+	bonuses := make([]int, len(text))
+	for j, r := range text {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			continue
+		}
+		if j == 0 {
+			bonuses[j] = bonusBoundary
+			continue
+		}
+		prev := text[j-1]
+		switch {
+		case !unicode.IsLetter(prev) && !unicode.IsDigit(prev):
+			bonuses[j] = bonusBoundary
+		case unicode.IsLower(prev) && unicode.IsUpper(r),
+			unicode.IsLetter(prev) && unicode.IsDigit(r):
+			bonuses[j] = bonusCamel
+		}
+	}
+	return bonuses
+}
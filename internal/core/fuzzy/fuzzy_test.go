@@ -0,0 +1,75 @@
+package fuzzy
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern   string
+		text      string
+		ok        bool
+		positions []int
+	}{
+		{"mgr", "migration rollback", true, []int{0, 2, 3}},
+		{"rollback", "migration rollback", true, []int{10, 11, 12, 13, 14, 15, 16, 17}},
+		{"bil roll", "rollback in billing", true, []int{0, 1, 2, 3, 12, 13, 14}},
+		{"rlm", "migration rollback", false, nil},
+		{"Roll", "migration rollback", false, nil}, // Uppercase matches case
+		{"roll", "Migration Rollback", true, []int{10, 11, 12, 13}},
+		{"", "anything", true, nil},
+	}
+	for _, tt := range tests {
+		r, ok := Match(tt.pattern, tt.text)
+		if ok != tt.ok {
+			t.Errorf("Match(%q, %q) ok = %v, want %v", tt.pattern, tt.text, ok, tt.ok)
+			continue
+		}
+		if fmt.Sprint(r.Positions) != fmt.Sprint(tt.positions) {
+			t.Errorf("Match(%q, %q) positions = %v, want %v", tt.pattern, tt.text, r.Positions, tt.positions)
+		}
+	}
+}
+
+func TestMatch_PrefersWordStartsAndRuns(t *testing.T) {
+	better := []struct{ pattern, good, bad string }{
+		{"fb", "foo_bar", "xfxbx"},           // Word starts over scattered
+		{"auth", "auth refactor", "a u t h"}, // Unbroken run over gaps
+		{"ccr", "code/ccrider", "cache cleaner"},
+		{"fb", "fooBar", "foobar"}, // camelCase hump
+	}
+	for _, tt := range better {
+		good, ok1 := Match(tt.pattern, tt.good)
+		bad, ok2 := Match(tt.pattern, tt.bad)
+		if !ok1 || !ok2 {
+			t.Fatalf("Match(%q) should match both %q and %q", tt.pattern, tt.good, tt.bad)
+		}
+		if good.Score <= bad.Score {
+			t.Errorf("Match(%q): %q scored %d, want more than %q (%d)", tt.pattern, tt.good, good.Score, tt.bad, bad.Score)
+		}
+	}
+}
+
+func TestRank(t *testing.T) {
+	candidates := []string{
+		"Deflaky the suite  ~/code/api  main",
+		"Fix flaky test  ~/code/api  main",
+		"Unrelated work  ~/code/web  dev",
+		"Another flaky thing  ~/code/billing  fix-flaky",
+	}
+
+	results := Rank("flaky", candidates)
+	var order []int
+	for _, r := range results {
+		order = append(order, r.Index)
+	}
+	// Word matches win; ties keep input order
+	if fmt.Sprint(order) != "[1 3 0]" {
+		t.Errorf("Rank() order = %v, want [1 3 0]", order)
+	}
+
+	if all := Rank("  ", candidates); len(all) != len(candidates) || all[1].Index != 1 {
+		t.Errorf("Rank() with an empty pattern = %v, want every candidate in order", all)
+	}
+}
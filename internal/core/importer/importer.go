@@ -18,6 +18,14 @@ import (
 // Importer handles importing sessions into the database
 type Importer struct {
 	db *db.DB
+
+	// Workers is the number of goroutines ImportDirectory uses to hash and
+	// parse files (0 means runtime.NumCPU). Database writes stay on one goroutine.
+	Workers int
+
+	// Full makes ImportDirectory ignore saved import positions and reparse
+	// every file from the start
+	Full bool
 }
 
 // New creates a new importer
@@ -25,15 +33,41 @@ func New(database *db.DB) *Importer {
 	return &Importer{db: database}
 }
 
-// ImportSession imports a single parsed session, optionally skipping already-imported messages
-// existingMessageCount: number of messages we already have for this session (0 for new sessions)
-func (i *Importer) ImportSession(session *ccsessions.ParsedSession, existingMessageCount int) error {
-	// Compute file hash
-	hash, err := computeFileHash(session.FilePath)
+// ImportSession imports a parsed session. The session may be a full parse or
+// an incremental one from ccsessions.ParseFileFrom; either way messages that
+// are already stored are skipped, and the file's import position is saved so
+// the next sync can resume from session.EndOffset.
+func (i *Importer) ImportSession(session *ccsessions.ParsedSession) error {
+	hash, prefix, err := hashSession(session)
+	if err != nil {
+		return err
+	}
+	return i.writeSession(session, hash, prefix)
+}
+
+// hashSession computes the file hash and import-position prefix hash for a
+// parsed session. The whole file is hashed on full imports only - incremental
+// imports must not cost O(file size), so they keep the previously stored hash.
+func hashSession(session *ccsessions.ParsedSession) (hash, prefix string, err error) {
+	if session.StartOffset == 0 {
+		hash, err = computeFileHash(session.FilePath)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to hash file: %w", err)
+		}
+	}
+
+	prefix, err = prefixHash(session.FilePath, session.EndOffset)
 	if err != nil {
-		return fmt.Errorf("failed to hash file: %w", err)
+		return "", "", fmt.Errorf("failed to hash file prefix: %w", err)
 	}
 
+	return hash, prefix, nil
+}
+
+// writeSession stores a parsed session in a single transaction. It is the
+// only part of an import that touches the database, so ImportDirectory runs
+// it on one goroutine while parsing happens in parallel.
+func (i *Importer) writeSession(session *ccsessions.ParsedSession, hash, prefix string) error {
 	// Begin transaction
 	tx, err := i.db.Begin()
 	if err != nil {
@@ -43,6 +77,31 @@ func (i *Importer) ImportSession(session *ccsessions.ParsedSession, existingMess
 		_ = tx.Rollback()
 	}()
 
+	// A file with no entries (e.g. just created, or entirely malformed) only
+	// gets its import recorded - there's no session to show yet
+	messagesInserted := 0
+	if len(session.Messages) > 0 || session.Summary != "" {
+		messagesInserted, err = storeSession(tx, session, hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := recordImport(tx, session, hash, prefix, messagesInserted); err != nil {
+		return err
+	}
+
+	// Commit transaction
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+
+	return nil
+}
+
+// storeSession upserts the session and inserts its new messages, returning
+// how many messages were added
+func storeSession(tx *sql.Tx, session *ccsessions.ParsedSession, hash string) (int, error) {
 	// Extract project path from FIRST message CWD (where session was initiated)
 	// This is the directory where `claude` was launched, NOT where user was last working
 	projectPath := extractProjectInitiationPath(session.Messages)
@@ -68,8 +127,11 @@ func (i *Importer) ImportSession(session *ccsessions.ParsedSession, existingMess
 	}
 
 	// Upsert session - update if this file is newer than existing
+	// (a NULL file_mtime marks a session that needs a full re-import).
+	// Incremental imports only see the tail of the file, so empty values
+	// don't overwrite what earlier imports found.
 	// NOTE: We set message_count to 0 initially, will update with actual count after filtering messages
-	_, err = tx.Exec(`
+	_, err := tx.Exec(`
 		INSERT INTO sessions (
 			session_id, project_path, summary, leaf_uuid, cwd,
 			created_at, updated_at, message_count, file_hash,
@@ -77,32 +139,32 @@ func (i *Importer) ImportSession(session *ccsessions.ParsedSession, existingMess
 		) VALUES (?, ?, ?, ?, ?, ?, ?, 0, ?, ?, ?)
 		ON CONFLICT(session_id) DO UPDATE SET
 			summary = CASE
-				WHEN excluded.file_mtime > sessions.file_mtime THEN excluded.summary
+				WHEN excluded.file_mtime > COALESCE(sessions.file_mtime, '') THEN COALESCE(NULLIF(excluded.summary, ''), sessions.summary)
 				ELSE sessions.summary
 			END,
 			leaf_uuid = CASE
-				WHEN excluded.file_mtime > sessions.file_mtime THEN excluded.leaf_uuid
+				WHEN excluded.file_mtime > COALESCE(sessions.file_mtime, '') THEN COALESCE(NULLIF(excluded.leaf_uuid, ''), sessions.leaf_uuid)
 				ELSE sessions.leaf_uuid
 			END,
 			cwd = CASE
-				WHEN excluded.file_mtime > sessions.file_mtime THEN excluded.cwd
+				WHEN excluded.file_mtime > COALESCE(sessions.file_mtime, '') THEN COALESCE(NULLIF(excluded.cwd, ''), sessions.cwd)
 				ELSE sessions.cwd
 			END,
 			updated_at = CASE
-				WHEN excluded.file_mtime > sessions.file_mtime THEN excluded.updated_at
+				WHEN excluded.file_mtime > COALESCE(sessions.file_mtime, '') THEN excluded.updated_at
 				ELSE sessions.updated_at
 			END,
 			-- message_count is updated after processing messages, not from parsed file
 			file_hash = CASE
-				WHEN excluded.file_mtime > sessions.file_mtime THEN excluded.file_hash
+				WHEN excluded.file_mtime > COALESCE(sessions.file_mtime, '') THEN COALESCE(NULLIF(excluded.file_hash, ''), sessions.file_hash)
 				ELSE sessions.file_hash
 			END,
 			file_size = CASE
-				WHEN excluded.file_mtime > sessions.file_mtime THEN excluded.file_size
+				WHEN excluded.file_mtime > COALESCE(sessions.file_mtime, '') THEN excluded.file_size
 				ELSE sessions.file_size
 			END,
 			file_mtime = CASE
-				WHEN excluded.file_mtime > sessions.file_mtime THEN excluded.file_mtime
+				WHEN excluded.file_mtime > COALESCE(sessions.file_mtime, '') THEN excluded.file_mtime
 				ELSE sessions.file_mtime
 			END
 	`,
@@ -118,49 +180,51 @@ func (i *Importer) ImportSession(session *ccsessions.ParsedSession, existingMess
 		session.FileMtime,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to upsert session: %w", err)
+		return 0, fmt.Errorf("failed to upsert session: %w", err)
 	}
 
 	// Get the session DB ID (either newly inserted or existing)
 	var sessionDBID int64
 	err = tx.QueryRow("SELECT id FROM sessions WHERE session_id = ?", session.SessionID).Scan(&sessionDBID)
 	if err != nil {
-		return fmt.Errorf("failed to get session ID: %w", err)
+		return 0, fmt.Errorf("failed to get session ID: %w", err)
 	}
 
-	// Insert messages (use INSERT OR IGNORE to skip duplicates from resumed sessions)
-	// Skip messages we already have based on existingMessageCount
+	// Insert messages (use INSERT OR IGNORE to skip duplicates from resumed
+	// sessions and from re-reading lines an earlier import already stored).
+	// Every entry is stored, including tool-only turns and metadata entries, so
+	// the parent_uuid chain stays intact. Readers hide entries without text.
 	messagesInserted := 0
-	processedCount := 0
-	actualMessageCount := 0 // Track actual messages we'll insert (after all filtering)
+	agentIDs := make(map[string]string) // Sidechain message uuid -> subagent run
 
 	for _, msg := range session.Messages {
-		// Skip messages with no text content (tool_use/tool_result only)
-		trimmed := strings.TrimSpace(msg.TextContent)
-		if trimmed == "" {
-			continue
+		// Entries like file-history-snapshot carry no uuid - derive a stable one
+		// from the session and line number so they don't collide
+		uuid := msg.UUID
+		if uuid == "" {
+			uuid = fmt.Sprintf("%s:%d", session.SessionID, msg.Sequence)
 		}
 
-		// Count messages we're processing (after filtering empty ones)
-		processedCount++
-
-		// If we already have this message, skip inserting it
-		if processedCount <= existingMessageCount {
-			// We already have this message in DB - skip INSERT
-			actualMessageCount++
-			continue
+		usage, err := responseUsage(tx, uuid, &msg)
+		if err != nil {
+			return 0, fmt.Errorf("failed to check usage for %s: %w", uuid, err)
 		}
 
-		// This is a new message we don't have yet - insert it
+		agentID, err := sidechainAgentID(tx, uuid, &msg, agentIDs)
+		if err != nil {
+			return 0, fmt.Errorf("failed to find subagent run for %s: %w", uuid, err)
+		}
 
 		result, err := tx.Exec(`
 			INSERT OR IGNORE INTO messages (
 				uuid, session_id, parent_uuid, type, sender,
 				content, text_content, timestamp, sequence,
-				is_sidechain, cwd, git_branch, version
-			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				is_sidechain, is_tool_only, cwd, git_branch, version,
+				model, api_message_id, input_tokens, output_tokens,
+				cache_read_tokens, cache_creation_tokens, agent_id
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULLIF(?, ''), NULLIF(?, ''), ?, ?, ?, ?, NULLIF(?, ''))
 		`,
-			msg.UUID,
+			uuid,
 			sessionDBID,
 			msg.ParentUUID,
 			msg.Type,
@@ -170,123 +234,372 @@ func (i *Importer) ImportSession(session *ccsessions.ParsedSession, existingMess
 			msg.Timestamp,
 			msg.Sequence,
 			msg.IsSidechain,
+			msg.IsToolOnly(),
 			msg.CWD,
 			msg.GitBranch,
 			msg.Version,
+			msg.Model,
+			msg.APIMessageID,
+			usage.InputTokens,
+			usage.OutputTokens,
+			usage.CacheReadInputTokens,
+			usage.CacheCreationInputTokens,
+			agentID,
 		)
 		if err != nil {
-			return fmt.Errorf("failed to insert message %s: %w", msg.UUID, err)
+			return 0, fmt.Errorf("failed to insert message %s: %w", uuid, err)
 		}
 
 		// Check if the message was actually inserted
 		rowsAffected, err := result.RowsAffected()
 		if err == nil && rowsAffected > 0 {
 			messagesInserted++
+
+			messageDBID, err := result.LastInsertId()
+			if err != nil {
+				return 0, fmt.Errorf("failed to get message ID for %s: %w", uuid, err)
+			}
+			if err := insertToolUses(tx, messageDBID, &msg); err != nil {
+				return 0, fmt.Errorf("failed to insert tool uses for %s: %w", uuid, err)
+			}
+		} else {
+			// Already stored - possibly by a version that didn't record tool
+			// uses or token usage
+			if err := backfillToolUses(tx, uuid, &msg); err != nil {
+				return 0, fmt.Errorf("failed to backfill tool uses for %s: %w", uuid, err)
+			}
+			if err := backfillUsage(tx, uuid, &msg, usage); err != nil {
+				return 0, fmt.Errorf("failed to backfill usage for %s: %w", uuid, err)
+			}
+			if agentID != "" {
+				_, err := tx.Exec(`UPDATE messages SET agent_id = ? WHERE uuid = ? AND agent_id IS NULL`, agentID, uuid)
+				if err != nil {
+					return 0, fmt.Errorf("failed to backfill subagent run for %s: %w", uuid, err)
+				}
+			}
+		}
+	}
+
+	// Attach tool results to tool uses recorded in earlier syncs (the tool_use
+	// and its tool_result can land on different sides of an incremental import)
+	for _, msg := range session.Messages {
+		for _, toolResult := range msg.ToolResults {
+			if toolResult.ToolUseID == "" {
+				continue
+			}
+			_, err := tx.Exec(`
+				UPDATE tool_uses SET output = ?
+				WHERE tool_id = ? AND COALESCE(output, '') = ''
+			`, toolResult.Output, toolResult.ToolUseID)
+			if err != nil {
+				return 0, fmt.Errorf("failed to update tool result %s: %w", toolResult.ToolUseID, err)
+			}
+
+			if toolResult.AgentID != "" {
+				_, err := tx.Exec(`UPDATE tool_uses SET agent_id = ? WHERE tool_id = ?`, toolResult.AgentID, toolResult.ToolUseID)
+				if err != nil {
+					return 0, fmt.Errorf("failed to link subagent run for %s: %w", toolResult.ToolUseID, err)
+				}
+			}
 		}
-		actualMessageCount++ // Count every message we process (whether inserted or already existed)
 	}
 
-	// Update the session's message_count with the ACTUAL count (not the bogus parsed value)
-	_, err = tx.Exec(`UPDATE sessions SET message_count = ? WHERE id = ?`, actualMessageCount, sessionDBID)
+	if err := linkSubagentsByPrompt(tx, sessionDBID); err != nil {
+		return 0, fmt.Errorf("failed to link subagent runs: %w", err)
+	}
+
+	// Update the session's message_count with the number of visible messages
+	// (the ones with text), matching what list/detail views display
+	_, err = tx.Exec(`
+		UPDATE sessions SET message_count = (
+			SELECT COUNT(*) FROM messages WHERE session_id = ? AND `+db.VisibleMessageCondition+`
+		) WHERE id = ?
+	`, sessionDBID, sessionDBID)
 	if err != nil {
-		return fmt.Errorf("failed to update message count: %w", err)
+		return 0, fmt.Errorf("failed to update message count: %w", err)
 	}
 
-	// Record import
+	// Roll token usage up to the session, along with the latest model used
+	// ("<synthetic>" marks messages Claude Code generated without an API call)
 	_, err = tx.Exec(`
-		INSERT INTO import_log (file_path, file_hash, sessions_imported, messages_imported, status)
-		VALUES (?, ?, 1, ?, 'success')
-	`, session.FilePath, hash, messagesInserted)
+		UPDATE sessions SET
+			input_tokens = (SELECT COALESCE(SUM(input_tokens), 0) FROM messages WHERE session_id = ?),
+			output_tokens = (SELECT COALESCE(SUM(output_tokens), 0) FROM messages WHERE session_id = ?),
+			cache_read_tokens = (SELECT COALESCE(SUM(cache_read_tokens), 0) FROM messages WHERE session_id = ?),
+			cache_creation_tokens = (SELECT COALESCE(SUM(cache_creation_tokens), 0) FROM messages WHERE session_id = ?),
+			model = COALESCE((
+				SELECT model FROM messages
+				WHERE session_id = ? AND model IS NOT NULL AND model != '<synthetic>'
+				ORDER BY sequence DESC LIMIT 1
+			), model)
+		WHERE id = ?
+	`, sessionDBID, sessionDBID, sessionDBID, sessionDBID, sessionDBID, sessionDBID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update token usage: %w", err)
+	}
+
+	return messagesInserted, nil
+}
+
+// recordImport saves the file's import position and logs the import along
+// with any lines the parser skipped
+func recordImport(tx *sql.Tx, session *ccsessions.ParsedSession, hash, prefix string, messagesInserted int) error {
+	// Remember where this file's import stopped
+	_, err := tx.Exec(`
+		INSERT INTO import_state (
+			file_path, session_id, byte_offset, line_count, prefix_hash,
+			file_size, file_mtime, updated_at
+		) VALUES (?, ?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(file_path) DO UPDATE SET
+			session_id = excluded.session_id,
+			byte_offset = excluded.byte_offset,
+			line_count = excluded.line_count,
+			prefix_hash = excluded.prefix_hash,
+			file_size = excluded.file_size,
+			file_mtime = excluded.file_mtime,
+			updated_at = excluded.updated_at
+	`,
+		session.FilePath,
+		session.SessionID,
+		session.EndOffset,
+		session.LineCount,
+		prefix,
+		session.FileSize,
+		session.FileMtime,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save import state: %w", err)
+	}
+
+	// Record import (incremental imports log the prefix hash they resumed from)
+	logHash := hash
+	if logHash == "" {
+		logHash = prefix
+	}
+	status, errorMessage := "success", ""
+	if len(session.Diagnostics) > 0 {
+		status = "partial"
+		errorMessage = fmt.Sprintf("skipped %d malformed line(s)", len(session.Diagnostics))
+	}
+	result, err := tx.Exec(`
+		INSERT INTO import_log (file_path, file_hash, sessions_imported, messages_imported, status, error_message)
+		VALUES (?, ?, 1, ?, ?, NULLIF(?, ''))
+	`, session.FilePath, logHash, messagesInserted, status, errorMessage)
 	if err != nil {
 		return fmt.Errorf("failed to record import: %w", err)
 	}
+	importLogID, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to get import log ID: %w", err)
+	}
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit: %w", err)
+	if err := recordDiagnostics(tx, importLogID, session); err != nil {
+		return fmt.Errorf("failed to record parse diagnostics: %w", err)
 	}
 
 	return nil
 }
 
-// ImportDirectory imports all sessions from a directory tree
-func (i *Importer) ImportDirectory(dirPath string, progress ProgressCallback) error {
-	// Find all .jsonl files
-	var files []string
-	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() && filepath.Ext(path) == ".jsonl" {
-			files = append(files, path)
-		}
-		return nil
-	})
+// importState is where the last import of a session file stopped
+type importState struct {
+	SessionID  string
+	ByteOffset int64
+	LineCount  int
+	PrefixHash string
+	FileSize   int64
+	FileMtime  time.Time
+}
+
+// loadImportStates returns the saved import position of every file imported
+// so far, keyed by file path
+func (i *Importer) loadImportStates() (map[string]*importState, error) {
+	rows, err := i.db.Query(`
+		SELECT file_path, session_id, byte_offset, line_count, prefix_hash, file_size, file_mtime
+		FROM import_state
+	`)
 	if err != nil {
-		return fmt.Errorf("failed to walk directory: %w", err)
+		return nil, err
+	}
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	states := make(map[string]*importState)
+	for rows.Next() {
+		var path string
+		var state importState
+		if err := rows.Scan(&path, &state.SessionID, &state.ByteOffset, &state.LineCount,
+			&state.PrefixHash, &state.FileSize, &state.FileMtime); err != nil {
+			return nil, err
+		}
+		states[path] = &state
 	}
+	return states, rows.Err()
+}
 
-	// Import each file
-	skipped := 0
-	for _, file := range files {
-		// Get file info for mtime check
-		fileInfo, err := os.Stat(file)
+// insertToolUses records the tool_use blocks of a newly inserted message
+func insertToolUses(tx *sql.Tx, messageID int64, msg *ccsessions.ParsedMessage) error {
+	for _, toolUse := range msg.ToolUses {
+		_, err := tx.Exec(`
+			INSERT INTO tool_uses (message_id, tool_name, tool_id, input, output, created_at)
+			VALUES (?, ?, ?, ?, ?, ?)
+		`,
+			messageID,
+			toolUse.Name,
+			toolUse.ID,
+			string(toolUse.Input),
+			toolUse.Output,
+			msg.Timestamp,
+		)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to stat %s: %v\n", file, err)
-			continue
+			return err
 		}
-		fileMtime := fileInfo.ModTime()
-
-		// Extract session ID from filename
-		sessionID := filepath.Base(file)
-		sessionID = strings.TrimSuffix(sessionID, ".jsonl")
-
-		// Check if we have this session and if our copy is up-to-date
-		var dbMtime sql.NullTime
-		var messageCount int
-		err = i.db.QueryRow(`
-			SELECT file_mtime, COALESCE(message_count, 0)
-			FROM sessions
-			WHERE session_id = ?
-		`, sessionID).Scan(&dbMtime, &messageCount)
-
-		if err == nil && dbMtime.Valid {
-			// We have this session - check if file changed
-			if !fileMtime.After(dbMtime.Time) {
-				// File hasn't been modified since we last imported - skip
-				skipped++
-				continue
-			}
+	}
+	return nil
+}
+
+// recordDiagnostics stores the lines the parser skipped. A full parse
+// replaces the file's earlier diagnostics; an incremental one adds to them,
+// since the lines it skipped before won't be read again.
+func recordDiagnostics(tx *sql.Tx, importLogID int64, session *ccsessions.ParsedSession) error {
+	if session.StartOffset == 0 {
+		if _, err := tx.Exec(`DELETE FROM import_diagnostics WHERE file_path = ?`, session.FilePath); err != nil {
+			return err
 		}
-		// else: new session or no mtime, need to import
+	}
 
-		// Parse and import (passing existing message count for incremental import)
-		session, err := ccsessions.ParseFile(file)
+	for _, d := range session.Diagnostics {
+		_, err := tx.Exec(`
+			INSERT INTO import_diagnostics (import_log_id, file_path, line_number, byte_offset, error)
+			VALUES (?, ?, ?, ?, ?)
+		`, importLogID, session.FilePath, d.Line, d.Offset, d.Err.Error())
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to parse %s: %v\n", file, err)
-			continue
+			return err
 		}
+	}
+	return nil
+}
 
-		if err := i.ImportSession(session, messageCount); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to import %s: %v\n", file, err)
-			continue
-		}
+// responseUsage returns the token usage to store for a message. Claude Code
+// writes one entry per content block of an API response, each repeating the
+// response's usage, so only the first entry stored for a response carries it.
+func responseUsage(tx *sql.Tx, uuid string, msg *ccsessions.ParsedMessage) (ccsessions.TokenUsage, error) {
+	if msg.APIMessageID == "" {
+		return msg.Usage, nil
+	}
 
-		// Update progress
-		if progress != nil {
-			firstMsg := ""
-			if len(session.Messages) > 0 {
-				firstMsg = session.Messages[0].TextContent
-				if len(firstMsg) > 100 {
-					firstMsg = firstMsg[:97] + "..."
-				}
+	var exists int
+	err := tx.QueryRow(`
+		SELECT COUNT(*) FROM messages WHERE api_message_id = ? AND uuid != ?
+	`, msg.APIMessageID, uuid).Scan(&exists)
+	if err != nil {
+		return ccsessions.TokenUsage{}, err
+	}
+	if exists > 0 {
+		return ccsessions.TokenUsage{}, nil
+	}
+	return msg.Usage, nil
+}
+
+// sidechainAgentID returns the subagent run a sidechain message belongs to,
+// or "" for main-chain messages. Older Claude Code versions don't write
+// agentId, so messages without one join their parent's run, and a run's
+// first message names it.
+func sidechainAgentID(tx *sql.Tx, uuid string, msg *ccsessions.ParsedMessage, agentIDs map[string]string) (string, error) {
+	if !msg.IsSidechain {
+		return "", nil
+	}
+
+	agentID := msg.AgentID
+	if agentID == "" && msg.ParentUUID != "" {
+		agentID = agentIDs[msg.ParentUUID]
+		if agentID == "" {
+			var stored sql.NullString
+			err := tx.QueryRow(`SELECT agent_id FROM messages WHERE uuid = ?`, msg.ParentUUID).Scan(&stored)
+			if err != nil && err != sql.ErrNoRows {
+				return "", err
 			}
-			progress.Update(session.Summary, firstMsg)
+			agentID = stored.String
 		}
 	}
+	if agentID == "" {
+		agentID = uuid
+	}
+
+	agentIDs[uuid] = agentID
+	return agentID, nil
+}
+
+// linkSubagentsByPrompt links Task calls whose result didn't name a subagent
+// run (older Claude Code versions) to the run started with the same prompt
+func linkSubagentsByPrompt(tx *sql.Tx, sessionDBID int64) error {
+	_, err := tx.Exec(`
+		UPDATE tool_uses SET agent_id = (
+			SELECT m.agent_id FROM messages m
+			WHERE m.session_id = ?
+				AND m.agent_id IS NOT NULL
+				AND COALESCE(m.parent_uuid, '') = ''
+				AND m.type = 'user'
+				AND TRIM(m.text_content) = TRIM(json_extract(tool_uses.input, '$.prompt'))
+				AND m.agent_id NOT IN (SELECT agent_id FROM tool_uses WHERE agent_id IS NOT NULL)
+			ORDER BY m.sequence LIMIT 1
+		)
+		WHERE agent_id IS NULL
+			AND tool_name IN ('Task', 'Agent')
+			AND json_valid(input)
+			AND message_id IN (SELECT id FROM messages WHERE session_id = ?)
+	`, sessionDBID, sessionDBID)
+	return err
+}
 
-	// Note: Don't print "Skipped X files" - that's an interface concern (core should be silent)
+// backfillUsage fills in model and token usage for an assistant message stored
+// before they were recorded
+func backfillUsage(tx *sql.Tx, uuid string, msg *ccsessions.ParsedMessage, usage ccsessions.TokenUsage) error {
+	if msg.Model == "" {
+		return nil
+	}
+	_, err := tx.Exec(`
+		UPDATE messages SET
+			model = ?, api_message_id = NULLIF(?, ''),
+			input_tokens = ?, output_tokens = ?,
+			cache_read_tokens = ?, cache_creation_tokens = ?
+		WHERE uuid = ? AND model IS NULL
+	`,
+		msg.Model,
+		msg.APIMessageID,
+		usage.InputTokens,
+		usage.OutputTokens,
+		usage.CacheReadInputTokens,
+		usage.CacheCreationInputTokens,
+		uuid,
+	)
+	return err
+}
 
+// backfillToolUses records tool_use blocks of an already-stored message that
+// are missing from tool_uses (messages imported before tool uses were tracked)
+func backfillToolUses(tx *sql.Tx, uuid string, msg *ccsessions.ParsedMessage) error {
+	for _, toolUse := range msg.ToolUses {
+		if toolUse.ID == "" {
+			continue
+		}
+		_, err := tx.Exec(`
+			INSERT INTO tool_uses (message_id, tool_name, tool_id, input, output, created_at)
+			SELECT id, ?, ?, ?, ?, ? FROM messages
+			WHERE uuid = ? AND NOT EXISTS (SELECT 1 FROM tool_uses WHERE tool_id = ?)
+		`,
+			toolUse.Name,
+			toolUse.ID,
+			string(toolUse.Input),
+			toolUse.Output,
+			msg.Timestamp,
+			uuid,
+			toolUse.ID,
+		)
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -307,6 +620,40 @@ func computeFileHash(path string) (string, error) {
 	return hex.EncodeToString(hash.Sum(nil)), nil
 }
 
+// prefixHashWindow is how many bytes prefixHash samples at each end of the
+// imported prefix
+const prefixHashWindow = 64 * 1024
+
+// prefixHash fingerprints the first n bytes of a file without reading all of
+// them: it hashes n itself plus the head and tail windows of that range. This
+// catches files that were replaced or rewritten (e.g. truncated and written
+// again) while keeping an incremental sync's cost proportional to new data.
+func prefixHash(path string, n int64) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	hash := sha256.New()
+	_, _ = fmt.Fprintf(hash, "%d\n", n)
+
+	head := min(n, prefixHashWindow)
+	if _, err := io.Copy(hash, io.NewSectionReader(file, 0, head)); err != nil {
+		return "", err
+	}
+	if n > head {
+		tailStart := max(head, n-prefixHashWindow)
+		if _, err := io.Copy(hash, io.NewSectionReader(file, tailStart, n-tailStart)); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
 // extractProjectInitiationPath finds the FIRST non-empty CWD from messages
 // This is where `claude` was launched and where the session file is stored
 func extractProjectInitiationPath(messages []ccsessions.ParsedMessage) string {
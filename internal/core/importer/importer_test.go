@@ -2,7 +2,10 @@ package importer
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/neilberkman/ccrider/internal/core/db"
 	"github.com/neilberkman/ccrider/pkg/ccsessions"
@@ -36,7 +39,7 @@ func TestImportSession(t *testing.T) {
 	}
 
 	// Import it
-	err = imp.ImportSession(session, 0)
+	err = imp.ImportSession(session)
 	if err != nil {
 		t.Fatalf("ImportSession() error = %v", err)
 	}
@@ -90,13 +93,13 @@ func TestImportSession_ResumedSession(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	err = imp.ImportSession(session1, 0)
+	err = imp.ImportSession(session1)
 	if err != nil {
 		t.Fatalf("ImportSession() error = %v", err)
 	}
 
 	// Import the same session again (simulating resumed session)
-	err = imp.ImportSession(session1, 0)
+	err = imp.ImportSession(session1)
 	if err != nil {
 		t.Fatalf("ImportSession() second import error = %v", err)
 	}
@@ -151,7 +154,7 @@ func TestImportSession_AgentSession(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	err = imp.ImportSession(session, 0)
+	err = imp.ImportSession(session)
 	if err != nil {
 		t.Fatalf("ImportSession() error = %v", err)
 	}
@@ -167,3 +170,592 @@ func TestImportSession_AgentSession(t *testing.T) {
 		t.Errorf("Expected session_id 'test-session-123', got %s", sessionID)
 	}
 }
+
+func TestImportSession_ToolUses(t *testing.T) {
+	// Setup test database
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.Remove(tmpfile.Name())
+	}()
+	_ = tmpfile.Close()
+
+	database, err := db.New(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = database.Close()
+	}()
+
+	imp := New(database)
+
+	session, err := ccsessions.ParseFile("../../../pkg/ccsessions/testdata/tool-session.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = imp.ImportSession(session)
+	if err != nil {
+		t.Fatalf("ImportSession() error = %v", err)
+	}
+
+	// Both tool invocations should be recorded, including the tool_use-only turn
+	var count int
+	err = database.QueryRow("SELECT COUNT(*) FROM tool_uses").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("Expected 2 tool uses, got %d", count)
+	}
+
+	// Find the session that ran the migration command
+	var sessionID, output string
+	err = database.QueryRow(`
+		SELECT s.session_id, t.output
+		FROM tool_uses t
+		JOIN messages m ON m.id = t.message_id
+		JOIN sessions s ON s.id = m.session_id
+		WHERE t.tool_name = 'Bash' AND t.input LIKE '%ecto.migrate%'
+	`).Scan(&sessionID, &output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sessionID != "tool-session-789" {
+		t.Errorf("Expected session 'tool-session-789', got %s", sessionID)
+	}
+	if output != "Migrated 20251108_add_accounts in 0.1s" {
+		t.Errorf("Expected paired tool output, got %q", output)
+	}
+
+	// Every entry is stored, including tool-only turns and the snapshot
+	err = database.QueryRow("SELECT COUNT(*) FROM messages").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 7 {
+		t.Errorf("Expected 7 stored entries, got %d", count)
+	}
+
+	err = database.QueryRow("SELECT COUNT(*) FROM messages WHERE is_tool_only = 1").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 tool-only messages, got %d", count)
+	}
+
+	// message_count reflects only the visible (text) messages
+	var messageCount int
+	err = database.QueryRow("SELECT message_count FROM sessions").Scan(&messageCount)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if messageCount != 3 {
+		t.Errorf("Expected message_count 3, got %d", messageCount)
+	}
+
+	// Detail keeps tool-only turns (for collapsing) but drops metadata entries
+	detail, err := database.GetSessionDetail("tool-session-789")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(detail.Messages) != 6 {
+		t.Fatalf("Expected 6 detail messages, got %d", len(detail.Messages))
+	}
+	if !detail.Messages[3].IsToolOnly || len(detail.Messages[3].ToolNames) != 1 || detail.Messages[3].ToolNames[0] != "Read" {
+		t.Errorf("Expected tool-only Read message, got %+v", detail.Messages[3])
+	}
+
+	// Re-importing must not duplicate tool uses
+	err = imp.ImportSession(session)
+	if err != nil {
+		t.Fatalf("ImportSession() second import error = %v", err)
+	}
+	err = database.QueryRow("SELECT COUNT(*) FROM tool_uses").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 tool uses after re-import, got %d", count)
+	}
+
+	// Messages stored before tool uses were tracked get them backfilled
+	if _, err := database.Exec("DELETE FROM tool_uses"); err != nil {
+		t.Fatal(err)
+	}
+	err = imp.ImportSession(session)
+	if err != nil {
+		t.Fatalf("ImportSession() backfill import error = %v", err)
+	}
+	err = database.QueryRow("SELECT COUNT(*) FROM tool_uses").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 backfilled tool uses, got %d", count)
+	}
+}
+
+func TestImportDirectory_Incremental(t *testing.T) {
+	// Setup test database
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.Remove(tmpfile.Name())
+	}()
+	_ = tmpfile.Close()
+
+	database, err := db.New(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = database.Close()
+	}()
+
+	imp := New(database)
+
+	data, err := os.ReadFile("../../../pkg/ccsessions/testdata/tool-session.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "tool-session-789.jsonl")
+	mtime := time.Now().Add(-time.Hour)
+	writeFile := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		// Bump mtime explicitly so fast consecutive writes are still seen as changes
+		mtime = mtime.Add(time.Minute)
+		if err := os.Chtimes(path, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	countRows := func(query string) int {
+		t.Helper()
+		var count int
+		if err := database.QueryRow(query).Scan(&count); err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+
+	// First sync sees the session up to the Bash tool_use
+	writeFile(strings.Join(lines[:3], ""))
+	if err := imp.ImportDirectory(dir, nil); err != nil {
+		t.Fatalf("ImportDirectory() error = %v", err)
+	}
+	if got := countRows("SELECT COUNT(*) FROM messages"); got != 2 {
+		t.Errorf("Expected 2 messages after first sync, got %d", got)
+	}
+
+	// Second sync resumes from the saved offset; the tool_result lands in the new chunk
+	writeFile(string(data))
+	if err := imp.ImportDirectory(dir, nil); err != nil {
+		t.Fatalf("ImportDirectory() error = %v", err)
+	}
+	if got := countRows("SELECT COUNT(*) FROM messages"); got != 7 {
+		t.Errorf("Expected 7 messages after resume, got %d", got)
+	}
+	if got := countRows("SELECT COUNT(*) FROM sessions"); got != 1 {
+		t.Errorf("Expected 1 session, got %d", got)
+	}
+
+	var output, summary string
+	var messageCount int
+	if err := database.QueryRow("SELECT output FROM tool_uses WHERE tool_id = 'toolu_01'").Scan(&output); err != nil {
+		t.Fatal(err)
+	}
+	if output != "Migrated 20251108_add_accounts in 0.1s" {
+		t.Errorf("Expected tool output attached across syncs, got %q", output)
+	}
+	if err := database.QueryRow("SELECT summary, message_count FROM sessions").Scan(&summary, &messageCount); err != nil {
+		t.Fatal(err)
+	}
+	if summary != "Run database migration" {
+		t.Errorf("Expected summary from the first chunk to be kept, got %q", summary)
+	}
+	if messageCount != 3 {
+		t.Errorf("Expected message_count 3, got %d", messageCount)
+	}
+
+	var offset int64
+	var lineCount int
+	if err := database.QueryRow("SELECT byte_offset, line_count FROM import_state WHERE file_path = ?", path).Scan(&offset, &lineCount); err != nil {
+		t.Fatal(err)
+	}
+	if offset != int64(len(data)) || lineCount != 8 {
+		t.Errorf("Expected import_state at %d/8, got %d/%d", len(data), offset, lineCount)
+	}
+
+	// A rewritten file (already-imported bytes changed) is reparsed from the start
+	rewritten := strings.Replace(string(data), "run the migrations", "run the migration", 1)
+	rewritten = strings.ReplaceAll(rewritten, `"msg-`, `"new-`)
+	writeFile(rewritten)
+	if err := imp.ImportDirectory(dir, nil); err != nil {
+		t.Fatalf("ImportDirectory() error = %v", err)
+	}
+	if got := countRows("SELECT COUNT(*) FROM messages WHERE uuid LIKE 'new-%'"); got != 6 {
+		t.Errorf("Expected all 6 rewritten messages to be imported, got %d", got)
+	}
+}
+
+// countingProgress records how many times the importer reported progress
+type countingProgress struct {
+	updates int
+}
+
+func (p *countingProgress) Update(sessionSummary string, firstMsg string) { p.updates++ }
+func (p *countingProgress) Finish()                                       {}
+
+func TestImportDirectory_Parallel(t *testing.T) {
+	// Setup test database
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.Remove(tmpfile.Name())
+	}()
+	_ = tmpfile.Close()
+
+	database, err := db.New(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = database.Close()
+	}()
+
+	// Spread the fixtures over two project directories, plus one broken file
+	dir := t.TempDir()
+	fixtures := map[string]string{
+		"-Users-test-project-a/test-session-123.jsonl": "sample.jsonl",
+		"-Users-test-project-a/tool-session-789.jsonl": "tool-session.jsonl",
+		"-Users-test-project-b/agent-abc123.jsonl":     "agent-session.jsonl",
+	}
+	for dest, src := range fixtures {
+		data, err := os.ReadFile(filepath.Join("../../../pkg/ccsessions/testdata", src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(dest)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, dest), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "-Users-test-project-b", "broken.jsonl"), []byte("{not json\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	imp := New(database)
+	imp.Workers = 4
+
+	progress := &countingProgress{}
+	if err := imp.ImportDirectory(dir, progress); err != nil {
+		t.Fatalf("ImportDirectory() error = %v", err)
+	}
+
+	// Every file is reported once, including the one with nothing parseable
+	if progress.updates != 4 {
+		t.Errorf("Expected 4 progress updates, got %d", progress.updates)
+	}
+
+	var count int
+	if err := database.QueryRow("SELECT COUNT(*) FROM sessions").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 sessions, got %d", count)
+	}
+
+	// Unchanged files are skipped on the next sync but still reported
+	progress = &countingProgress{}
+	if err := imp.ImportDirectory(dir, progress); err != nil {
+		t.Fatalf("ImportDirectory() second sync error = %v", err)
+	}
+	if progress.updates != 4 {
+		t.Errorf("Expected 4 progress updates on resync, got %d", progress.updates)
+	}
+	if err := database.QueryRow("SELECT COUNT(*) FROM import_log").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 4 {
+		t.Errorf("Expected unchanged files to be skipped (4 import_log rows), got %d", count)
+	}
+
+	// The broken file is logged as partial, without creating an empty session
+	if err := database.QueryRow("SELECT COUNT(*) FROM import_log WHERE status = 'partial' AND file_path LIKE '%broken.jsonl'").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("Expected broken.jsonl to be logged as partial, got %d rows", count)
+	}
+}
+
+func TestImportSession_Partial(t *testing.T) {
+	// Setup test database
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.Remove(tmpfile.Name())
+	}()
+	_ = tmpfile.Close()
+
+	database, err := db.New(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = database.Close()
+	}()
+
+	imp := New(database)
+
+	session, err := ccsessions.ParseFile("../../../pkg/ccsessions/testdata/corrupt-session.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = imp.ImportSession(session)
+	if err != nil {
+		t.Fatalf("ImportSession() error = %v", err)
+	}
+
+	// The readable messages are imported
+	var count int
+	err = database.QueryRow("SELECT COUNT(*) FROM messages").Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 messages, got %d", count)
+	}
+
+	var status string
+	err = database.QueryRow("SELECT status FROM import_log").Scan(&status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != "partial" {
+		t.Errorf("Expected import status 'partial', got %q", status)
+	}
+
+	diagnostics, err := database.ListImportDiagnostics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %d", len(diagnostics))
+	}
+	if diagnostics[0].Line != 3 || diagnostics[1].Line != 4 {
+		t.Errorf("Expected diagnostics for lines 3 and 4, got %d and %d", diagnostics[0].Line, diagnostics[1].Line)
+	}
+
+	// Re-importing the whole file replaces its diagnostics rather than duplicating them
+	err = imp.ImportSession(session)
+	if err != nil {
+		t.Fatalf("ImportSession() second import error = %v", err)
+	}
+	diagnostics, err = database.ListImportDiagnostics()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diagnostics) != 2 {
+		t.Errorf("Expected 2 diagnostics after re-import, got %d", len(diagnostics))
+	}
+}
+
+func TestImportSession_Usage(t *testing.T) {
+	// Setup test database
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.Remove(tmpfile.Name())
+	}()
+	_ = tmpfile.Close()
+
+	database, err := db.New(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = database.Close()
+	}()
+
+	imp := New(database)
+
+	session, err := ccsessions.ParseFile("../../../pkg/ccsessions/testdata/usage-session.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = imp.ImportSession(session)
+	if err != nil {
+		t.Fatalf("ImportSession() error = %v", err)
+	}
+
+	// A response split over two entries is only counted once, on the first
+	var input, cacheRead int
+	err = database.QueryRow("SELECT input_tokens, cache_read_tokens FROM messages WHERE uuid = 'a-2'").Scan(&input, &cacheRead)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if input != 0 || cacheRead != 0 {
+		t.Errorf("Expected no usage on the second entry of a response, got %d/%d", input, cacheRead)
+	}
+
+	// Re-importing doesn't double count
+	err = imp.ImportSession(session)
+	if err != nil {
+		t.Fatalf("ImportSession() second import error = %v", err)
+	}
+
+	detail, err := database.GetSessionDetail("usage-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := db.TokenUsage{InputTokens: 30, OutputTokens: 20, CacheReadTokens: 2200, CacheCreationTokens: 200}
+	if detail.Usage != want {
+		t.Errorf("Session usage = %+v, want %+v", detail.Usage, want)
+	}
+	if detail.Model != "claude-opus-4-1-20250805" {
+		t.Errorf("Session model = %q, want the latest response's model", detail.Model)
+	}
+}
+
+func TestImportSession_Branches(t *testing.T) {
+	// Setup test database
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.Remove(tmpfile.Name())
+	}()
+	_ = tmpfile.Close()
+
+	database, err := db.New(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = database.Close()
+	}()
+
+	imp := New(database)
+
+	session, err := ccsessions.ParseFile("../../../pkg/ccsessions/testdata/branched-session.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = imp.ImportSession(session)
+	if err != nil {
+		t.Fatalf("ImportSession() error = %v", err)
+	}
+
+	detail, err := database.GetSessionDetail("branched-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The summary's leaf (a-3) is stale: the conversation went on to a-4.
+	// "Use React" and its reply were abandoned when the prompt was edited.
+	var active, abandoned []string
+	for _, msg := range detail.Messages {
+		if msg.Branch > 0 {
+			abandoned = append(abandoned, msg.UUID)
+		} else {
+			active = append(active, msg.UUID)
+		}
+	}
+	if strings.Join(abandoned, ",") != "u-2,a-2" {
+		t.Errorf("Abandoned = %v, want [u-2 a-2]", abandoned)
+	}
+	if strings.Join(active, ",") != "u-1,a-1,u-3,a-3,u-4,a-4" {
+		t.Errorf("Active = %v, want [u-1 a-1 u-3 a-3 u-4 a-4]", active)
+	}
+}
+
+func TestImportSession_Subagents(t *testing.T) {
+	// Setup test database
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.Remove(tmpfile.Name())
+	}()
+	_ = tmpfile.Close()
+
+	database, err := db.New(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = database.Close()
+	}()
+
+	imp := New(database)
+
+	// The agent file merges into the parent session
+	for _, file := range []string{"task-session.jsonl", "agent-task.jsonl"} {
+		session, err := ccsessions.ParseFile("../../../pkg/ccsessions/testdata/" + file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := imp.ImportSession(session); err != nil {
+			t.Fatalf("ImportSession(%s) error = %v", file, err)
+		}
+	}
+
+	detail, err := database.GetSessionDetail("task-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var main []string
+	subagents := make(map[string]db.SubagentRun)
+	for _, msg := range detail.Messages {
+		main = append(main, msg.UUID)
+		for _, run := range msg.Subagents {
+			subagents[msg.UUID] = run
+		}
+	}
+	if strings.Join(main, ",") != "u-1,a-1,u-2,a-2,u-3,a-3" {
+		t.Errorf("Main conversation = %v, want no sidechain messages", main)
+	}
+
+	// Linked by the agentId in the Task result
+	run := subagents["a-1"]
+	if run.AgentID != "agent01" || run.SubagentType != "Explore" || run.Description != "Find config loading" || len(run.Messages) != 4 {
+		t.Errorf("a-1 subagent = %+v, want the 4-message Explore run agent01", run)
+	}
+
+	// Linked by matching the Task prompt (no agentId anywhere)
+	run = subagents["a-2"]
+	if run.ToolUseID != "toolu_task2" || len(run.Messages) != 2 || run.Messages[0].UUID != "s-5" {
+		t.Errorf("a-2 subagent = %+v, want the run starting at s-5", run)
+	}
+
+	if len(detail.UnlinkedSubagents) != 0 {
+		t.Errorf("Expected every run to be linked, got %d unlinked", len(detail.UnlinkedSubagents))
+	}
+}
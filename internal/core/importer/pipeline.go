@@ -0,0 +1,184 @@
+package importer
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"github.com/neilberkman/ccrider/pkg/ccsessions"
+)
+
+// parsedFile is a worker's result for one session file, ready for the writer
+type parsedFile struct {
+	path    string
+	session *ccsessions.ParsedSession // nil if the file was skipped or failed
+	hash    string
+	prefix  string
+	err     error
+}
+
+// ImportDirectory imports all sessions from a directory tree.
+//
+// Files are discovered, hashed and parsed by a pool of Workers goroutines;
+// a single writer (the calling goroutine) commits each parsed file to the
+// database, since SQLite only allows one writer at a time. progress receives
+// exactly one Update per discovered file - skipped and failed files included -
+// so it reaches the total counted by walking the same directory.
+func (i *Importer) ImportDirectory(dirPath string, progress ProgressCallback) error {
+	err := i.importPaths(func(paths chan<- string) error {
+		return filepath.WalkDir(dirPath, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && filepath.Ext(path) == ".jsonl" {
+				paths <- path
+			}
+			return nil
+		})
+	}, progress)
+	if err != nil {
+		return fmt.Errorf("failed to walk directory: %w", err)
+	}
+	return nil
+}
+
+// ImportFiles imports the given session files through the same pipeline as
+// ImportDirectory, e.g. the files a watcher saw change
+func (i *Importer) ImportFiles(files []string, progress ProgressCallback) error {
+	return i.importPaths(func(paths chan<- string) error {
+		for _, file := range files {
+			paths <- file
+		}
+		return nil
+	}, progress)
+}
+
+// importPaths runs the import pipeline over the paths that discover sends,
+// returning discover's error once every discovered file has been handled
+func (i *Importer) importPaths(discover func(paths chan<- string) error, progress ProgressCallback) error {
+	// Load every saved import position up front so workers never need the
+	// database connection the writer is holding
+	states := map[string]*importState{}
+	if !i.Full {
+		var err error
+		states, err = i.loadImportStates()
+		if err != nil {
+			return fmt.Errorf("failed to load import state: %w", err)
+		}
+	}
+
+	workers := i.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	// Discovery: feed .jsonl paths to the workers
+	paths := make(chan string, workers)
+	var discoverErr error
+	go func() {
+		defer close(paths)
+		discoverErr = discover(paths)
+	}()
+
+	// Workers: stat, hash and parse. The results channel is bounded so at most
+	// a few parsed sessions are held in memory ahead of the writer.
+	results := make(chan parsedFile, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for path := range paths {
+				results <- parseForImport(path, states[path])
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Writer: commit results one at a time
+	for result := range results {
+		summary, firstMsg := "", ""
+
+		if result.err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", result.err)
+		} else if result.session != nil {
+			if err := i.writeSession(result.session, result.hash, result.prefix); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to import %s: %v\n", result.path, err)
+			} else {
+				summary = result.session.Summary
+				if len(result.session.Messages) > 0 {
+					firstMsg = result.session.Messages[0].TextContent
+					if len(firstMsg) > 100 {
+						firstMsg = firstMsg[:97] + "..."
+					}
+				}
+			}
+		}
+
+		// Update progress
+		if progress != nil {
+			progress.Update(summary, firstMsg)
+		}
+	}
+
+	// Note: Don't print "Skipped X files" - that's an interface concern (core should be silent)
+
+	// Discovery has finished: results only closes after paths did
+	return discoverErr
+}
+
+// parseForImport prepares one file for the writer. state is the file's saved
+// import position, or nil to parse it from the start.
+func parseForImport(path string, state *importState) parsedFile {
+	result := parsedFile{path: path}
+
+	// Get file info for mtime check
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		result.err = fmt.Errorf("failed to stat %s: %w", path, err)
+		return result
+	}
+	fileMtime := fileInfo.ModTime()
+
+	if state != nil && state.FileSize == fileInfo.Size() && !fileMtime.After(state.FileMtime) {
+		// File hasn't been modified since we last imported - skip
+		return result
+	}
+
+	// Resume after the last imported line if the file only grew; if it
+	// shrank or the already-imported bytes changed, reparse from the start
+	var offset int64
+	var startLine int
+	if state != nil && fileInfo.Size() >= state.ByteOffset {
+		prefix, err := prefixHash(path, state.ByteOffset)
+		if err == nil && prefix == state.PrefixHash {
+			offset = state.ByteOffset
+			startLine = state.LineCount
+		}
+	}
+
+	session, err := ccsessions.ParseFileFrom(path, offset, startLine)
+	if err != nil {
+		result.err = fmt.Errorf("failed to parse %s: %w", path, err)
+		return result
+	}
+	if offset > 0 {
+		// The tail may not repeat the sessionId; keep the one found before
+		session.SessionID = state.SessionID
+	}
+
+	result.hash, result.prefix, err = hashSession(session)
+	if err != nil {
+		result.err = fmt.Errorf("failed to import %s: %w", path, err)
+		return result
+	}
+
+	result.session = session
+	return result
+}
@@ -35,6 +35,10 @@ func NewProgressReporter(w io.Writer, total int) *ProgressReporter {
 // Update updates the progress bar with current session info
 func (p *ProgressReporter) Update(sessionSummary string, firstMsg string) {
 	p.current++
+	if p.current > p.total {
+		// Files created after the initial count
+		p.total = p.current
+	}
 
 	// Calculate progress percentage
 	pct := float64(p.current) / float64(p.total) * 100
@@ -45,7 +49,11 @@ func (p *ProgressReporter) Update(sessionSummary string, firstMsg string) {
 	bar := strings.Repeat("█", filled) + strings.Repeat("░", barWidth-filled)
 
 	// Truncate display text to fit terminal
+	// (skipped files report no summary - keep showing the last one)
 	displayText := sessionSummary
+	if displayText == "" {
+		displayText = p.lastMsg
+	}
 	if len(displayText) > 60 {
 		displayText = displayText[:57] + "..."
 	}
@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tmc/langchaingo/llms/anthropic"
+)
+
+// AnthropicProvider implements Provider using the Anthropic Messages API
+type AnthropicProvider struct {
+	llm *anthropic.LLM
+}
+
+// AnthropicConfig holds configuration for the Anthropic provider
+type AnthropicConfig struct {
+	APIKey  string // API key, defaults to $ANTHROPIC_API_KEY
+	Model   string // Model ID, defaults to claude-haiku-4-5
+	BaseURL string // API base URL (optional, for proxies)
+}
+
+// NewAnthropicProvider creates a new Anthropic provider
+func NewAnthropicProvider(cfg AnthropicConfig) (*AnthropicProvider, error) {
+	if cfg.Model == "" {
+		// Haiku for cost-effective summarization, as with Bedrock
+		cfg.Model = "claude-haiku-4-5"
+	}
+
+	opts := []anthropic.Option{anthropic.WithModel(cfg.Model)}
+	if cfg.APIKey != "" {
+		opts = append(opts, anthropic.WithToken(cfg.APIKey))
+	}
+	if cfg.BaseURL != "" {
+		opts = append(opts, anthropic.WithBaseURL(cfg.BaseURL))
+	}
+
+	llm, err := anthropic.New(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Anthropic LLM (set ANTHROPIC_API_KEY or api_key): %w", err)
+	}
+	return &AnthropicProvider{llm: llm}, nil
+}
+
+// GenerateText implements Provider
+func (p *AnthropicProvider) GenerateText(ctx context.Context, prompt string) (string, error) {
+	return generateText(ctx, p.llm, p.Name(), prompt)
+}
+
+// Name implements Provider
+func (p *AnthropicProvider) Name() string {
+	return "anthropic"
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/bedrockruntime"
-	"github.com/tmc/langchaingo/llms"
 	"github.com/tmc/langchaingo/llms/bedrock"
 )
 
@@ -72,14 +71,7 @@ func NewBedrockProvider(ctx context.Context, cfg BedrockConfig) (*BedrockProvide
 
 // GenerateText implements Provider
 func (p *BedrockProvider) GenerateText(ctx context.Context, prompt string) (string, error) {
-	response, err := llms.GenerateFromSinglePrompt(ctx, p.llm, prompt,
-		llms.WithMaxTokens(1024),
-		llms.WithTemperature(0.3),
-	)
-	if err != nil {
-		return "", fmt.Errorf("bedrock generation failed: %w", err)
-	}
-	return response, nil
+	return generateText(ctx, p.llm, p.Name(), prompt)
 }
 
 // Name implements Provider
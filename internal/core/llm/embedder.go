@@ -0,0 +1,36 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+)
+
+// Embedder is the interface for embedding backends, which turn text into
+// vectors for semantic search
+type Embedder interface {
+	// Embed returns one vector per text, in order
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+
+	// Name identifies the embedder and its model (e.g., "hash-384",
+	// "ollama:nomic-embed-text"). Vectors from different embedders aren't
+	// comparable, so stored vectors are keyed by it.
+	Name() string
+}
+
+// EmbedderConfig selects and configures an embedder
+type EmbedderConfig struct {
+	Provider string // "hash" (default, offline) or "ollama"
+	Model    string // Ollama model, defaults to nomic-embed-text
+	URL      string // Ollama server, defaults to http://localhost:11434
+}
+
+// NewEmbedder creates the embedder described by cfg
+func NewEmbedder(cfg EmbedderConfig) (Embedder, error) {
+	switch cfg.Provider {
+	case "", "hash":
+		return NewHashEmbedder(DefaultHashDimensions), nil
+	case "ollama":
+		return NewOllamaEmbedder(OllamaConfig{Model: cfg.Model, URL: cfg.URL}), nil
+	}
+	return nil, fmt.Errorf("unknown embeddings provider %q (want hash or ollama)", cfg.Provider)
+}
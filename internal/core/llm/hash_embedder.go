@@ -0,0 +1,114 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// DefaultHashDimensions is the vector size of the default HashEmbedder
+const DefaultHashDimensions = 384
+
+// HashEmbedder implements Embedder with the hashing trick: words, word
+// pairs and character trigrams are hashed into a fixed-size vector. It needs
+// no model and works offline, and because it shares subword features it
+// matches across inflections and partial words ("deploying" and "deployment")
+// that keyword search would miss. It only knows about surface similarity,
+// though - a model-based embedder (e.g. Ollama) also matches synonyms.
+type HashEmbedder struct {
+	dims int
+}
+
+// NewHashEmbedder creates a hashing embedder with the given vector size
+func NewHashEmbedder(dims int) *HashEmbedder {
+	return &HashEmbedder{dims: dims}
+}
+
+// Embed implements Embedder
+func (e *HashEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		vectors[i] = e.embed(text)
+	}
+	return vectors, nil
+}
+
+// Name implements Embedder
+func (e *HashEmbedder) Name() string {
+	return fmt.Sprintf("hash-%d", e.dims)
+}
+
+// Feature weights, relative to a whole word
+const (
+	hashBigramWeight  = 0.5
+	hashTrigramWeight = 0.25
+)
+
+func (e *HashEmbedder) embed(text string) []float32 {
+	vec := make([]float32, e.dims)
+	add := func(feature string, weight float32) {
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(feature))
+		sum := h.Sum64()
+		// One bit picks the sign so collisions cancel out rather than pile up
+		if sum>>63 == 1 {
+			weight = -weight
+		}
+		vec[sum%uint64(e.dims)] += weight
+	}
+
+	words := hashWords(text)
+	for i, word := range words {
+		add("w:"+word, 1)
+		if i > 0 {
+			add("b:"+words[i-1]+" "+word, hashBigramWeight)
+		}
+		padded := []rune("#" + word + "#")
+		for j := 0; j+3 <= len(padded); j++ {
+			add("t:"+string(padded[j:j+3]), hashTrigramWeight)
+		}
+	}
+
+	// Normalize so similarity is a dot product
+	var norm float64
+	for _, v := range vec {
+		norm += float64(v) * float64(v)
+	}
+	if norm > 0 {
+		scale := float32(1 / math.Sqrt(norm))
+		for i := range vec {
+			vec[i] *= scale
+		}
+	}
+	return vec
+}
+
+// hashStopWords are too common to say what a text is about
+var hashStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true, "be": true,
+	"but": true, "by": true, "can": true, "do": true, "for": true, "from": true, "has": true,
+	"have": true, "i": true, "if": true, "in": true, "is": true, "it": true, "its": true,
+	"let": true, "me": true, "my": true, "not": true, "of": true, "on": true, "or": true,
+	"so": true, "that": true, "the": true, "this": true, "to": true, "was": true, "we": true,
+	"will": true, "with": true, "you": true, "your": true,
+}
+
+// hashWords splits text into lowercase words, dropping stop words
+func hashWords(text string) []string {
+	fields := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '_'
+	})
+	words := fields[:0]
+	for _, w := range fields {
+		if !hashStopWords[w] {
+			words = append(words, w)
+		}
+	}
+	return words
+}
@@ -0,0 +1,48 @@
+package llm
+
+import (
+	"context"
+	"testing"
+)
+
+func TestHashEmbedder(t *testing.T) {
+	e := NewHashEmbedder(DefaultHashDimensions)
+	vectors, err := e.Embed(context.Background(), []string{
+		"deploying the service to production",
+		"production deployment of our service failed",
+		"chocolate cake recipe with frosting",
+		"",
+	})
+	if err != nil {
+		t.Fatalf("Embed() error = %v", err)
+	}
+
+	dot := func(a, b []float32) float64 {
+		var sum float64
+		for i := range a {
+			sum += float64(a[i]) * float64(b[i])
+		}
+		return sum
+	}
+	if n := dot(vectors[0], vectors[0]); n < 0.999 || n > 1.001 {
+		t.Errorf("Expected a unit vector, got norm² %f", n)
+	}
+	related, unrelated := dot(vectors[0], vectors[1]), dot(vectors[0], vectors[2])
+	if related <= unrelated+0.1 {
+		t.Errorf("Expected related texts to be more similar: related %f, unrelated %f", related, unrelated)
+	}
+	if dot(vectors[3], vectors[3]) != 0 {
+		t.Error("Expected a zero vector for empty text")
+	}
+
+	// Deterministic
+	again, _ := e.Embed(context.Background(), []string{"deploying the service to production"})
+	for i := range again[0] {
+		if again[0][i] != vectors[0][i] {
+			t.Fatal("Expected the same vector for the same text")
+		}
+	}
+	if e.Name() != "hash-384" {
+		t.Errorf("Name() = %q", e.Name())
+	}
+}
@@ -0,0 +1,41 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/tmc/langchaingo/llms/ollama"
+)
+
+// OllamaProvider implements Provider using a local Ollama server, so
+// nothing leaves the machine
+type OllamaProvider struct {
+	llm *ollama.LLM
+}
+
+// NewOllamaProvider creates a new Ollama provider. The model must already be
+// pulled (ollama pull llama3.2).
+func NewOllamaProvider(cfg OllamaConfig) (*OllamaProvider, error) {
+	if cfg.Model == "" {
+		cfg.Model = "llama3.2"
+	}
+	if cfg.URL == "" {
+		cfg.URL = defaultOllamaURL
+	}
+
+	llm, err := ollama.New(ollama.WithModel(cfg.Model), ollama.WithServerURL(cfg.URL))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Ollama LLM: %w", err)
+	}
+	return &OllamaProvider{llm: llm}, nil
+}
+
+// GenerateText implements Provider
+func (p *OllamaProvider) GenerateText(ctx context.Context, prompt string) (string, error) {
+	return generateText(ctx, p.llm, p.Name(), prompt)
+}
+
+// Name implements Provider
+func (p *OllamaProvider) Name() string {
+	return "ollama"
+}
@@ -0,0 +1,88 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// defaultOllamaURL is where Ollama listens by default
+const defaultOllamaURL = "http://localhost:11434"
+
+// OllamaEmbedder implements Embedder using a local Ollama server
+type OllamaEmbedder struct {
+	client *http.Client
+	url    string
+	model  string
+}
+
+// OllamaConfig holds configuration for the Ollama embedder and provider
+type OllamaConfig struct {
+	Model string // Defaults to nomic-embed-text for embeddings, llama3.2 for text
+	URL   string // Server URL, defaults to http://localhost:11434
+}
+
+// NewOllamaEmbedder creates a new Ollama embedder. The model must already be
+// pulled (ollama pull nomic-embed-text).
+func NewOllamaEmbedder(cfg OllamaConfig) *OllamaEmbedder {
+	if cfg.Model == "" {
+		cfg.Model = "nomic-embed-text"
+	}
+	if cfg.URL == "" {
+		cfg.URL = defaultOllamaURL
+	}
+	return &OllamaEmbedder{
+		client: &http.Client{Timeout: 5 * time.Minute},
+		url:    strings.TrimRight(cfg.URL, "/"),
+		model:  cfg.Model,
+	}
+}
+
+// Embed implements Embedder
+func (e *OllamaEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"model": e.model,
+		"input": texts,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url+"/api/embed", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ollama embedding failed (is ollama running?): %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("ollama embedding failed: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var result struct {
+		Embeddings [][]float32 `json:"embeddings"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode ollama response: %w", err)
+	}
+	if len(result.Embeddings) != len(texts) {
+		return nil, fmt.Errorf("ollama returned %d embeddings for %d texts", len(result.Embeddings), len(texts))
+	}
+	return result.Embeddings, nil
+}
+
+// Name implements Embedder
+func (e *OllamaEmbedder) Name() string {
+	return "ollama:" + e.model
+}
@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/tmc/langchaingo/llms/openai"
+)
+
+// OpenAIProvider implements Provider using OpenAI-compatible chat
+// completions: OpenAI itself, or a local server such as vLLM, LM Studio or
+// llama.cpp's llama-server
+type OpenAIProvider struct {
+	llm *openai.LLM
+}
+
+// OpenAIConfig holds configuration for the OpenAI provider
+type OpenAIConfig struct {
+	APIKey  string // API key, defaults to $OPENAI_API_KEY (optional with BaseURL)
+	Model   string // Model ID, defaults to gpt-4o-mini
+	BaseURL string // API base URL including /v1, e.g. http://localhost:8000/v1
+}
+
+// NewOpenAIProvider creates a new OpenAI provider
+func NewOpenAIProvider(cfg OpenAIConfig) (*OpenAIProvider, error) {
+	if cfg.Model == "" {
+		cfg.Model = "gpt-4o-mini"
+	}
+	if cfg.APIKey == "" && cfg.BaseURL != "" && os.Getenv("OPENAI_API_KEY") == "" {
+		// Local servers don't check the key, but the client requires one
+		cfg.APIKey = "none"
+	}
+
+	opts := []openai.Option{openai.WithModel(cfg.Model)}
+	if cfg.APIKey != "" {
+		opts = append(opts, openai.WithToken(cfg.APIKey))
+	}
+	if cfg.BaseURL != "" {
+		opts = append(opts, openai.WithBaseURL(cfg.BaseURL))
+	}
+
+	llm, err := openai.New(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OpenAI LLM (set OPENAI_API_KEY, api_key or url): %w", err)
+	}
+	return &OpenAIProvider{llm: llm}, nil
+}
+
+// GenerateText implements Provider
+func (p *OpenAIProvider) GenerateText(ctx context.Context, prompt string) (string, error) {
+	return generateText(ctx, p.llm, p.Name(), prompt)
+}
+
+// Name implements Provider
+func (p *OpenAIProvider) Name() string {
+	return "openai"
+}
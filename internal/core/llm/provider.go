@@ -2,6 +2,10 @@ package llm
 
 import (
 	"context"
+	"fmt"
+
+	"github.com/neilberkman/ccrider/internal/core/db"
+	"github.com/tmc/langchaingo/llms"
 )
 
 // Provider is the interface for LLM backends
@@ -13,12 +17,79 @@ type Provider interface {
 	Name() string
 }
 
+// ProviderConfig selects and configures an LLM provider
+type ProviderConfig struct {
+	Provider string // "bedrock" (default), "anthropic", "openai", "ollama" or "replay"
+	Model    string // Model ID; each provider has a default
+	URL      string // API base URL, for OpenAI-compatible servers, Ollama or a proxy
+	APIKey   string // Anthropic or OpenAI API key (default from the provider's usual env var)
+
+	Bedrock BedrockConfig // AWS settings for bedrock; its ModelID is set from Model
+
+	// FixtureDir holds replay fixtures. The replay provider answers from
+	// them; any other provider also records its responses there.
+	FixtureDir string
+}
+
+// NewProvider creates the provider described by cfg
+func NewProvider(ctx context.Context, cfg ProviderConfig) (Provider, error) {
+	if cfg.Provider == "replay" {
+		return NewReplayProvider(ReplayConfig{Dir: cfg.FixtureDir})
+	}
+
+	provider, err := newModelProvider(ctx, cfg)
+	if err != nil || cfg.FixtureDir == "" {
+		return provider, err
+	}
+	return NewReplayProvider(ReplayConfig{Dir: cfg.FixtureDir, Record: provider})
+}
+
+// newModelProvider creates the provider for a real model
+func newModelProvider(ctx context.Context, cfg ProviderConfig) (Provider, error) {
+	switch cfg.Provider {
+	case "", "bedrock":
+		if cfg.Model != "" {
+			cfg.Bedrock.ModelID = cfg.Model
+		}
+		return NewBedrockProvider(ctx, cfg.Bedrock)
+	case "anthropic":
+		return NewAnthropicProvider(AnthropicConfig{APIKey: cfg.APIKey, Model: cfg.Model, BaseURL: cfg.URL})
+	case "openai":
+		return NewOpenAIProvider(OpenAIConfig{APIKey: cfg.APIKey, Model: cfg.Model, BaseURL: cfg.URL})
+	case "ollama":
+		return NewOllamaProvider(OllamaConfig{Model: cfg.Model, URL: cfg.URL})
+	}
+	return nil, fmt.Errorf("unknown LLM provider %q (want bedrock, anthropic, openai, ollama or replay)", cfg.Provider)
+}
+
+// maxResponseTokens caps an LLM response. Session summaries are a JSON
+// object with a multi-paragraph summary and several lists, which a lower
+// limit can cut off mid-object.
+const maxResponseTokens = 2048
+
+// generateText runs a single prompt with the settings every provider uses
+// for summaries
+func generateText(ctx context.Context, model llms.Model, name, prompt string) (string, error) {
+	response, err := llms.GenerateFromSinglePrompt(ctx, model, prompt,
+		llms.WithMaxTokens(maxResponseTokens),
+		llms.WithTemperature(0.3),
+	)
+	if err != nil {
+		return "", fmt.Errorf("%s generation failed: %w", name, err)
+	}
+	return response, nil
+}
+
 // SummaryRequest contains the data needed to generate a session summary
 type SummaryRequest struct {
 	SessionID       string
 	ProjectPath     string
 	Messages        []Message
 	ExistingSummary string // Claude Code's native summary, if any
+
+	// Previous is the stored summary of the session, if any. When the
+	// session has grown since, only the new messages are summarized.
+	Previous *db.SessionSummary
 }
 
 // Message is a simplified message for summarization
@@ -0,0 +1,119 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeServer answers one API path with a canned response, recording the
+// request body
+func fakeServer(t *testing.T, path string, response interface{}) (*httptest.Server, *map[string]interface{}) {
+	t.Helper()
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.Error(w, "unexpected path "+r.URL.Path, http.StatusNotFound)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+	return server, &got
+}
+
+func TestNewProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      func(url string) ProviderConfig
+		path     string
+		response interface{}
+		model    string
+	}{
+		{
+			name: "anthropic",
+			cfg: func(url string) ProviderConfig {
+				return ProviderConfig{Provider: "anthropic", APIKey: "test-key", URL: url}
+			},
+			path: "/messages",
+			response: map[string]interface{}{
+				"id": "msg_1", "type": "message", "role": "assistant",
+				"content":     []map[string]string{{"type": "text", "text": "Fixed the login bug"}},
+				"stop_reason": "end_turn",
+			},
+			model: "claude-haiku-4-5",
+		},
+		{
+			name: "openai",
+			cfg: func(url string) ProviderConfig {
+				// A local OpenAI-compatible server needs no key
+				return ProviderConfig{Provider: "openai", Model: "qwen2.5", URL: url}
+			},
+			path: "/chat/completions",
+			response: map[string]interface{}{
+				"id": "chatcmpl-1", "object": "chat.completion",
+				"choices": []map[string]interface{}{{
+					"index":         0,
+					"message":       map[string]string{"role": "assistant", "content": "Fixed the login bug"},
+					"finish_reason": "stop",
+				}},
+			},
+			model: "qwen2.5",
+		},
+		{
+			name: "ollama",
+			cfg: func(url string) ProviderConfig {
+				return ProviderConfig{Provider: "ollama", URL: url}
+			},
+			path: "/api/chat",
+			response: map[string]interface{}{
+				"model":   "llama3.2",
+				"message": map[string]string{"role": "assistant", "content": "Fixed the login bug"},
+				"done":    true,
+			},
+			model: "llama3.2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OPENAI_API_KEY", "")
+			server, got := fakeServer(t, tt.path, tt.response)
+
+			provider, err := NewProvider(context.Background(), tt.cfg(server.URL))
+			if err != nil {
+				t.Fatalf("NewProvider() error = %v", err)
+			}
+			if provider.Name() != tt.name {
+				t.Errorf("Name() = %q, want %q", provider.Name(), tt.name)
+			}
+
+			text, err := provider.GenerateText(context.Background(), "Summarize this session")
+			if err != nil {
+				t.Fatalf("GenerateText() error = %v", err)
+			}
+			if text != "Fixed the login bug" {
+				t.Errorf("GenerateText() = %q", text)
+			}
+			if (*got)["model"] != tt.model {
+				t.Errorf("Request model = %v, want %q", (*got)["model"], tt.model)
+			}
+		})
+	}
+}
+
+func TestNewProvider_Errors(t *testing.T) {
+	if _, err := NewProvider(context.Background(), ProviderConfig{Provider: "gemini"}); err == nil || !strings.Contains(err.Error(), "unknown LLM provider") {
+		t.Errorf("Expected an unknown provider error, got %v", err)
+	}
+
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	if _, err := NewProvider(context.Background(), ProviderConfig{Provider: "anthropic"}); err == nil {
+		t.Error("Expected an error for anthropic without an API key")
+	}
+}
@@ -0,0 +1,89 @@
+package llm
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ReplayConfig configures a ReplayProvider
+type ReplayConfig struct {
+	Dir string // Fixture directory
+
+	// Record, if set, answers prompts that have no fixture yet, and its
+	// responses are saved as new fixtures. Without it, a prompt with no
+	// fixture is an error.
+	Record Provider
+}
+
+// ReplayProvider answers prompts from fixture files, so summarization can
+// run offline and deterministically: in tests, or end to end with
+// `ccrider summarize --provider replay`. Fixtures are keyed by PromptHash:
+// <hash>.txt holds the response, and <hash>.prompt.txt the prompt it
+// answers, for reading and diffing.
+type ReplayProvider struct {
+	dir    string
+	record Provider
+}
+
+// NewReplayProvider creates a provider answering from the fixtures in cfg.Dir
+func NewReplayProvider(cfg ReplayConfig) (*ReplayProvider, error) {
+	if cfg.Dir == "" {
+		return nil, fmt.Errorf("replay needs a fixture directory")
+	}
+	if cfg.Record != nil {
+		if err := os.MkdirAll(cfg.Dir, 0755); err != nil {
+			return nil, fmt.Errorf("create fixture directory: %w", err)
+		}
+	} else if _, err := os.Stat(cfg.Dir); err != nil {
+		return nil, fmt.Errorf("replay fixtures: %w", err)
+	}
+	return &ReplayProvider{dir: cfg.Dir, record: cfg.Record}, nil
+}
+
+// PromptHash is the key a prompt's response is stored under
+func PromptHash(prompt string) string {
+	sum := sha256.Sum256([]byte(prompt))
+	return hex.EncodeToString(sum[:8])
+}
+
+// GenerateText implements Provider
+func (p *ReplayProvider) GenerateText(ctx context.Context, prompt string) (string, error) {
+	hash := PromptHash(prompt)
+	path := filepath.Join(p.dir, hash+".txt")
+
+	response, err := os.ReadFile(path)
+	if err == nil {
+		return string(response), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return "", fmt.Errorf("replay: %w", err)
+	}
+	if p.record == nil {
+		return "", fmt.Errorf("replay: no recorded response for prompt %s in %s", hash, p.dir)
+	}
+
+	text, err := p.record.GenerateText(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filepath.Join(p.dir, hash+".prompt.txt"), []byte(prompt), 0644); err != nil {
+		return "", fmt.Errorf("record prompt: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		return "", fmt.Errorf("record response: %w", err)
+	}
+	return text, nil
+}
+
+// Name implements Provider
+func (p *ReplayProvider) Name() string {
+	if p.record != nil {
+		return p.record.Name() + ", recording"
+	}
+	return "replay"
+}
@@ -0,0 +1,58 @@
+package llm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReplayProvider(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "fixtures")
+
+	// Record: the real provider answers once, then the fixture does
+	real := &scriptedProvider{}
+	recorder, err := NewReplayProvider(ReplayConfig{Dir: dir, Record: real})
+	if err != nil {
+		t.Fatalf("NewReplayProvider() error = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := recorder.GenerateText(ctx, "Summarize session A"); err != nil {
+			t.Fatalf("GenerateText() error = %v", err)
+		}
+	}
+	if real.calls != 1 {
+		t.Errorf("Recorded provider called %d times, want 1", real.calls)
+	}
+	prompt, err := os.ReadFile(filepath.Join(dir, PromptHash("Summarize session A")+".prompt.txt"))
+	if err != nil || string(prompt) != "Summarize session A" {
+		t.Errorf("Recorded prompt = %q, %v", prompt, err)
+	}
+
+	// Replay: answers from the fixtures alone
+	replay, err := NewProvider(ctx, ProviderConfig{Provider: "replay", FixtureDir: dir})
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	if replay.Name() != "replay" {
+		t.Errorf("Name() = %q", replay.Name())
+	}
+	text, err := replay.GenerateText(ctx, "Summarize session A")
+	if err != nil || text != `{"one_line": "ENA-42 login fix", "full": "Fixed the login handler."}` {
+		t.Errorf("GenerateText() = %q, %v", text, err)
+	}
+	if _, err := replay.GenerateText(ctx, "Summarize session B"); err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("Expected an error for an unrecorded prompt, got %v", err)
+	}
+}
+
+func TestNewReplayProvider_Errors(t *testing.T) {
+	if _, err := NewReplayProvider(ReplayConfig{}); err == nil {
+		t.Error("Expected an error without a fixture directory")
+	}
+	if _, err := NewReplayProvider(ReplayConfig{Dir: filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("Expected an error for a missing fixture directory")
+	}
+}
@@ -0,0 +1,194 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/neilberkman/ccrider/internal/core/db"
+)
+
+// JobRunner works through a summary job's sessions, several at a time,
+// recording each session's status so an interrupted run can be resumed
+type JobRunner struct {
+	DB         *db.DB
+	Extractor  *MetadataExtractor
+	Summarizer *HierarchicalSummarizer // nil to only extract metadata
+
+	// Concurrency is how many sessions are processed at once (default 1)
+	Concurrency int
+
+	// Resummarize summarizes sessions from scratch. Otherwise a session
+	// that has grown since it was summarized only has its new messages
+	// summarized, and the result is combined with its stored chunks.
+	Resummarize bool
+
+	// OnResult, if set, is called after each session finishes. Calls are
+	// never concurrent.
+	OnResult func(SessionResult)
+}
+
+// SessionResult is how one session of a job went
+type SessionResult struct {
+	Item    db.SummaryJobItem
+	Status  string             // db.JobItemDone, JobItemSkipped or JobItemFailed
+	Summary *db.SessionSummary // nil unless summarized
+	Issues  int
+	Files   int
+	Err     error
+}
+
+// Run processes items of job. Once ctx is cancelled no new sessions are
+// started, sessions in flight are left pending, and ctx's error is returned.
+func (r *JobRunner) Run(ctx context.Context, job *db.SummaryJob, items []db.SummaryJobItem) error {
+	queue := make(chan db.SummaryJobItem)
+	var mu sync.Mutex // Serializes OnResult
+	var wg sync.WaitGroup
+
+	for range max(r.Concurrency, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range queue {
+				if ctx.Err() != nil {
+					continue // Interrupted before it started
+				}
+				result := r.process(ctx, item)
+				if result.Err != nil && ctx.Err() != nil {
+					continue // Interrupted: leave it pending for the next run
+				}
+				if err := r.DB.SetSummaryJobItemStatus(job.ID, item.SessionID, result.Status, errString(result.Err)); err != nil && result.Err == nil {
+					result.Status = db.JobItemFailed
+					result.Err = fmt.Errorf("record job status: %w", err)
+				}
+				if r.OnResult != nil {
+					mu.Lock()
+					r.OnResult(result)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+feed:
+	for _, item := range items {
+		select {
+		case <-ctx.Done():
+			break feed
+		case queue <- item:
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	return ctx.Err()
+}
+
+// process extracts metadata from one session and summarizes it
+func (r *JobRunner) process(ctx context.Context, item db.SummaryJobItem) SessionResult {
+	result := SessionResult{Item: item, Status: db.JobItemFailed}
+
+	messages, err := loadMessages(r.DB, item.SessionID)
+	if err != nil {
+		result.Err = fmt.Errorf("get messages: %w", err)
+		return result
+	}
+	if len(messages) == 0 {
+		result.Status = db.JobItemSkipped
+		return result
+	}
+
+	// Extract metadata (always do this)
+	issues := r.Extractor.ExtractIssues(messages)
+	files := r.Extractor.ExtractFiles(messages)
+	result.Issues, result.Files = len(issues), len(files)
+
+	if len(issues) > 0 {
+		for i := range issues {
+			issues[i].SessionID = item.SessionID
+		}
+		if err := r.DB.SaveSessionIssues(item.SessionID, issues); err != nil {
+			result.Err = fmt.Errorf("save issues: %w", err)
+			return result
+		}
+	}
+	if len(files) > 0 {
+		for i := range files {
+			files[i].SessionID = item.SessionID
+		}
+		if err := r.DB.SaveSessionFiles(item.SessionID, files); err != nil {
+			result.Err = fmt.Errorf("save files: %w", err)
+			return result
+		}
+	}
+
+	if r.Summarizer != nil {
+		req := SummaryRequest{
+			SessionID:   item.SessionUUID,
+			ProjectPath: item.ProjectPath,
+			Messages:    messages,
+		}
+		if !r.Resummarize {
+			if req.Previous, err = r.DB.GetSessionSummary(item.SessionID); err != nil {
+				result.Err = fmt.Errorf("get summary: %w", err)
+				return result
+			}
+		}
+
+		summary, err := r.Summarizer.SummarizeSession(ctx, req)
+		if err != nil {
+			result.Err = fmt.Errorf("summarize: %w", err)
+			return result
+		}
+
+		summary.SessionID = item.SessionID
+		if err := r.DB.SaveSessionSummary(*summary); err != nil {
+			result.Err = fmt.Errorf("save summary: %w", err)
+			return result
+		}
+		result.Summary = summary
+	}
+
+	result.Status = db.JobItemDone
+	return result
+}
+
+// loadMessages returns a session's visible messages in order, so there are
+// as many as its message_count
+func loadMessages(database *db.DB, sessionID int64) ([]Message, error) {
+	rows, err := database.Query(`
+		SELECT sender, text_content
+		FROM messages
+		WHERE session_id = ? AND `+db.VisibleMessageCondition+`
+		ORDER BY sequence
+	`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var messages []Message
+	for rows.Next() {
+		var sender, content string
+		if err := rows.Scan(&sender, &content); err != nil {
+			return nil, err
+		}
+		msgType := "user"
+		if sender == "assistant" {
+			msgType = "assistant"
+		}
+		messages = append(messages, Message{
+			Type:    msgType,
+			Content: content,
+		})
+	}
+
+	return messages, rows.Err()
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
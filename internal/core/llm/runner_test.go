@@ -0,0 +1,245 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/neilberkman/ccrider/internal/core/db"
+)
+
+// scriptedProvider answers every prompt with a fixed summary, failing
+// prompts that contain failOn. It is safe for concurrent use.
+type scriptedProvider struct {
+	failOn string
+	mu     sync.Mutex
+	calls  int
+}
+
+func (p *scriptedProvider) GenerateText(ctx context.Context, prompt string) (string, error) {
+	p.mu.Lock()
+	p.calls++
+	p.mu.Unlock()
+	if p.failOn != "" && strings.Contains(prompt, p.failOn) {
+		return "", errors.New("invalid request")
+	}
+	return `{"one_line": "ENA-42 login fix", "full": "Fixed the login handler."}`, nil
+}
+
+func (p *scriptedProvider) Name() string { return "scripted" }
+
+// setupJobDB creates a database with a session per entry of sessions,
+// holding that many messages, and a job over all of them
+func setupJobDB(t *testing.T, sessions map[string]int) (*db.DB, *db.SummaryJob, []db.SummaryJobItem) {
+	t.Helper()
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Remove(tmpfile.Name()) })
+	_ = tmpfile.Close()
+
+	database, err := db.New(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("db.New() error = %v", err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+
+	var ids []int64
+	for uuid, count := range sessions {
+		result, err := database.Exec(`
+			INSERT INTO sessions (session_id, project_path, message_count, created_at, updated_at)
+			VALUES (?, '/test/project', ?, datetime('now'), datetime('now'))
+		`, uuid, count)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id, _ := result.LastInsertId()
+		ids = append(ids, id)
+
+		for i := 0; i < count; i++ {
+			sender := "human"
+			if i%2 == 1 {
+				sender = "assistant"
+			}
+			if _, err := database.Exec(`
+				INSERT INTO messages (uuid, session_id, type, sender, text_content, timestamp, sequence)
+				VALUES (?, ?, ?, ?, ?, datetime('now'), ?)
+			`, fmt.Sprintf("%s-%d", uuid, i), id, sender, sender, fmt.Sprintf("%s message %d about ENA-42", uuid, i), i); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	job, err := database.CreateSummaryJob("test", ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, err := database.ListSummaryJobItems(job.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return database, job, items
+}
+
+func TestJobRunner(t *testing.T) {
+	database, job, items := setupJobDB(t, map[string]int{
+		"short":  4,
+		"long":   250, // Chunked
+		"empty":  0,
+		"broken": 2,
+	})
+
+	provider := &scriptedProvider{failOn: "broken message"}
+	summarizer := NewHierarchicalSummarizer(provider)
+	summarizer.ChunkConcurrency = 3
+
+	results := make(map[string]SessionResult)
+	runner := &JobRunner{
+		DB:          database,
+		Extractor:   NewMetadataExtractor(),
+		Summarizer:  summarizer,
+		Concurrency: 2,
+		OnResult: func(r SessionResult) {
+			results[r.Item.SessionUUID] = r
+		},
+	}
+	if err := runner.Run(context.Background(), job, items); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	want := map[string]string{
+		"short":  db.JobItemDone,
+		"long":   db.JobItemDone,
+		"empty":  db.JobItemSkipped,
+		"broken": db.JobItemFailed,
+	}
+	for uuid, status := range want {
+		if got := results[uuid].Status; got != status {
+			t.Errorf("%s status = %q, want %q (err %v)", uuid, got, status, results[uuid].Err)
+		}
+	}
+	if got := len(results["long"].Summary.ChunkSummaries); got != 3 {
+		t.Errorf("long session has %d chunk summaries, want 3", got)
+	}
+	if results["short"].Issues != 1 {
+		t.Errorf("short session issues = %d, want 1", results["short"].Issues)
+	}
+
+	var summaries int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM session_summaries`).Scan(&summaries); err != nil {
+		t.Fatal(err)
+	}
+	if summaries != 2 {
+		t.Errorf("Saved %d summaries, want 2", summaries)
+	}
+
+	// Only the failed session is left to retry
+	found, err := database.FindSummaryJob("test")
+	if err != nil || found == nil || found.Remaining != 1 {
+		t.Errorf("FindSummaryJob() = %+v, %v, want 1 session left", found, err)
+	}
+}
+
+func TestJobRunner_Interrupted(t *testing.T) {
+	database, job, items := setupJobDB(t, map[string]int{"a": 2, "b": 2, "c": 2})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	provider := &scriptedProvider{}
+	runner := &JobRunner{
+		DB:          database,
+		Extractor:   NewMetadataExtractor(),
+		Summarizer:  NewHierarchicalSummarizer(provider),
+		Concurrency: 2,
+	}
+	if err := runner.Run(ctx, job, items); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() error = %v, want context.Canceled", err)
+	}
+
+	// Nothing may be marked finished or failed by the interruption
+	found, err := database.FindSummaryJob("test")
+	if err != nil || found == nil || found.Remaining != 3 {
+		t.Errorf("FindSummaryJob() = %+v, %v, want all 3 sessions left", found, err)
+	}
+	var failed int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM summary_job_items WHERE status != ?`, db.JobItemPending).Scan(&failed); err != nil {
+		t.Fatal(err)
+	}
+	if failed != 0 {
+		t.Errorf("%d sessions left non-pending after interruption", failed)
+	}
+}
+
+func TestJobRunner_ExtendsGrownSession(t *testing.T) {
+	database, job, items := setupJobDB(t, map[string]int{"grows": 150})
+
+	provider := &promptRecorder{}
+	runner := &JobRunner{
+		DB:         database,
+		Extractor:  NewMetadataExtractor(),
+		Summarizer: NewHierarchicalSummarizer(provider),
+	}
+	if err := runner.Run(context.Background(), job, items); err != nil {
+		t.Fatal(err)
+	}
+
+	// The session grows by 110 messages
+	sessionID := items[0].SessionID
+	for i := 150; i < 260; i++ {
+		if _, err := database.Exec(`
+			INSERT INTO messages (uuid, session_id, type, sender, text_content, timestamp, sequence)
+			VALUES (?, ?, 'human', 'human', ?, datetime('now'), ?)
+		`, fmt.Sprintf("grows-%d", i), sessionID, fmt.Sprintf("grows message %d", i), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	provider.prompts = nil
+	job, err := database.CreateSummaryJob("test", []int64{sessionID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runner.Run(context.Background(), job, items); err != nil {
+		t.Fatal(err)
+	}
+	if len(provider.prompts) != 3 {
+		t.Errorf("Extending made %d LLM calls, want 2 chunks and a combine", len(provider.prompts))
+	}
+
+	summary, err := database.GetSessionSummary(sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := chunkRanges(summary.ChunkSummaries); got != "0:0-99 1:90-189 2:180-259" {
+		t.Errorf("Stored chunks = %s", got)
+	}
+	if summary.MessageCount != 260 || summary.Version != 2 {
+		t.Errorf("Summary covers %d messages at version %d, want 260 at version 2", summary.MessageCount, summary.Version)
+	}
+
+	// Summarizing from scratch replaces the chunks, leaving none stale
+	runner.Resummarize = true
+	if _, err := database.Exec(`DELETE FROM messages WHERE session_id = ? AND sequence >= 120`, sessionID); err != nil {
+		t.Fatal(err)
+	}
+	job, err = database.CreateSummaryJob("test", []int64{sessionID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runner.Run(context.Background(), job, items); err != nil {
+		t.Fatal(err)
+	}
+	summary, err = database.GetSessionSummary(sessionID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := chunkRanges(summary.ChunkSummaries); got != "0:0-99 1:90-119" {
+		t.Errorf("Stored chunks after re-summarizing = %s", got)
+	}
+}
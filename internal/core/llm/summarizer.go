@@ -2,10 +2,12 @@ package llm
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"path/filepath"
-	"regexp"
 	"strings"
+	"sync"
 
 	"github.com/neilberkman/ccrider/internal/core/db"
 )
@@ -22,6 +24,10 @@ const (
 // HierarchicalSummarizer implements progressive chunk-based summarization
 type HierarchicalSummarizer struct {
 	provider Provider
+
+	// ChunkConcurrency is how many chunks of a long session are summarized
+	// at once (default 1)
+	ChunkConcurrency int
 }
 
 // NewHierarchicalSummarizer creates a new hierarchical summarizer
@@ -40,51 +46,141 @@ func (s *HierarchicalSummarizer) SummarizeSession(ctx context.Context, req Summa
 
 	var summary db.SessionSummary
 	summary.MessageCount = msgCount
+	summary.Version = 1 // Saving over a stored summary bumps its version
 
 	// Short sessions: single pass
 	if msgCount <= ChunkSize {
-		oneLine, full, tokens, err := s.summarizeDirect(ctx, req.ProjectPath, messages)
+		oneLine, full, details, tokens, err := s.summarizeDirect(ctx, req.ProjectPath, messages)
 		if err != nil {
 			return nil, err
 		}
 		summary.OneLine = oneLine
 		summary.Full = full
+		summary.Details = details
 		summary.TokensApprox = tokens
 		return &summary, nil
 	}
 
-	// Long sessions: chunk and combine
-	chunks := s.chunkMessages(messages)
-	var chunkSummaries []db.ChunkSummary
-
-	for i, chunk := range chunks {
-		chunkText, tokens, err := s.summarizeChunk(ctx, req.ProjectPath, chunk.messages, i, len(chunks))
-		if err != nil {
-			return nil, fmt.Errorf("summarize chunk %d: %w", i, err)
-		}
-		chunkSummaries = append(chunkSummaries, db.ChunkSummary{
-			ChunkIndex:   i,
-			MessageStart: chunk.startSeq,
-			MessageEnd:   chunk.endSeq,
-			Summary:      chunkText,
-			TokensApprox: tokens,
-		})
+	// Long sessions: chunk and combine, reusing the chunks of a previous
+	// summary so a grown session only pays for its new messages
+	kept := reusableChunks(req.Previous, msgCount)
+	start := 0
+	if len(kept) > 0 {
+		start = kept[len(kept)-1].MessageEnd + 1 - ChunkOverlap
+	}
+	chunks := s.chunkMessages(messages[start:])
+	for i := range chunks {
+		chunks[i].startSeq += start
+		chunks[i].endSeq += start
+	}
+	newSummaries, err := s.summarizeChunks(ctx, req.ProjectPath, chunks, len(kept))
+	if err != nil {
+		return nil, err
 	}
+	chunkSummaries := append(kept, newSummaries...)
 
 	// Combine chunk summaries into final summary
-	oneLine, full, tokens, err := s.combineChunks(ctx, req.ProjectPath, chunkSummaries)
+	oneLine, full, details, tokens, err := s.combineChunks(ctx, req.ProjectPath, chunkSummaries)
 	if err != nil {
 		return nil, fmt.Errorf("combine chunks: %w", err)
 	}
 
 	summary.OneLine = oneLine
 	summary.Full = full
+	summary.Details = details
 	summary.TokensApprox = tokens
 	summary.ChunkSummaries = chunkSummaries
 
 	return &summary, nil
 }
 
+// reusableChunks returns the chunk summaries of prev that still hold for a
+// session that has grown to msgCount messages: its complete chunks, or a
+// short session's whole summary as the first chunk. It returns nil when
+// there is nothing to reuse and the session must be summarized from scratch.
+func reusableChunks(prev *db.SessionSummary, msgCount int) []db.ChunkSummary {
+	if prev == nil || prev.MessageCount <= ChunkOverlap || prev.MessageCount >= msgCount {
+		return nil // Nothing stored, or the session didn't grow (re-imported?)
+	}
+
+	if len(prev.ChunkSummaries) == 0 {
+		if prev.MessageCount > ChunkSize || prev.Full == "" {
+			return nil
+		}
+		return []db.ChunkSummary{{
+			ChunkIndex:   0,
+			MessageStart: 0,
+			MessageEnd:   prev.MessageCount - 1,
+			Summary:      prev.Full,
+			TokensApprox: prev.TokensApprox,
+		}}
+	}
+
+	var kept []db.ChunkSummary
+	for i, c := range prev.ChunkSummaries {
+		if c.ChunkIndex != i || c.MessageEnd >= prev.MessageCount {
+			return nil // Not the chunks chunkMessages makes
+		}
+		// The last chunk is usually partial: redo it along with the new
+		// messages rather than leave a run of small chunks behind
+		if c.MessageEnd-c.MessageStart+1 < ChunkSize {
+			break
+		}
+		kept = append(kept, c)
+	}
+	return kept
+}
+
+// summarizeChunks summarizes each chunk, up to ChunkConcurrency at a time.
+// first is the index of the first chunk, for chunks continuing a session.
+func (s *HierarchicalSummarizer) summarizeChunks(ctx context.Context, projectPath string, chunks []messageChunk, first int) ([]db.ChunkSummary, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	summaries := make([]db.ChunkSummary, len(chunks))
+	errs := make([]error, len(chunks))
+	sem := make(chan struct{}, max(s.ChunkConcurrency, 1))
+	var wg sync.WaitGroup
+	for i, chunk := range chunks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			index := first + i
+			chunkText, tokens, err := s.summarizeChunk(ctx, projectPath, chunk.messages, index, first+len(chunks))
+			if err != nil {
+				errs[i] = fmt.Errorf("summarize chunk %d: %w", index, err)
+				cancel() // No use finishing the rest
+				return
+			}
+			summaries[i] = db.ChunkSummary{
+				ChunkIndex:   index,
+				MessageStart: chunk.startSeq,
+				MessageEnd:   chunk.endSeq,
+				Summary:      chunkText,
+				TokensApprox: tokens,
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Report the first chunk that failed, not one cancelled because of it
+	for _, err := range errs {
+		if err != nil && !errors.Is(err, context.Canceled) {
+			return nil, err
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return summaries, nil
+}
+
 type messageChunk struct {
 	messages []Message
 	startSeq int
@@ -111,7 +207,7 @@ func (s *HierarchicalSummarizer) chunkMessages(messages []Message) []messageChun
 }
 
 // summarizeDirect handles short sessions with a single LLM call
-func (s *HierarchicalSummarizer) summarizeDirect(ctx context.Context, projectPath string, messages []Message) (oneLine, full string, tokens int, err error) {
+func (s *HierarchicalSummarizer) summarizeDirect(ctx context.Context, projectPath string, messages []Message) (oneLine, full string, details *db.SummaryDetails, tokens int, err error) {
 	conversationText := formatMessages(messages)
 	projectName := filepath.Base(projectPath)
 
@@ -134,23 +230,20 @@ GOOD: "ENA-6962: accounts table unique constraint on (company_id, email) - dedup
 BAD: "The user investigated email notification issues"
 GOOD: "Unlinked email notifications: ProposalEmail association logic in email_processor.ex"
 
-Provide TWO summaries:
-1. ONE_LINE: 60-80 chars. Topic-focused, specific identifiers, no filler words.
-2. FULL: 2-3 paragraphs with technical details, file paths, specific changes.
-
-Format:
-ONE_LINE: <summary>
-FULL: <summary>`, projectName, conversationText)
+Respond with ONLY this JSON object, no other text:
+{
+  "one_line": "60-80 chars. Topic-focused, specific identifiers, no filler words.",
+  "full": "2-3 paragraphs with technical details, file paths, specific changes.",
+%s
+}`, projectName, conversationText, summaryDetailFields)
 
-	response, err := s.provider.GenerateText(ctx, prompt)
+	oneLine, full, details, responseTokens, err := s.generateStructuredSummary(ctx, prompt)
 	if err != nil {
-		return "", "", 0, err
+		return "", "", nil, 0, err
 	}
+	tokens = estimateTokens(conversationText) + responseTokens
 
-	oneLine, full = parseSummaryResponse(response)
-	tokens = estimateTokens(conversationText) + estimateTokens(response)
-
-	return oneLine, full, tokens, nil
+	return oneLine, full, details, tokens, nil
 }
 
 // summarizeChunk summarizes a single chunk of messages
@@ -171,7 +264,7 @@ RULES:
 - Be technical and specific
 - NO meta-language like "the user" or "the assistant"
 
-Provide 1-2 paragraphs covering: specific files changed, functions modified, bugs fixed (with specifics), schema/data changes.`, chunkIndex+1, totalChunks, projectName, conversationText)
+Provide 1-2 paragraphs covering: specific files changed, functions modified, bugs fixed (with specifics), schema/data changes, notable commands run, decisions made, and anything left unfinished.`, chunkIndex+1, totalChunks, projectName, conversationText)
 
 	response, err := s.provider.GenerateText(ctx, prompt)
 	if err != nil {
@@ -183,7 +276,7 @@ Provide 1-2 paragraphs covering: specific files changed, functions modified, bug
 }
 
 // combineChunks combines chunk summaries into a final summary
-func (s *HierarchicalSummarizer) combineChunks(ctx context.Context, projectPath string, chunks []db.ChunkSummary) (oneLine, full string, tokens int, err error) {
+func (s *HierarchicalSummarizer) combineChunks(ctx context.Context, projectPath string, chunks []db.ChunkSummary) (oneLine, full string, details *db.SummaryDetails, tokens int, err error) {
 	var chunkTexts []string
 	for _, c := range chunks {
 		chunkTexts = append(chunkTexts, fmt.Sprintf("Part %d (messages %d-%d):\n%s", c.ChunkIndex+1, c.MessageStart, c.MessageEnd, c.Summary))
@@ -207,23 +300,56 @@ RULES:
 BAD: "Fixed migration issues and investigated email problems"
 GOOD: "ENA-6962: dedupe migration for accounts(company_id,email); email association in ProposalEmail"
 
-Provide TWO summaries:
-1. ONE_LINE: 60-80 chars. Primary topic with key identifiers. No filler.
-2. FULL: 2-3 paragraphs synthesizing all technical details, files, changes.
+Respond with ONLY this JSON object, no other text:
+{
+  "one_line": "60-80 chars. Primary topic with key identifiers. No filler.",
+  "full": "2-3 paragraphs synthesizing all technical details, files, changes.",
+%s
+}`, projectName, combinedChunks, summaryDetailFields)
+
+	oneLine, full, details, responseTokens, err := s.generateStructuredSummary(ctx, prompt)
+	if err != nil {
+		return "", "", nil, 0, err
+	}
+	tokens = estimateTokens(combinedChunks) + responseTokens
 
-Format:
-ONE_LINE: <summary>
-FULL: <summary>`, projectName, combinedChunks)
+	return oneLine, full, details, tokens, nil
+}
 
+// generateStructuredSummary runs a session summary prompt and parses the
+// reply. A reply that is broken JSON (cut off, or a syntax error such as a
+// trailing comma) is sent back once to be repaired; if that fails too, the
+// error is returned rather than storing a summary made of the raw reply.
+func (s *HierarchicalSummarizer) generateStructuredSummary(ctx context.Context, prompt string) (oneLine, full string, details *db.SummaryDetails, tokens int, err error) {
 	response, err := s.provider.GenerateText(ctx, prompt)
 	if err != nil {
-		return "", "", 0, err
+		return "", "", nil, 0, err
+	}
+	tokens = estimateTokens(response)
+
+	oneLine, full, details, parseErr := parseStructuredSummary(response)
+	if parseErr == nil {
+		return oneLine, full, details, tokens, nil
 	}
 
-	oneLine, full = parseSummaryResponse(response)
-	tokens = estimateTokens(combinedChunks) + estimateTokens(response)
+	repairPrompt := fmt.Sprintf(`This reply was meant to be a single JSON object with the fields one_line, full, goal, outcome, decisions, todos, commands and files_changed, but it isn't usable (%v). It may be cut off, be plain text, or have a syntax error such as a trailing comma.
+
+Reply:
+%s
+
+Respond with ONLY the corrected JSON object, no other text. If the reply was cut off, shorten "full" so the whole object fits.`, parseErr, response)
 
-	return oneLine, full, tokens, nil
+	repaired, err := s.provider.GenerateText(ctx, repairPrompt)
+	if err != nil {
+		return "", "", nil, 0, err
+	}
+	tokens += estimateTokens(repaired)
+
+	oneLine, full, details, err = parseStructuredSummary(repaired)
+	if err != nil {
+		return "", "", nil, 0, err
+	}
+	return oneLine, full, details, tokens, nil
 }
 
 func formatMessages(messages []Message) string {
@@ -245,36 +371,101 @@ func formatMessages(messages []Message) string {
 	return sb.String()
 }
 
-func parseSummaryResponse(response string) (oneLine, full string) {
-	response = strings.TrimSpace(response)
+// summaryDetailFields describes the structured fields of the JSON object the
+// session summary prompts ask for
+const summaryDetailFields = `  "goal": "What the session set out to do, in one sentence.",
+  "outcome": "done (goal reached), blocked (stopped on a problem) or abandoned (dropped or moved on); empty if unclear.",
+  "decisions": ["Key technical decisions, with the reason for each."],
+  "todos": ["Work left unfinished, deferred or promised for later. Empty if none."],
+  "commands": ["Notable commands run: builds, tests, migrations, deploys."],
+  "files_changed": ["Paths of files created or modified."]`
+
+// structuredSummary is the JSON object the session summary prompts ask for
+type structuredSummary struct {
+	OneLine      string     `json:"one_line"`
+	Full         string     `json:"full"`
+	Goal         string     `json:"goal"`
+	Outcome      string     `json:"outcome"`
+	Decisions    stringList `json:"decisions"`
+	TODOs        stringList `json:"todos"`
+	Commands     stringList `json:"commands"`
+	FilesChanged stringList `json:"files_changed"`
+}
 
-	// Try to parse ONE_LINE: and FULL: format
-	oneLineRe := regexp.MustCompile(`(?i)ONE_LINE:\s*(.+?)(?:\n|FULL:|$)`)
-	fullRe := regexp.MustCompile(`(?i)FULL:\s*([\s\S]+)$`)
+// stringList is a JSON list of strings that also accepts a single string,
+// which smaller models sometimes give for a one-item list
+type stringList []string
 
-	if matches := oneLineRe.FindStringSubmatch(response); len(matches) > 1 {
-		oneLine = strings.TrimSpace(matches[1])
+func (l *stringList) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*l = stringList{s}
+		return nil
 	}
-	if matches := fullRe.FindStringSubmatch(response); len(matches) > 1 {
-		full = strings.TrimSpace(matches[1])
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
 	}
+	*l = list
+	return nil
+}
 
-	// Fallback: if parsing failed, use whole response for both
-	if oneLine == "" {
-		lines := strings.SplitN(response, "\n", 2)
-		oneLine = strings.TrimSpace(lines[0])
-		if len(oneLine) > 100 {
-			oneLine = oneLine[:97] + "..."
-		}
+// parseStructuredSummary parses a response to the session summary prompts.
+// A response that isn't a JSON object (plain text, or the ONE_LINE:/FULL:
+// format summaries used before), doesn't parse (cut off at the token limit, a
+// trailing comma) or has no one_line is an error, so the session is repaired
+// or retried rather than summarized with the raw reply.
+func parseStructuredSummary(response string) (oneLine, full string, details *db.SummaryDetails, err error) {
+	// Models like to wrap JSON in a code fence or a sentence of preamble
+	start := strings.Index(response, "{")
+	end := strings.LastIndex(response, "}")
+	if start < 0 || end < start {
+		return "", "", nil, errors.New("summary response has no JSON object")
+	}
+	var parsed structuredSummary
+	if err := json.Unmarshal([]byte(response[start:end+1]), &parsed); err != nil {
+		return "", "", nil, fmt.Errorf("summary response is not valid JSON: %w", err)
+	}
+	if strings.TrimSpace(parsed.OneLine) == "" {
+		return "", "", nil, errors.New("summary response has no one_line")
 	}
 
-	// Clean up bad patterns from one-line summary
-	oneLine = cleanSummary(oneLine)
+	oneLine = cleanSummary(strings.TrimSpace(parsed.OneLine))
+	full = strings.TrimSpace(parsed.Full)
 	if full == "" {
-		full = response
+		full = oneLine
+	}
+
+	outcome := strings.ToLower(strings.TrimSpace(parsed.Outcome))
+	switch outcome {
+	case db.OutcomeDone, db.OutcomeAbandoned, db.OutcomeBlocked:
+	default:
+		outcome = "" // Unclear, or not one of ours
 	}
 
-	return oneLine, full
+	return oneLine, full, &db.SummaryDetails{
+		Goal:         strings.TrimSpace(parsed.Goal),
+		Outcome:      outcome,
+		Decisions:    cleanList(parsed.Decisions),
+		TODOs:        cleanList(parsed.TODOs),
+		Commands:     cleanList(parsed.Commands),
+		FilesChanged: cleanList(parsed.FilesChanged),
+	}, nil
+}
+
+// cleanList trims a list's items, dropping blank and repeated ones
+func cleanList(items []string) []string {
+	var cleaned []string
+	seen := make(map[string]bool)
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" || seen[item] {
+			continue
+		}
+		seen[item] = true
+		cleaned = append(cleaned, item)
+	}
+	return cleaned
 }
 
 // cleanSummary removes common bad patterns from summaries
@@ -0,0 +1,282 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/neilberkman/ccrider/internal/core/db"
+)
+
+// promptRecorder answers every prompt with a fixed summary, keeping the
+// prompts it was sent
+type promptRecorder struct {
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (p *promptRecorder) GenerateText(ctx context.Context, prompt string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prompts = append(p.prompts, prompt)
+	return fmt.Sprintf(`{"one_line": "summary %d", "full": "Full summary %d."}`, len(p.prompts), len(p.prompts)), nil
+}
+
+func (p *promptRecorder) Name() string { return "recorder" }
+
+func testMessages(n int) []Message {
+	messages := make([]Message, n)
+	for i := range messages {
+		messages[i] = Message{Type: "user", Content: fmt.Sprintf("message-%03d", i)}
+	}
+	return messages
+}
+
+func chunkRanges(chunks []db.ChunkSummary) string {
+	var ranges []string
+	for _, c := range chunks {
+		ranges = append(ranges, fmt.Sprintf("%d:%d-%d", c.ChunkIndex, c.MessageStart, c.MessageEnd))
+	}
+	return strings.Join(ranges, " ")
+}
+
+func TestSummarizeSession_Extend(t *testing.T) {
+	ctx := context.Background()
+	provider := &promptRecorder{}
+	s := NewHierarchicalSummarizer(provider)
+
+	// 150 messages: two chunks and a combine
+	first, err := s.SummarizeSession(ctx, SummaryRequest{ProjectPath: "/p", Messages: testMessages(150)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := chunkRanges(first.ChunkSummaries); got != "0:0-99 1:90-149" {
+		t.Fatalf("First chunks = %s", got)
+	}
+
+	// Grown to 260: the complete first chunk is kept, the partial second
+	// one is redone along with the new messages
+	provider.prompts = nil
+	grown, err := s.SummarizeSession(ctx, SummaryRequest{ProjectPath: "/p", Messages: testMessages(260), Previous: first})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := chunkRanges(grown.ChunkSummaries); got != "0:0-99 1:90-189 2:180-259" {
+		t.Errorf("Extended chunks = %s", got)
+	}
+	if grown.ChunkSummaries[0].Summary != first.ChunkSummaries[0].Summary {
+		t.Errorf("First chunk was re-summarized: %q", grown.ChunkSummaries[0].Summary)
+	}
+	if len(provider.prompts) != 3 {
+		t.Errorf("Made %d LLM calls, want 2 chunks and a combine", len(provider.prompts))
+	}
+	for _, prompt := range provider.prompts {
+		if strings.Contains(prompt, "message-000") {
+			t.Errorf("Prompt includes already summarized messages:\n%s", prompt)
+		}
+	}
+	if grown.MessageCount != 260 {
+		t.Errorf("MessageCount = %d, want 260", grown.MessageCount)
+	}
+}
+
+func TestSummarizeSession_ExtendShortSession(t *testing.T) {
+	ctx := context.Background()
+	provider := &promptRecorder{}
+	s := NewHierarchicalSummarizer(provider)
+
+	first, err := s.SummarizeSession(ctx, SummaryRequest{ProjectPath: "/p", Messages: testMessages(60)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The one-pass summary stands in for the first chunk
+	provider.prompts = nil
+	grown, err := s.SummarizeSession(ctx, SummaryRequest{ProjectPath: "/p", Messages: testMessages(200), Previous: first})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := chunkRanges(grown.ChunkSummaries); got != "0:0-59 1:50-149 2:140-199" {
+		t.Errorf("Extended chunks = %s", got)
+	}
+	if grown.ChunkSummaries[0].Summary != first.Full {
+		t.Errorf("First chunk = %q, want the previous full summary", grown.ChunkSummaries[0].Summary)
+	}
+	if strings.Contains(provider.prompts[0], "message-000") {
+		t.Errorf("Prompt includes already summarized messages:\n%s", provider.prompts[0])
+	}
+}
+
+func TestReusableChunks(t *testing.T) {
+	chunked := &db.SessionSummary{
+		MessageCount: 150,
+		ChunkSummaries: []db.ChunkSummary{
+			{ChunkIndex: 0, MessageStart: 0, MessageEnd: 99, Summary: "a"},
+			{ChunkIndex: 1, MessageStart: 90, MessageEnd: 149, Summary: "b"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		prev     *db.SessionSummary
+		msgCount int
+		want     int
+	}{
+		{"no previous summary", nil, 200, 0},
+		{"complete chunks kept", chunked, 200, 1},
+		{"session did not grow", chunked, 150, 0},
+		{"session shrank", chunked, 120, 0},
+		{"short summary", &db.SessionSummary{MessageCount: 40, Full: "f"}, 200, 1},
+		{"too short to overlap", &db.SessionSummary{MessageCount: 5, Full: "f"}, 200, 0},
+		{"chunks past the message count", &db.SessionSummary{
+			MessageCount:   50,
+			ChunkSummaries: []db.ChunkSummary{{ChunkIndex: 0, MessageStart: 0, MessageEnd: 99}},
+		}, 200, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reusableChunks(tt.prev, tt.msgCount); len(got) != tt.want {
+				t.Errorf("reusableChunks() = %d chunks, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStructuredSummary(t *testing.T) {
+	tests := []struct {
+		name        string
+		response    string
+		wantOneLine string
+		wantFull    string
+		want        *db.SummaryDetails
+		wantErr     bool
+	}{
+		{
+			name: "json",
+			response: `{"one_line": "ENA-42: OAuth login with PKCE", "full": "Added OAuth.",
+				"goal": "Add OAuth login", "outcome": "Blocked",
+				"decisions": ["Use PKCE", " ", "Use PKCE"], "todos": ["Handle token refresh"],
+				"commands": ["go test ./auth/..."], "files_changed": ["auth/oauth.go"]}`,
+			wantOneLine: "ENA-42: OAuth login with PKCE",
+			wantFull:    "Added OAuth.",
+			want: &db.SummaryDetails{
+				Goal:         "Add OAuth login",
+				Outcome:      db.OutcomeBlocked,
+				Decisions:    []string{"Use PKCE"},
+				TODOs:        []string{"Handle token refresh"},
+				Commands:     []string{"go test ./auth/..."},
+				FilesChanged: []string{"auth/oauth.go"},
+			},
+		},
+		{
+			name:        "fenced, with a string for a list and an unknown outcome",
+			response:    "Here is the summary:\n```json\n{\"one_line\": \"Flaky test fix\", \"outcome\": \"partially done\", \"todos\": \"Re-enable CI job\"}\n```",
+			wantOneLine: "Flaky test fix",
+			wantFull:    "Flaky test fix",
+			want:        &db.SummaryDetails{TODOs: []string{"Re-enable CI job"}},
+		},
+		{
+			name:        "meta prefix",
+			response:    `{"one_line": "Based on the conversation, flaky auth tests", "full": "Fixed flaky auth tests."}`,
+			wantOneLine: "Flaky auth tests",
+			wantFull:    "Fixed flaky auth tests.",
+			want:        &db.SummaryDetails{},
+		},
+		{
+			name:     "old format",
+			response: "ONE_LINE: Flaky test fix\nFULL: Fixed the flaky test.",
+			wantErr:  true,
+		},
+		{
+			name:     "plain text",
+			response: "Flaky test fix\nRetried the job.",
+			wantErr:  true,
+		},
+		{
+			name:     "json without a summary",
+			response: `{"goal": "Fix the test"}`,
+			wantErr:  true,
+		},
+		{
+			name:     "trailing comma",
+			response: "```json\n{\"one_line\": \"Flaky test fix\", \"todos\": [\"Re-enable CI job\",],}\n```",
+			wantErr:  true,
+		},
+		{
+			name:     "cut off",
+			response: "{\"one_line\": \"Flaky test fix\", \"full\": \"Retried the job and",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oneLine, full, details, err := parseStructuredSummary(tt.response)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseStructuredSummary() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if oneLine != tt.wantOneLine || full != tt.wantFull {
+				t.Errorf("parseStructuredSummary() = %q, %q, want %q, %q", oneLine, full, tt.wantOneLine, tt.wantFull)
+			}
+			if !reflect.DeepEqual(details, tt.want) {
+				t.Errorf("parseStructuredSummary() details = %+v, want %+v", details, tt.want)
+			}
+		})
+	}
+}
+
+// sequenceProvider answers prompts with its responses in turn
+type sequenceProvider struct {
+	responses []string
+	prompts   []string
+}
+
+func (p *sequenceProvider) GenerateText(ctx context.Context, prompt string) (string, error) {
+	p.prompts = append(p.prompts, prompt)
+	if len(p.prompts) > len(p.responses) {
+		return "", fmt.Errorf("unexpected prompt %d", len(p.prompts))
+	}
+	return p.responses[len(p.prompts)-1], nil
+}
+
+func (p *sequenceProvider) Name() string { return "sequence" }
+
+func TestSummarizeSession_BrokenJSON(t *testing.T) {
+	const (
+		trailingComma = `{"one_line": "Flaky test fix", "todos": ["Re-enable CI job",],}`
+		cutOff        = `{"one_line": "Flaky test fix", "full": "Retried the job and`
+		valid         = `{"one_line": "Flaky test fix", "todos": ["Re-enable CI job"]}`
+		plainText     = "Flaky test fix\nRetried the job."
+	)
+
+	// Repaired on the second try
+	provider := &sequenceProvider{responses: []string{trailingComma, valid}}
+	summary, err := NewHierarchicalSummarizer(provider).SummarizeSession(context.Background(), SummaryRequest{Messages: testMessages(4)})
+	if err != nil {
+		t.Fatalf("SummarizeSession() error = %v", err)
+	}
+	if summary.OneLine != "Flaky test fix" || summary.Details == nil || len(summary.Details.TODOs) != 1 {
+		t.Errorf("SummarizeSession() = %+v", summary)
+	}
+	if len(provider.prompts) != 2 || !strings.Contains(provider.prompts[1], trailingComma) {
+		t.Errorf("Expected a repair prompt with the broken reply, got %d prompts", len(provider.prompts))
+	}
+
+	// Still broken: an error, not a summary made of the raw reply
+	provider = &sequenceProvider{responses: []string{cutOff, cutOff}}
+	summary, err = NewHierarchicalSummarizer(provider).SummarizeSession(context.Background(), SummaryRequest{Messages: testMessages(4)})
+	if err == nil {
+		t.Errorf("SummarizeSession() = %+v, want an error", summary)
+	}
+
+	// Plain text is repaired, or fails, the same way
+	provider = &sequenceProvider{responses: []string{plainText, plainText}}
+	summary, err = NewHierarchicalSummarizer(provider).SummarizeSession(context.Background(), SummaryRequest{Messages: testMessages(4)})
+	if err == nil || len(provider.prompts) != 2 {
+		t.Errorf("SummarizeSession() = %+v after %d prompts, want an error after a repair prompt", summary, len(provider.prompts))
+	}
+}
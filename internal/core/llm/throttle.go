@@ -0,0 +1,169 @@
+package llm
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ThrottleConfig limits how fast a provider is called and how throttling
+// errors are retried
+type ThrottleConfig struct {
+	RequestsPerMinute int           // 0 for no limit
+	MaxConcurrent     int           // Requests in flight at once, 0 for no limit
+	MaxRetries        int           // Retries of a throttled request, defaults to 5
+	InitialBackoff    time.Duration // First retry delay, doubled each retry; defaults to 2s
+}
+
+// ThrottledProvider wraps a Provider with a token-bucket rate limit and a
+// cap on requests in flight, and retries requests the provider throttled
+// with exponential backoff. It is safe for concurrent use; share one across
+// workers so the limits apply to all of them together.
+type ThrottledProvider struct {
+	provider Provider
+	limiter  *tokenBucket  // nil without a rate limit
+	slots    chan struct{} // nil without a concurrency limit
+	cfg      ThrottleConfig
+}
+
+// NewThrottledProvider wraps provider with cfg's rate limit and retries
+func NewThrottledProvider(provider Provider, cfg ThrottleConfig) *ThrottledProvider {
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 5
+	}
+	if cfg.InitialBackoff == 0 {
+		cfg.InitialBackoff = 2 * time.Second
+	}
+
+	p := &ThrottledProvider{provider: provider, cfg: cfg}
+	if cfg.RequestsPerMinute > 0 {
+		// A burst of up to a tenth of the per-minute rate, so a fresh run
+		// doesn't trip the provider's own limit all at once
+		burst := max(cfg.RequestsPerMinute/10, 1)
+		p.limiter = newTokenBucket(float64(cfg.RequestsPerMinute)/60, burst)
+	}
+	if cfg.MaxConcurrent > 0 {
+		p.slots = make(chan struct{}, cfg.MaxConcurrent)
+	}
+	return p
+}
+
+// GenerateText implements Provider
+func (p *ThrottledProvider) GenerateText(ctx context.Context, prompt string) (string, error) {
+	backoff := p.cfg.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		response, err := p.attempt(ctx, prompt)
+		if err == nil || !IsThrottled(err) || attempt >= p.cfg.MaxRetries {
+			return response, err
+		}
+
+		// Equal jitter (half the backoff, plus up to as much again at
+		// random), so concurrent workers don't retry in lockstep
+		delay := backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1))
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(delay):
+		}
+		backoff *= 2
+	}
+}
+
+// attempt makes one request once a slot is free and the rate limit allows.
+// The slot is released before any backoff, so waiting retries don't hold it.
+func (p *ThrottledProvider) attempt(ctx context.Context, prompt string) (string, error) {
+	if p.slots != nil {
+		select {
+		case p.slots <- struct{}{}:
+			defer func() { <-p.slots }()
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	if p.limiter != nil {
+		if err := p.limiter.wait(ctx); err != nil {
+			return "", err
+		}
+	}
+	return p.provider.GenerateText(ctx, prompt)
+}
+
+// Name implements Provider
+func (p *ThrottledProvider) Name() string {
+	return p.provider.Name()
+}
+
+// IsThrottled reports whether err is a provider's rate limit or overload
+// response, which is worth retrying after a pause. Providers wrap these
+// differently, so the error text is matched.
+func IsThrottled(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, marker := range []string{
+		"throttl",           // Bedrock ThrottlingException
+		"too many requests", // HTTP 429
+		"rate limit",        // OpenAI, Anthropic rate_limit_error
+		"rate_limit",
+		"overloaded", // Anthropic 529
+		"status code: 429",
+		"status code: 529",
+		"status code: 503",
+	} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// tokenBucket allows rate requests per second on average, with bursts of
+// up to burst requests
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), now: time.Now}
+}
+
+// reserve takes a token, returning how long to wait before using it
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	if !b.last.IsZero() {
+		b.tokens = min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	}
+	b.last = now
+
+	// Tokens may go negative: later callers queue behind earlier ones
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// wait blocks until a request may be made
+func (b *tokenBucket) wait(ctx context.Context) error {
+	delay := b.reserve()
+	if delay == 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(delay):
+		return nil
+	}
+}
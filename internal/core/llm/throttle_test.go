@@ -0,0 +1,146 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyProvider fails its first failures calls with err
+type flakyProvider struct {
+	failures int
+	err      error
+	calls    int
+}
+
+func (p *flakyProvider) GenerateText(ctx context.Context, prompt string) (string, error) {
+	p.calls++
+	if p.calls <= p.failures {
+		return "", p.err
+	}
+	return "summary", nil
+}
+
+func (p *flakyProvider) Name() string { return "flaky" }
+
+func TestThrottledProvider_Retries(t *testing.T) {
+	throttled := fmt.Errorf("bedrock generation failed: %w", errors.New("ThrottlingException: Too many tokens"))
+
+	tests := []struct {
+		name      string
+		failures  int
+		err       error
+		wantErr   bool
+		wantCalls int
+	}{
+		{"succeeds after throttling", 2, throttled, false, 3},
+		{"gives up after max retries", 10, throttled, true, 4},
+		{"does not retry other errors", 1, errors.New("invalid model"), true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := &flakyProvider{failures: tt.failures, err: tt.err}
+			p := NewThrottledProvider(inner, ThrottleConfig{MaxRetries: 3, InitialBackoff: time.Millisecond})
+
+			text, err := p.GenerateText(context.Background(), "prompt")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GenerateText() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && text != "summary" {
+				t.Errorf("GenerateText() = %q", text)
+			}
+			if inner.calls != tt.wantCalls {
+				t.Errorf("Provider called %d times, want %d", inner.calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Unix(0, 0)
+	b := newTokenBucket(2, 2) // 2 per second, bursts of 2
+	b.now = func() time.Time { return now }
+
+	// The burst is free, then callers queue half a second apart
+	for i, want := range []time.Duration{0, 0, 500 * time.Millisecond, time.Second} {
+		if got := b.reserve(); got != want {
+			t.Errorf("reserve() #%d = %v, want %v", i, got, want)
+		}
+	}
+
+	// Waiting long enough refills the bucket, up to the burst
+	now = now.Add(10 * time.Second)
+	for i, want := range []time.Duration{0, 0, 500 * time.Millisecond} {
+		if got := b.reserve(); got != want {
+			t.Errorf("reserve() after refill #%d = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestIsThrottled(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{errors.New("operation error Bedrock Runtime: InvokeModel, ThrottlingException"), true},
+		{errors.New("API returned unexpected status code: 429: rate_limit_error"), true},
+		{errors.New("anthropic: overloaded_error"), true},
+		{errors.New("context deadline exceeded"), false},
+		{errors.New("invalid api key"), false},
+	}
+
+	for _, tt := range tests {
+		if got := IsThrottled(tt.err); got != tt.want {
+			t.Errorf("IsThrottled(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// slowProvider records the most calls it had in flight at once
+type slowProvider struct {
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (p *slowProvider) GenerateText(ctx context.Context, prompt string) (string, error) {
+	p.mu.Lock()
+	p.inFlight++
+	p.maxInFlight = max(p.maxInFlight, p.inFlight)
+	p.mu.Unlock()
+
+	time.Sleep(5 * time.Millisecond)
+
+	p.mu.Lock()
+	p.inFlight--
+	p.mu.Unlock()
+	return "summary", nil
+}
+
+func (p *slowProvider) Name() string { return "slow" }
+
+func TestThrottledProvider_MaxConcurrent(t *testing.T) {
+	inner := &slowProvider{}
+	p := NewThrottledProvider(inner, ThrottleConfig{MaxConcurrent: 2})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := p.GenerateText(context.Background(), "prompt"); err != nil {
+				t.Errorf("GenerateText() error = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if inner.maxInFlight != 2 {
+		t.Errorf("%d requests in flight at once, want 2", inner.maxInFlight)
+	}
+}
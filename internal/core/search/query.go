@@ -0,0 +1,498 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/olebedev/when"
+	"github.com/olebedev/when/rules/common"
+	"github.com/olebedev/when/rules/en"
+)
+
+// Query is a parsed search query: full-text terms combined with boolean
+// operators, plus field filters that narrow which messages can match.
+//
+// Syntax:
+//   - auth token - both terms (AND is implied)
+//   - "token refresh" - exact phrase
+//   - auth OR oauth, (auth OR oauth) AND token - alternatives, grouping
+//   - auth NOT oauth, auth -oauth - exclusion
+//   - migrat* - prefix match
+//   - field:value - filter (see QueryFields); quote values with spaces
+//
+// Terms match within a single message, so an excluded term only rules out
+// the messages containing it. Filters apply to the whole query. Terms are
+// always escaped, so characters like - or : in them are matched as text,
+// never parsed as FTS5 syntax.
+type Query struct {
+	Text    queryNode     // Full-text part; nil for a query of only filters
+	Filters []FieldFilter // In the order they appeared
+	Code    bool          // code: was given - search in code mode
+}
+
+// FieldFilter is a field:value filter from a query
+type FieldFilter struct {
+	Field string
+	Value string
+}
+
+// QueryFields describes the field filters a query can use
+var QueryFields = map[string]string{
+	"project": "project path contains value",
+	"branch":  "git branch contains value",
+	"role":    "message is from user or assistant",
+	"tool":    "session used the named tool (e.g. Bash); tool matches only from its calls",
+	"file":    "session read or edited a file whose path contains value",
+	"issue":   "session mentions the issue ID (e.g. ENA-1234)",
+	"model":   "session used a model whose name contains value",
+	"after":   "on or after a date (2024-11-01, yesterday, 3-days-ago)",
+	"before":  "before a date",
+	"date":    "same as after",
+	"code":    "match the value (or the whole query) as code identifiers",
+}
+
+// QueryError reports a query that can't be searched, e.g. one that only
+// excludes terms
+type QueryError struct {
+	Msg string
+}
+
+func (e *QueryError) Error() string {
+	return "invalid query: " + e.Msg
+}
+
+// queryNode is a node of the full-text expression tree
+type queryNode interface {
+	// fts compiles the node into an FTS5 match expression
+	fts() (string, error)
+}
+
+// termNode is a single term, phrase or prefix
+type termNode struct {
+	text   string
+	phrase bool // Quoted in the query
+	prefix bool // Ended in *
+}
+
+// andNode matches all of its terms and none of its exclusions
+type andNode struct {
+	terms      []queryNode
+	exclusions []queryNode
+}
+
+// orNode matches any of its alternatives
+type orNode struct {
+	alternatives []queryNode
+}
+
+// notNode is an exclusion; it is only valid inside an andNode
+type notNode struct {
+	child queryNode
+}
+
+func (n *termNode) fts() (string, error) {
+	s := `"` + strings.ReplaceAll(n.text, `"`, `""`) + `"`
+	if n.prefix {
+		s += "*"
+	}
+	return s, nil
+}
+
+func (n *andNode) fts() (string, error) {
+	if len(n.terms) == 0 {
+		return "", &QueryError{Msg: "NOT needs a term to exclude from (e.g. auth NOT oauth)"}
+	}
+	terms, err := compileAll(n.terms)
+	if err != nil {
+		return "", err
+	}
+	s := strings.Join(terms, " AND ")
+	if len(n.exclusions) > 0 {
+		exclusions, err := compileAll(n.exclusions)
+		if err != nil {
+			return "", err
+		}
+		if len(terms) > 1 {
+			s = "(" + s + ")"
+		}
+		s += " NOT " + strings.Join(exclusions, " NOT ")
+	}
+	return s, nil
+}
+
+func (n *orNode) fts() (string, error) {
+	alternatives, err := compileAll(n.alternatives)
+	if err != nil {
+		return "", err
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", nil
+}
+
+func (n *notNode) fts() (string, error) {
+	return "", &QueryError{Msg: "NOT needs a term to exclude from (e.g. auth NOT oauth)"}
+}
+
+// compileAll compiles nodes, parenthesizing compound ones
+func compileAll(nodes []queryNode) ([]string, error) {
+	var out []string
+	for _, n := range nodes {
+		s, err := n.fts()
+		if err != nil {
+			return nil, err
+		}
+		if a, ok := n.(*andNode); ok && len(a.terms)+len(a.exclusions) > 1 {
+			s = "(" + s + ")"
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+// FTS compiles the full-text part of the query into an FTS5 match
+// expression, or "" if the query has no terms
+func (q *Query) FTS() (string, error) {
+	if q.Text == nil {
+		return "", nil
+	}
+	return q.Text.fts()
+}
+
+// plainText returns the query's terms and phrases as plain text, without
+// operators or excluded terms, for embedding
+func (q *Query) plainText() string {
+	var words []string
+	var walk func(n queryNode)
+	walk = func(n queryNode) {
+		switch n := n.(type) {
+		case *termNode:
+			words = append(words, n.text)
+		case *andNode:
+			for _, t := range n.terms {
+				walk(t)
+			}
+		case *orNode:
+			for _, a := range n.alternatives {
+				walk(a)
+			}
+		}
+	}
+	walk(q.Text)
+	return strings.Join(words, " ")
+}
+
+// singleTerm returns the query's only term when it is one unquoted word
+func (q *Query) singleTerm() (string, bool) {
+	t, ok := q.Text.(*termNode)
+	if !ok || t.phrase || t.prefix {
+		return "", false
+	}
+	return t.text, true
+}
+
+// Filter returns the values given for a field, in order
+func (q *Query) Filter(field string) []string {
+	var values []string
+	for _, f := range q.Filters {
+		if f.Field == field {
+			values = append(values, f.Value)
+		}
+	}
+	return values
+}
+
+// FilterTerm formats a field filter for a query, quoting a value with spaces
+func FilterTerm(field, value string) string {
+	if strings.ContainsAny(value, " \t\n") {
+		return field + `:"` + value + `"`
+	}
+	return field + ":" + value
+}
+
+// queryToken is a lexical token of a query
+type queryToken struct {
+	kind  tokenKind
+	text  string
+	field string // tokenFilter only
+}
+
+type tokenKind int
+
+const (
+	tokenTerm tokenKind = iota
+	tokenPhrase
+	tokenPrefix
+	tokenFilter
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+// ParseQuery parses a search query. It is lenient with incomplete input,
+// since queries are parsed as they are typed: unclosed quotes and parentheses
+// are closed at the end, and dangling operators are ignored.
+func ParseQuery(input string) (*Query, error) {
+	q := &Query{}
+	var tokens []queryToken
+	for _, tok := range tokenizeQuery(input) {
+		if tok.kind != tokenFilter {
+			tokens = append(tokens, tok)
+			continue
+		}
+		if tok.field == "code" {
+			// code:term searches the term in code mode
+			q.Code = true
+			if tok.text != "" {
+				tokens = append(tokens, queryToken{kind: tokenTerm, text: tok.text})
+			}
+			continue
+		}
+		if tok.text != "" {
+			q.Filters = append(q.Filters, FieldFilter{Field: tok.field, Value: tok.text})
+		}
+	}
+
+	p := &queryParser{tokens: tokens}
+	q.Text = p.parseOr()
+	for p.pos < len(p.tokens) {
+		// A stray ")" - skip it and keep going
+		p.pos++
+		if rest := p.parseOr(); rest != nil {
+			q.Text = joinAnd(q.Text, rest)
+		}
+	}
+	if q.Text != nil {
+		if _, err := q.Text.fts(); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, role := range q.Filter("role") {
+		if role := strings.ToLower(role); role != "user" && role != "assistant" {
+			return nil, &QueryError{Msg: fmt.Sprintf("role must be user or assistant, not %q", role)}
+		}
+	}
+	return q, nil
+}
+
+// tokenizeQuery splits a query into tokens
+func tokenizeQuery(input string) []queryToken {
+	var tokens []queryToken
+	runes := []rune(input)
+	i := 0
+
+	// readQuoted reads a quoted string starting at the opening quote
+	readQuoted := func() string {
+		i++ // Opening quote
+		start := i
+		for i < len(runes) && runes[i] != '"' {
+			i++
+		}
+		s := string(runes[start:i])
+		if i < len(runes) {
+			i++ // Closing quote
+		}
+		return s
+	}
+
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case r == ' ' || r == '\t' || r == '\n':
+			i++
+		case r == '(':
+			tokens = append(tokens, queryToken{kind: tokenOpen})
+			i++
+		case r == ')':
+			tokens = append(tokens, queryToken{kind: tokenClose})
+			i++
+		case r == '"':
+			tokens = append(tokens, queryToken{kind: tokenPhrase, text: readQuoted()})
+		case r == '-' && i+1 < len(runes) && !strings.ContainsRune(" \t\n-", runes[i+1]):
+			// Leading - excludes the next term (a - inside a word is text)
+			tokens = append(tokens, queryToken{kind: tokenNot})
+			i++
+		default:
+			start := i
+			for i < len(runes) && !strings.ContainsRune(" \t\n()\"", runes[i]) {
+				i++
+			}
+			word := string(runes[start:i])
+
+			if field, value, ok := strings.Cut(word, ":"); ok {
+				if _, known := QueryFields[strings.ToLower(field)]; known {
+					if value == "" && i < len(runes) && runes[i] == '"' {
+						value = readQuoted()
+					}
+					tokens = append(tokens, queryToken{kind: tokenFilter, field: strings.ToLower(field), text: value})
+					continue
+				}
+			}
+
+			switch {
+			case word == "AND":
+				tokens = append(tokens, queryToken{kind: tokenAnd})
+			case word == "OR":
+				tokens = append(tokens, queryToken{kind: tokenOr})
+			case word == "NOT":
+				tokens = append(tokens, queryToken{kind: tokenNot})
+			case strings.HasSuffix(word, "*") && len(strings.TrimRight(word, "*")) > 0:
+				tokens = append(tokens, queryToken{kind: tokenPrefix, text: strings.TrimRight(word, "*")})
+			case strings.Trim(word, "*") != "":
+				tokens = append(tokens, queryToken{kind: tokenTerm, text: word})
+			}
+		}
+	}
+	return tokens
+}
+
+// queryParser builds the expression tree by recursive descent. OR binds
+// loosest, then AND (explicit or implied), then NOT.
+type queryParser struct {
+	tokens []queryToken
+	pos    int
+}
+
+func (p *queryParser) peek() (queryToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return queryToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *queryParser) parseOr() queryNode {
+	var alternatives []queryNode
+	for {
+		if n := p.parseAnd(); n != nil {
+			alternatives = append(alternatives, n)
+		}
+		tok, ok := p.peek()
+		if !ok || tok.kind != tokenOr {
+			break
+		}
+		p.pos++
+	}
+	switch len(alternatives) {
+	case 0:
+		return nil
+	case 1:
+		return alternatives[0]
+	}
+	return &orNode{alternatives: alternatives}
+}
+
+func (p *queryParser) parseAnd() queryNode {
+	var node queryNode
+	for {
+		tok, ok := p.peek()
+		if !ok || tok.kind == tokenOr || tok.kind == tokenClose {
+			break
+		}
+		if tok.kind == tokenAnd {
+			p.pos++
+			continue
+		}
+		if n := p.parseUnary(); n != nil {
+			node = joinAnd(node, n)
+		}
+	}
+	return node
+}
+
+func (p *queryParser) parseUnary() queryNode {
+	tok, _ := p.peek()
+	if tok.kind == tokenNot {
+		p.pos++
+		if n := p.parseUnary(); n != nil {
+			if inner, ok := n.(*notNode); ok {
+				return inner.child // NOT NOT x
+			}
+			return &notNode{child: n}
+		}
+		return nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *queryParser) parsePrimary() queryNode {
+	tok, ok := p.peek()
+	if !ok {
+		return nil
+	}
+	p.pos++
+	switch tok.kind {
+	case tokenTerm:
+		return &termNode{text: tok.text}
+	case tokenPhrase:
+		if strings.TrimSpace(tok.text) == "" {
+			return nil
+		}
+		return &termNode{text: tok.text, phrase: true}
+	case tokenPrefix:
+		return &termNode{text: tok.text, prefix: true}
+	case tokenOpen:
+		n := p.parseOr()
+		if tok, ok := p.peek(); ok && tok.kind == tokenClose {
+			p.pos++
+		}
+		return n
+	}
+	return nil
+}
+
+// joinAnd combines two nodes with AND, flattening nested ANDs and keeping
+// exclusions separate from the terms they exclude from
+func joinAnd(a, b queryNode) queryNode {
+	if a == nil {
+		if not, ok := b.(*notNode); ok {
+			return &andNode{exclusions: []queryNode{not.child}}
+		}
+		return b
+	}
+	and, ok := a.(*andNode)
+	if !ok {
+		and = &andNode{terms: []queryNode{a}}
+	} else {
+		and = &andNode{terms: append([]queryNode{}, and.terms...), exclusions: append([]queryNode{}, and.exclusions...)}
+	}
+	switch b := b.(type) {
+	case *notNode:
+		and.exclusions = append(and.exclusions, b.child)
+	case *andNode:
+		and.terms = append(and.terms, b.terms...)
+		and.exclusions = append(and.exclusions, b.exclusions...)
+	default:
+		and.terms = append(and.terms, b)
+	}
+	return and
+}
+
+// parseQueryDate parses a date filter value: natural language (yesterday,
+// last week, 3-days-ago) or a date in a common format
+func parseQueryDate(s string) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, true
+	}
+	formats := []string{
+		"2006-01-02",
+		"2006-01-02T15:04:05",
+		"2006/01/02",
+		"01/02/2006",
+	}
+	for _, format := range formats {
+		if t, err := time.ParseInLocation(format, s, time.Local); err == nil {
+			return t, true
+		}
+	}
+
+	// Natural language, with dashes standing in for spaces in the query
+	w := when.New(nil)
+	w.Add(en.All...)
+	w.Add(common.All...)
+	if result, err := w.Parse(strings.ReplaceAll(s, "-", " "), time.Now()); err == nil && result != nil {
+		return result.Time, true
+	}
+	return time.Time{}, false
+}
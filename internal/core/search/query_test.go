@@ -0,0 +1,85 @@
+package search
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		input   string
+		fts     string
+		filters []FieldFilter
+	}{
+		{`auth token`, `"auth" AND "token"`, nil},
+		{`"token refresh" expiry`, `"token refresh" AND "expiry"`, nil},
+		{`auth OR oauth`, `("auth" OR "oauth")`, nil},
+		{`(auth OR oauth) AND token`, `("auth" OR "oauth") AND "token"`, nil},
+		{`auth NOT oauth`, `"auth" NOT "oauth"`, nil},
+		{`auth token -oauth -saml`, `("auth" AND "token") NOT "oauth" NOT "saml"`, nil},
+		{`a OR b c -d`, `("a" OR (("b" AND "c") NOT "d"))`, nil},
+		{`migrat*`, `"migrat"*`, nil},
+		{`ENA-7030 col:row`, `"ENA-7030" AND "col:row"`, nil},
+		{`say "hi`, `"say" AND "hi"`, nil},                   // Unclosed quote
+		{`(auth OR`, `"auth"`, nil},                          // Unclosed group, dangling OR
+		{`auth) token`, `"auth" AND "token"`, nil},           // Stray paren
+		{`a "b""c"`, `"a" AND "b" AND "c"`, nil},             // Adjacent phrases
+		{`"don't panic" "" x`, `"don't panic" AND "x"`, nil}, // Empty phrases are dropped
+		{`deploy role:user tool:Bash`, `"deploy"`, []FieldFilter{{"role", "user"}, {"tool", "Bash"}}},
+		{`project:"my app" fix`, `"fix"`, []FieldFilter{{"project", "my app"}}},
+		{`Branch:main`, ``, []FieldFilter{{"branch", "main"}}},
+	}
+	for _, tt := range tests {
+		q, err := ParseQuery(tt.input)
+		if err != nil {
+			t.Errorf("ParseQuery(%q) error = %v", tt.input, err)
+			continue
+		}
+		fts, err := q.FTS()
+		if err != nil || fts != tt.fts {
+			t.Errorf("ParseQuery(%q).FTS() = %q, %v, want %q", tt.input, fts, err, tt.fts)
+		}
+		if len(q.Filters) != len(tt.filters) {
+			t.Errorf("ParseQuery(%q).Filters = %v, want %v", tt.input, q.Filters, tt.filters)
+			continue
+		}
+		for i := range tt.filters {
+			if q.Filters[i] != tt.filters[i] {
+				t.Errorf("ParseQuery(%q).Filters = %v, want %v", tt.input, q.Filters, tt.filters)
+			}
+		}
+	}
+}
+
+func TestParseQuery_Code(t *testing.T) {
+	q, err := ParseQuery("code:user_id rename")
+	if err != nil {
+		t.Fatalf("ParseQuery() error = %v", err)
+	}
+	fts, _ := q.FTS()
+	if !q.Code || fts != `"user_id" AND "rename"` {
+		t.Errorf("ParseQuery() = %q (code %v), want code search for user_id and rename", fts, q.Code)
+	}
+}
+
+func TestParseQuery_Errors(t *testing.T) {
+	for _, input := range []string{"-oauth", "NOT oauth", "auth OR -oauth", "fix role:robot"} {
+		_, err := ParseQuery(input)
+		var queryErr *QueryError
+		if !errors.As(err, &queryErr) {
+			t.Errorf("ParseQuery(%q) error = %v, want a QueryError", input, err)
+		}
+	}
+}
+
+func TestFilterTerm(t *testing.T) {
+	for _, value := range []string{"billing", "my app", "2025-01-01"} {
+		q, err := ParseQuery("rollback " + FilterTerm("project", value))
+		if err != nil {
+			t.Fatalf("ParseQuery() error = %v", err)
+		}
+		if got := q.Filter("project"); len(got) != 1 || got[0] != value {
+			t.Errorf("FilterTerm(project, %q) parsed as %v", value, got)
+		}
+	}
+}
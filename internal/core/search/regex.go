@@ -0,0 +1,299 @@
+package search
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"time"
+
+	"github.com/neilberkman/ccrider/internal/core/db"
+)
+
+// SearchRegex scans message text for a Go regular expression (filters.Query)
+// and calls fn with each matching session's results as soon as that session
+// has been scanned, most recently updated session first. Matching is
+// case-sensitive unless the pattern starts with (?i).
+//
+// Literal text the pattern requires is used to pre-filter messages in SQL
+// (through FTS where a literal starts a word), so only candidate messages
+// are run through the regexp. Project, session and date filters apply;
+// Limit and Offset count sessions. fn must not use the database, which is
+// still reading the results.
+func SearchRegex(database *db.DB, filters SearchFilters, fn func(SessionSearchResult) error) error {
+	re, err := regexp.Compile(filters.Query)
+	if err != nil {
+		return &QueryError{Msg: strings.TrimPrefix(err.Error(), "error parsing regexp: ")}
+	}
+
+	conditions := []string{"m.text_content IS NOT NULL", "m.text_content != ''"}
+	var args []interface{}
+	from := `
+		FROM messages m
+		JOIN sessions s ON s.id = m.session_id
+		LEFT JOIN session_summaries ss ON s.id = ss.session_id`
+
+	literals, foldCase := requiredLiterals(filters.Query)
+	if match := literalFTSQuery(literals); match != "" {
+		from = `
+		FROM messages_fts_code
+		JOIN messages m ON messages_fts_code.rowid = m.id
+		JOIN sessions s ON s.id = m.session_id
+		LEFT JOIN session_summaries ss ON s.id = ss.session_id`
+		conditions = append(conditions, "messages_fts_code MATCH ?")
+		args = append(args, match)
+	}
+	for _, lit := range literals {
+		// LIKE only folds ASCII case, so it can't stand in for (?i) otherwise
+		if foldCase && !isASCII(lit) {
+			continue
+		}
+		conditions = append(conditions, `m.text_content LIKE '%' || ? || '%' ESCAPE '\'`)
+		args = append(args, escapeLike(lit))
+	}
+
+	scope, scopeArgs, err := scopeConditions(filters)
+	if err != nil {
+		return err
+	}
+	conditions = append(conditions, scope...)
+	args = append(args, scopeArgs...)
+
+	rows, err := database.Query(`
+		SELECT
+			m.uuid,
+			s.session_id,
+			COALESCE(ss.one_line_summary, s.llm_summary, s.summary, ''),
+			m.text_content,
+			m.timestamp,
+			s.project_path
+		`+from+`
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY s.updated_at DESC, s.id, m.timestamp DESC, m.sequence DESC
+	`, args...)
+	if err != nil {
+		return fmt.Errorf("regex search query failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var current *SessionSearchResult
+	skipped, sent := 0, 0
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+		session := *current
+		current = nil
+		if skipped < filters.Offset {
+			skipped++
+			return nil
+		}
+		sent++
+		return fn(session)
+	}
+
+	for rows.Next() {
+		var r SearchResult
+		if err := rows.Scan(&r.MessageUUID, &r.SessionID, &r.SessionSummary, &r.MessageText, &r.Timestamp, &r.ProjectPath); err != nil {
+			return fmt.Errorf("failed to scan result: %w", err)
+		}
+		locs := re.FindAllStringIndex(r.MessageText, -1)
+		if len(locs) == 0 {
+			continue
+		}
+
+		if current != nil && current.SessionID != r.SessionID {
+			if err := flush(); err != nil {
+				return err
+			}
+			if filters.Limit > 0 && sent >= filters.Limit {
+				return nil
+			}
+		}
+		if current == nil {
+			// Matches are newest first, so the first is the latest
+			current = &SessionSearchResult{
+				SessionID:      r.SessionID,
+				SessionSummary: r.SessionSummary,
+				ProjectPath:    r.ProjectPath,
+				UpdatedAt:      r.Timestamp,
+				Matches:        []SearchResult{},
+			}
+			if t, ok := parseTimestamp(r.Timestamp); ok {
+				current.UpdatedAt = t.Format(time.RFC3339)
+			}
+		}
+
+		current.MatchCount++
+		if filters.MatchLimit == 0 || len(current.Matches) < filters.MatchLimit {
+			r.MessageText = regexSnippet(r.MessageText, locs, 100)
+			current.Matches = append(current.Matches, r)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating results: %w", err)
+	}
+	return flush()
+}
+
+// searchRegexPage runs SearchRegex and returns one page of its results
+func searchRegexPage(database *db.DB, filters SearchFilters) (*SessionSearchPage, error) {
+	var sessions []SessionSearchResult
+	all := filters
+	all.Limit, all.Offset = 0, 0
+	err := SearchRegex(database, all, func(session SessionSearchResult) error {
+		sessions = append(sessions, session)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return paginate(sessions, filters.Limit, filters.Offset), nil
+}
+
+// requiredLiterals returns literal strings every match of pattern contains,
+// and whether they should be matched ignoring case. It errs on the side of
+// returning fewer: alternations and optional parts contribute nothing.
+func requiredLiterals(pattern string) ([]string, bool) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, false
+	}
+	re = re.Simplify()
+
+	var literals []string
+	foldCase := false
+	var walk func(re *syntax.Regexp)
+	walk = func(re *syntax.Regexp) {
+		switch re.Op {
+		case syntax.OpLiteral:
+			literals = append(literals, string(re.Rune))
+			foldCase = foldCase || re.Flags&syntax.FoldCase != 0
+		case syntax.OpConcat:
+			// Adjacent literals form one longer literal
+			var run []rune
+			for _, sub := range re.Sub {
+				if sub.Op == syntax.OpLiteral {
+					run = append(run, sub.Rune...)
+					foldCase = foldCase || sub.Flags&syntax.FoldCase != 0
+					continue
+				}
+				if len(run) > 0 {
+					literals = append(literals, string(run))
+					run = nil
+				}
+				walk(sub)
+			}
+			if len(run) > 0 {
+				literals = append(literals, string(run))
+			}
+		case syntax.OpCapture, syntax.OpPlus:
+			walk(re.Sub[0])
+		case syntax.OpRepeat:
+			if re.Min > 0 {
+				walk(re.Sub[0])
+			}
+		}
+	}
+	walk(re)
+	return literals, foldCase
+}
+
+// literalFTSQuery builds an FTS5 query over messages_fts_code from required
+// literals, or "" if none can be used. FTS5 matches tokens and token
+// prefixes, so only the words of a literal that start at a word break inside
+// it are usable: one that also ends at a break must be a whole token, one
+// that runs to the end of the literal is a prefix. Words are limited to
+// ASCII to stay within what the tokenizer is known to split on.
+func literalFTSQuery(literals []string) string {
+	var terms []string
+	for _, lit := range literals {
+		words := strings.FieldsFunc(lit, func(r rune) bool { return !isTokenChar(r) })
+		if len(words) == 0 {
+			continue
+		}
+		// Where each word falls in the literal
+		pos := 0
+		for _, word := range words {
+			start := strings.Index(lit[pos:], word) + pos
+			end := start + len(word)
+			pos = end
+			if start == 0 || !isASCII(word) {
+				continue // May be the end of a longer token
+			}
+			if end < len(lit) {
+				terms = append(terms, `"`+word+`"`)
+			} else {
+				terms = append(terms, `"`+word+`"*`)
+			}
+		}
+	}
+	return strings.Join(terms, " AND ")
+}
+
+// isTokenChar reports whether messages_fts_code treats r as part of a word
+func isTokenChar(r rune) bool {
+	return r == '_' || r >= 0x80 || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+// escapeLike escapes LIKE wildcards for use with ESCAPE '\'
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// regexSnippet cuts a snippet of about maxLen bytes around the first match,
+// marking every match in it with HighlightStart and HighlightEnd
+func regexSnippet(text string, locs [][]int, maxLen int) string {
+	first := locs[0]
+	start := first[0] - maxLen/2
+	if start < 0 {
+		start = 0
+	}
+	end := start + maxLen
+	if end < first[1] {
+		end = first[1] // Show the whole first match, however long
+	}
+	if end > len(text) {
+		end = len(text)
+	}
+	// Don't cut through a UTF-8 sequence
+	for start > 0 && start < len(text) && text[start]&0xC0 == 0x80 {
+		start--
+	}
+	for end < len(text) && text[end]&0xC0 == 0x80 {
+		end++
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("...")
+	}
+	pos := start
+	for _, loc := range locs {
+		if loc[1] <= start || loc[0] >= end || loc[0] == loc[1] {
+			continue
+		}
+		from, to := max(loc[0], pos), min(loc[1], end)
+		b.WriteString(text[pos:from])
+		b.WriteString(HighlightStart)
+		b.WriteString(text[from:to])
+		b.WriteString(HighlightEnd)
+		pos = to
+	}
+	b.WriteString(text[pos:end])
+	if end < len(text) {
+		b.WriteString("...")
+	}
+
+	// Newlines would break the one-line snippet layouts
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ").Replace(b.String())
+}
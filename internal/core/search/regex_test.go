@@ -0,0 +1,149 @@
+package search
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/neilberkman/ccrider/internal/core/db"
+)
+
+func TestRequiredLiterals(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		literals []string
+		foldCase bool
+	}{
+		{`ENA-\d{4}.*rollback`, []string{"ENA-", "rollback"}, false},
+		{`(?i)deploy(ed)? to prod`, []string{"DEPLOY", " TO PROD"}, true}, // Folded literals are upper case
+		{`(foo|bar)baz+`, []string{"ba", "z"}, false},
+		{`x*`, nil, false},
+		{`[`, nil, false}, // Invalid
+	}
+	for _, tt := range tests {
+		literals, foldCase := requiredLiterals(tt.pattern)
+		if strings.Join(literals, "|") != strings.Join(tt.literals, "|") || foldCase != tt.foldCase {
+			t.Errorf("requiredLiterals(%q) = %q, %v, want %q, %v", tt.pattern, literals, foldCase, tt.literals, tt.foldCase)
+		}
+	}
+}
+
+func TestLiteralFTSQuery(t *testing.T) {
+	tests := []struct {
+		literals []string
+		want     string
+	}{
+		{[]string{"ENA-", "rollback"}, ""},                        // Either could be the end of a longer word
+		{[]string{"run the migration"}, `"the" AND "migration"*`}, // "run" could end a word
+		{[]string{"user_id = 5"}, `"5"*`},                         // _ is part of a word
+		{[]string{"résumé. next"}, `"next"*`},                     // Non-ASCII words are skipped
+	}
+	for _, tt := range tests {
+		if got := literalFTSQuery(tt.literals); got != tt.want {
+			t.Errorf("literalFTSQuery(%q) = %q, want %q", tt.literals, got, tt.want)
+		}
+	}
+}
+
+func TestSearchRegex(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Remove(tmpfile.Name()) }()
+	_ = tmpfile.Close()
+
+	database, err := db.New(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	sessions := []struct {
+		id, updated string
+		messages    []string
+	}{
+		{"old", "2025-01-01 10:00:00", []string{"ENA-1234 needs a rollback", "unrelated"}},
+		{"new", "2025-02-01 10:00:00", []string{"ENA-5678: rollback plan\nthen ENA-9999 rollback too", "ENA-42 rollback (too short)", "ena-1111 ROLLBACK"}},
+		{"none", "2025-03-01 10:00:00", []string{"rollback ENA-1234 (wrong order)"}},
+	}
+	for _, s := range sessions {
+		result, err := database.Exec(`
+			INSERT INTO sessions (session_id, project_path, summary, created_at, updated_at)
+			VALUES (?, '/test', '', ?, ?)
+		`, s.id, s.updated, s.updated)
+		if err != nil {
+			t.Fatalf("Failed to insert session: %v", err)
+		}
+		id, _ := result.LastInsertId()
+		for i, text := range s.messages {
+			_, err := database.Exec(`
+				INSERT INTO messages (uuid, session_id, type, text_content, timestamp, sequence)
+				VALUES (?, ?, 'user', ?, ?, ?)
+			`, s.id+"-"+string(rune('a'+i)), id, text, s.updated, i+1)
+			if err != nil {
+				t.Fatalf("Failed to insert message: %v", err)
+			}
+		}
+	}
+
+	var got []SessionSearchResult
+	err = SearchRegex(database, SearchFilters{Query: `ENA-\d{4}.*rollback`}, func(s SessionSearchResult) error {
+		got = append(got, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("SearchRegex() error = %v", err)
+	}
+	if len(got) != 2 || got[0].SessionID != "new" || got[1].SessionID != "old" {
+		t.Fatalf("Expected new then old, got %+v", got)
+	}
+	if got[0].MatchCount != 1 || got[1].MatchCount != 1 {
+		t.Errorf("Expected one matching message per session, got %d and %d", got[0].MatchCount, got[1].MatchCount)
+	}
+	snippet := Highlight(got[0].Matches[0].MessageText, func(s string) string { return "[" + s + "]" })
+	if snippet != "[ENA-5678: rollback] plan then [ENA-9999 rollback] too" {
+		t.Errorf("Snippet = %q", snippet)
+	}
+
+	// (?i) matches despite the case-insensitive pre-filter being ASCII only
+	page, err := SearchWithFilters(database, SearchFilters{Query: `(?i)ena-\d{4}.*rollback`, Mode: ModeRegex, Limit: 1})
+	if err != nil {
+		t.Fatalf("SearchWithFilters() error = %v", err)
+	}
+	if page.Total != 2 || len(page.Sessions) != 1 || page.Sessions[0].SessionID != "new" || page.NextOffset != 1 {
+		t.Errorf("Expected first of 2 pages with new, got %+v", page)
+	}
+	if page.Sessions[0].MatchCount != 2 {
+		t.Errorf("Expected 2 case-insensitive matches in new, got %d", page.Sessions[0].MatchCount)
+	}
+
+	// Limit and Offset count sessions while streaming
+	got = nil
+	err = SearchRegex(database, SearchFilters{Query: `rollback`, Offset: 1, Limit: 1}, func(s SessionSearchResult) error {
+		got = append(got, s)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("SearchRegex() error = %v", err)
+	}
+	if len(got) != 1 || got[0].SessionID != "new" {
+		t.Errorf("Expected only the second session (new), got %+v", got)
+	}
+
+	// Pre-filtered through FTS ("a" AND "roll"*)
+	page, err = SearchWithFilters(database, SearchFilters{Query: `needs a roll`, Mode: ModeRegex})
+	if err != nil {
+		t.Fatalf("SearchWithFilters() error = %v", err)
+	}
+	if page.Total != 1 || page.Sessions[0].SessionID != "old" {
+		t.Errorf("Expected only old, got %+v", page)
+	}
+
+	err = SearchRegex(database, SearchFilters{Query: `ENA-(\d`}, func(SessionSearchResult) error { return nil })
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) {
+		t.Errorf("Expected a QueryError for an invalid pattern, got %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"fmt"
 	"math"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
@@ -19,16 +20,22 @@ type SearchResult struct {
 	MessageText    string
 	Timestamp      string
 	ProjectPath    string
+	ToolName       string // Tool whose input or output matched, or "" for message text
 }
 
 // SearchFilters defines filtering criteria for search
 type SearchFilters struct {
-	Query            string // The search query text
+	Query            string // The search query (see Query for the syntax)
+	Mode             Mode   // Which index to search (defaults to ModeAuto)
 	ProjectPath      string // Filter by project path (substring match)
 	CurrentSessionID string // If set, only search within this session
 	ExcludeCurrent   bool   // If true with CurrentSessionID set, exclude that session
-	AfterDate        string // Only results after this timestamp (ISO 8601)
-	BeforeDate       string // Only results before this timestamp (ISO 8601)
+	AfterDate        string // Only results at or after this time (ISO 8601 date or timestamp)
+	BeforeDate       string // Only results before this time (ISO 8601 date or timestamp)
+
+	Limit      int // Max sessions to return (0 for no limit)
+	Offset     int // Sessions to skip, for paging through results
+	MatchLimit int // Max matches returned per session (0 for all); MatchCount counts every match
 }
 
 // SessionSearchResult represents search results grouped by session
@@ -36,168 +43,534 @@ type SessionSearchResult struct {
 	SessionID      string
 	SessionSummary string
 	ProjectPath    string
-	UpdatedAt      string
+	UpdatedAt      string // Latest matching message (RFC 3339)
 	Matches        []SearchResult
+	MatchCount     int     // Total matching messages and tool calls in the session
 	Score          float64 // Relevance score for ranking
 }
 
-// Default sort order for search results (most recent first)
-const defaultOrderBy = "m.timestamp DESC"
+// SessionSearchPage is one page of session results
+type SessionSearchPage struct {
+	Sessions   []SessionSearchResult
+	Total      int // Sessions matching across all pages
+	NextOffset int // Offset of the next page, or 0 if this is the last
+}
+
+// Mode selects how a query is matched
+type Mode string
+
+const (
+	ModeAuto  Mode = ""      // Code for queries that look like an identifier, otherwise text
+	ModeText  Mode = "text"  // Natural language, with porter stemming
+	ModeCode  Mode = "code"  // Exact tokens without stemming, for identifiers like user_id
+	ModeRegex Mode = "regex" // Query is a Go regular expression (see SearchRegex)
+)
 
-// Search performs a full-text search using the natural language FTS table
-// Results are ordered by timestamp (most recent first)
-func Search(database *db.DB, query string) ([]SearchResult, error) {
-	return search(database, query, "messages_fts", 1000)
+// ParseMode parses a mode name: auto, text, code or regex
+func ParseMode(s string) (Mode, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "auto":
+		return ModeAuto, nil
+	case "text":
+		return ModeText, nil
+	case "code":
+		return ModeCode, nil
+	case "regex":
+		return ModeRegex, nil
+	}
+	return ModeAuto, fmt.Errorf("invalid search mode %q (want auto, text, code or regex)", s)
 }
 
-// SearchWithFilters performs filtered search and groups results by session
+// Snippets mark each matched token with HighlightStart and HighlightEnd;
+// render them with Highlight or remove them with StripHighlights
+const (
+	HighlightStart = "\x02"
+	HighlightEnd   = "\x03"
+)
+
+// Column weights for bm25() ranking. A user's prompt says what they were
+// working on, so it counts for more than the assistant's reply; a matching
+// session summary counts for most.
+const (
+	userTextWeight      = 2.0
+	assistantTextWeight = 1.0
+	summaryWeight       = 3.0
+	toolTextWeight      = 1.0 // Tool inputs and outputs
+)
+
+// SearchWithFilters performs filtered search and returns a page of results
+// grouped by session, most relevant first. Filters are applied in SQL so they
+// see every match, not just the most recent ones.
 // This consolidates business logic that was duplicated across TUI and MCP
-func SearchWithFilters(database *db.DB, filters SearchFilters) ([]SessionSearchResult, error) {
+func SearchWithFilters(database *db.DB, filters SearchFilters) (*SessionSearchPage, error) {
 	// Validate query (minimum 2 characters)
 	query := strings.TrimSpace(filters.Query)
 	if len(query) < 2 {
-		return nil, nil // Empty results for queries too short
+		return &SessionSearchPage{}, nil // Empty results for queries too short
+	}
+	if filters.Mode == ModeRegex {
+		return searchRegexPage(database, filters)
 	}
 
-	// Perform core search
-	results, err := Search(database, query)
+	sessions, q, err := rankSessions(database, filters)
 	if err != nil {
 		return nil, err
 	}
 
-	// Apply filters (business logic)
-	sessionMap := make(map[string]*SessionSearchResult)
-	var sessionOrder []string
+	page := paginate(sessions, filters.Limit, filters.Offset)
+	if len(page.Sessions) == 0 {
+		return page, nil
+	}
 
-	for _, result := range results {
-		// Filter by current session
-		if filters.CurrentSessionID != "" {
-			if filters.ExcludeCurrent && result.SessionID == filters.CurrentSessionID {
-				continue
-			}
-			if !filters.ExcludeCurrent && result.SessionID != filters.CurrentSessionID {
-				continue
-			}
-		}
+	// Fetch matching messages for this page's sessions only
+	if err := loadMatches(database, q, page.Sessions, filters.MatchLimit); err != nil {
+		return nil, err
+	}
 
-		// Filter by project path
-		if filters.ProjectPath != "" && !strings.Contains(result.ProjectPath, filters.ProjectPath) {
-			continue
-		}
+	return page, nil
+}
 
-		// Filter by date range
-		if filters.AfterDate != "" && result.Timestamp < filters.AfterDate {
-			continue
+// rankSessions finds every session matching filters and scores it, most
+// relevant first, without loading matches. It also returns the compiled query
+// for loadMatches.
+func rankSessions(database *db.DB, filters SearchFilters) ([]SessionSearchResult, *filteredQuery, error) {
+	parsed, err := ParseQuery(strings.TrimSpace(filters.Query))
+	if err != nil {
+		return nil, nil, err
+	}
+	if parsed.Code {
+		filters.Mode = ModeCode
+	}
+	if term, ok := parsed.singleTerm(); ok && filters.Mode == ModeAuto && looksLikeIdentifier(term) {
+		filters.Mode = ModeCode
+	}
+
+	q, err := buildFilteredQuery(parsed, filters)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Score every match, then roll matches up per session. bm25() can't be
+	// used in an aggregate directly, so the matches are materialized first.
+	hits, args := q.unionAll(func(src matchSource) string {
+		return "m.session_id AS session_id, m.timestamp AS timestamp, " + src.relevance + " AS relevance"
+	}, "", nil)
+	rows, err := database.Query(`
+		WITH hits AS MATERIALIZED (
+			`+hits+`
+		)
+		SELECT
+			s.session_id,
+			COALESCE(ss.one_line_summary, s.llm_summary, s.summary, ''),
+			s.project_path,
+			COUNT(*),
+			MAX(hits.timestamp),
+			MAX(hits.relevance)
+		FROM hits
+		JOIN sessions s ON s.id = hits.session_id
+		LEFT JOIN session_summaries ss ON s.id = ss.session_id
+		GROUP BY hits.session_id
+	`, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("search query failed: %w", err)
+	}
+
+	var sessions []SessionSearchResult
+	var bestMatches []float64
+	for rows.Next() {
+		var session SessionSearchResult
+		var updatedAt string
+		var best float64
+		if err := rows.Scan(&session.SessionID, &session.SessionSummary, &session.ProjectPath, &session.MatchCount, &updatedAt, &best); err != nil {
+			_ = rows.Close()
+			return nil, nil, fmt.Errorf("failed to scan result: %w", err)
 		}
-		if filters.BeforeDate != "" && result.Timestamp > filters.BeforeDate {
-			continue
+		session.UpdatedAt = updatedAt
+		if t, ok := parseTimestamp(updatedAt); ok {
+			session.UpdatedAt = t.Format(time.RFC3339)
 		}
+		sessions = append(sessions, session)
+		bestMatches = append(bestMatches, best)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, nil, fmt.Errorf("error iterating results: %w", err)
+	}
+	_ = rows.Close()
 
-		// Group by session
-		sessionID := result.SessionID
-		session, exists := sessionMap[sessionID]
-		if !exists {
-			session = &SessionSearchResult{
-				SessionID:      sessionID,
-				SessionSummary: result.SessionSummary,
-				ProjectPath:    result.ProjectPath,
-				UpdatedAt:      result.Timestamp,
-				Matches:        []SearchResult{},
-			}
-			sessionMap[sessionID] = session
-			sessionOrder = append(sessionOrder, sessionID)
+	summaries, err := summaryRelevance(database, q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Calculate relevance scores for each session and sort by them
+	// (descending), most recent first among equals so pages are stable
+	now := time.Now()
+	for i := range sessions {
+		sessions[i].Score = calculateRelevanceScore(&sessions[i], bestMatches[i], summaries[sessions[i].SessionID], now)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		a, b := sessions[i], sessions[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.UpdatedAt != b.UpdatedAt {
+			return a.UpdatedAt > b.UpdatedAt
 		}
+		return a.SessionID < b.SessionID
+	})
+
+	return sessions, q, nil
+}
 
-		// Add this match to the session
-		session.Matches = append(session.Matches, result)
+// paginate returns the page of sessions at offset
+func paginate(sessions []SessionSearchResult, limit, offset int) *SessionSearchPage {
+	page := &SessionSearchPage{Total: len(sessions)}
+	if offset < 0 {
+		offset = 0
 	}
+	if offset > len(sessions) {
+		offset = len(sessions)
+	}
+	end := len(sessions)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+		page.NextOffset = end
+	}
+	page.Sessions = sessions[offset:end]
+	return page
+}
 
-	// Calculate relevance scores for each session
-	now := time.Now()
-	for _, session := range sessionMap {
-		session.Score = calculateRelevanceScore(query, session, now)
+// filteredQuery is a search compiled to SQL: the sources its matches come
+// from, each with the query's filters compiled in
+type filteredQuery struct {
+	sources  []matchSource
+	match    string // FTS5 match expression, if the query has terms
+	useLike  bool   // No FTS match; snippets are extracted in Go
+	likeTerm string // The substring matched when useLike is set, if any
+}
+
+// matchSource is where matches come from (message text or tool blocks) as
+// FROM and WHERE clauses over m (the message) and s (its session)
+type matchSource struct {
+	from       string
+	where      string
+	args       []interface{}
+	textColumn string // Selects the match text (a snippet for FTS queries)
+	relevance  string // Scores a match; higher is better
+	toolColumn string // Selects the tool a match is in, or '' for message text
+}
+
+const (
+	messagesFrom = `
+		FROM messages m
+		JOIN sessions s ON s.id = m.session_id
+		LEFT JOIN session_summaries ss ON s.id = ss.session_id`
+	toolUsesFrom = `
+		FROM tool_uses t
+		JOIN messages m ON m.id = t.message_id
+		JOIN sessions s ON s.id = m.session_id
+		LEFT JOIN session_summaries ss ON s.id = ss.session_id`
+)
+
+// buildFilteredQuery compiles a parsed query and its filters into SQL.
+// Matches come from message text and from tool inputs and outputs; with a
+// tool: filter, tool matches are limited to that tool's calls (and file: to
+// its calls on that file), and a query of only filters lists those calls.
+func buildFilteredQuery(parsed *Query, filters SearchFilters) (*filteredQuery, error) {
+	q := &filteredQuery{}
+
+	match, err := parsed.FTS()
+	if err != nil {
+		return nil, err
+	}
+	q.match = match
+	likeTerm, single := parsed.singleTerm()
+	tools := parsed.Filter("tool")
+
+	messages := &matchSource{toolColumn: "''"}
+	toolUses := &matchSource{toolColumn: "t.tool_name"}
+
+	// Code queries match whole tokens in the unstemmed index. A single word
+	// with punctuation FTS5 would split it on (ENA-7030, user_id) uses LIKE
+	// for exact substring matching (see search).
+	switch {
+	case match == "":
+		// Only filters: every message with text in the filtered sessions, or
+		// with a tool: filter, every call to the tool
+		q.useLike = true
+		messages.from = messagesFrom
+		messages.textColumn = "m.text_content"
+		messages.relevance = "1.0"
+		messages.where = "COALESCE(m.text_content, '') != ''"
+		toolUses.from = toolUsesFrom
+		toolUses.textColumn = "COALESCE(t.input, '')"
+		toolUses.relevance = "1.0"
+		toolUses.where = "1"
+	case filters.Mode == ModeCode:
+		messages.from = `
+		FROM messages_fts_code
+		JOIN messages m ON messages_fts_code.rowid = m.id
+		JOIN sessions s ON s.id = m.session_id
+		LEFT JOIN session_summaries ss ON s.id = ss.session_id`
+		messages.textColumn = "snippet(messages_fts_code, 0, char(2), char(3), '...', 20)"
+		messages.relevance = "-bm25(messages_fts_code)"
+		messages.where = "messages_fts_code MATCH ?"
+		messages.args = append(messages.args, match)
+	case single && strings.ContainsAny(likeTerm, "-_@#$%&"):
+		q.useLike = true
+		q.likeTerm = likeTerm
+		messages.from = messagesFrom
+		messages.textColumn = "m.text_content"
+		messages.relevance = "1.0"
+		messages.where = "m.text_content LIKE '%' || ? || '%'"
+		messages.args = append(messages.args, likeTerm)
+		toolUses.from = toolUsesFrom
+		toolUses.textColumn = "TRIM(COALESCE(t.input, '') || char(10) || COALESCE(t.output, ''))"
+		toolUses.relevance = "1.0"
+		toolUses.where = "(t.input LIKE '%' || ? || '%' OR t.output LIKE '%' || ? || '%')"
+		toolUses.args = append(toolUses.args, likeTerm, likeTerm)
+	default:
+		messages.from = `
+		FROM messages_fts
+		JOIN messages m ON messages_fts.rowid = m.id
+		JOIN sessions s ON s.id = m.session_id
+		LEFT JOIN session_summaries ss ON s.id = ss.session_id`
+		messages.textColumn = "snippet(messages_fts, -1, char(2), char(3), '...', 20)"
+		messages.relevance = fmt.Sprintf("-bm25(messages_fts, %g, %g)", userTextWeight, assistantTextWeight)
+		messages.where = "messages_fts MATCH ?"
+		messages.args = append(messages.args, match)
+	}
+	// Tool blocks are searched through tool_uses_fts in text and code mode alike
+	if toolUses.from == "" {
+		toolUses.from = `
+		FROM tool_uses_fts
+		JOIN tool_uses t ON tool_uses_fts.rowid = t.id
+		JOIN messages m ON m.id = t.message_id
+		JOIN sessions s ON s.id = m.session_id
+		LEFT JOIN session_summaries ss ON s.id = ss.session_id`
+		toolUses.textColumn = "snippet(tool_uses_fts, -1, char(2), char(3), '...', 20)"
+		toolUses.relevance = fmt.Sprintf("%g * -bm25(tool_uses_fts)", toolTextWeight)
+		toolUses.where = "tool_uses_fts MATCH ?"
+		toolUses.args = append(toolUses.args, match)
 	}
 
-	// Convert map to slice and sort by relevance score (descending)
-	var sessionResults []SessionSearchResult
-	for _, sessionID := range sessionOrder {
-		sessionResults = append(sessionResults, *sessionMap[sessionID])
+	switch {
+	case match != "":
+		q.sources = []matchSource{*messages, *toolUses}
+	case len(tools) > 0:
+		q.sources = []matchSource{*toolUses}
+	default:
+		q.sources = []matchSource{*messages}
 	}
 
-	sort.Slice(sessionResults, func(i, j int) bool {
-		return sessionResults[i].Score > sessionResults[j].Score
-	})
+	conditions, args, err := scopeConditions(filters)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range parsed.Filters {
+		condition, filterArgs, err := filterCondition(f)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, filterArgs...)
+	}
 
-	return sessionResults, nil
+	// Conditions on the tool call itself
+	var toolConditions []string
+	var toolArgs []interface{}
+	if len(tools) > 0 {
+		var names []string
+		for _, tool := range tools {
+			names = append(names, "t.tool_name = ? COLLATE NOCASE")
+			toolArgs = append(toolArgs, tool)
+		}
+		toolConditions = append(toolConditions, "("+strings.Join(names, " OR ")+")")
+		for _, file := range parsed.Filter("file") {
+			toolConditions = append(toolConditions, toolFilePath+" LIKE '%' || ? || '%'")
+			toolArgs = append(toolArgs, file)
+		}
+	}
+
+	for i := range q.sources {
+		src := &q.sources[i]
+		where := append([]string{src.where}, conditions...)
+		src.args = append(src.args, args...)
+		if src.toolColumn != "''" {
+			where = append(where, toolConditions...)
+			src.args = append(src.args, toolArgs...)
+		}
+		src.where = strings.Join(where, " AND ")
+	}
+	return q, nil
 }
 
-// SearchCode performs a full-text search using the code-optimized FTS table
-// This table uses unicode61 tokenizer without stemming to preserve code identifiers
-func SearchCode(database *db.DB, query string) ([]SearchResult, error) {
-	return search(database, query, "messages_fts_code", 1000)
+// toolFilePath is the file a tool call t was given, if its input names one
+const toolFilePath = `CASE WHEN json_valid(t.input) THEN COALESCE(
+				json_extract(t.input, '$.file_path'),
+				json_extract(t.input, '$.notebook_path'),
+				json_extract(t.input, '$.path')
+			) END`
+
+// unionAll builds one SELECT per source, with the columns selectColumns
+// gives it and the extra condition, combined with UNION ALL
+func (q *filteredQuery) unionAll(selectColumns func(src matchSource) string, extra string, extraArgs []interface{}) (string, []interface{}) {
+	var selects []string
+	var args []interface{}
+	for _, src := range q.sources {
+		where := src.where
+		if extra != "" {
+			where += " AND " + extra
+		}
+		selects = append(selects, "SELECT "+selectColumns(src)+src.from+"\n\t\tWHERE "+where)
+		args = append(args, src.args...)
+		args = append(args, extraArgs...)
+	}
+	return strings.Join(selects, "\n\t\tUNION ALL\n\t\t"), args
 }
 
-// search is the internal implementation shared by Search and SearchCode
-func search(database *db.DB, query string, ftsTable string, limit int) ([]SearchResult, error) {
-	// Validate query
-	query = strings.TrimSpace(query)
-	if query == "" {
-		return nil, fmt.Errorf("search query cannot be empty")
+// scopeConditions compiles the filters outside the query text (session,
+// project and dates) into SQL conditions on m (a message) and s (its session)
+func scopeConditions(filters SearchFilters) ([]string, []interface{}, error) {
+	var conditions []string
+	var args []interface{}
+
+	if filters.CurrentSessionID != "" {
+		if filters.ExcludeCurrent {
+			conditions = append(conditions, "s.session_id != ?")
+		} else {
+			conditions = append(conditions, "s.session_id = ?")
+		}
+		args = append(args, filters.CurrentSessionID)
 	}
 
-	// Check if query contains special characters that FTS5 can't handle well
-	// For these, use LIKE instead for exact substring matching
-	hasSpecialChars := strings.ContainsAny(query, "-_@#$%&")
+	if filters.ProjectPath != "" {
+		conditions = append(conditions, "s.project_path LIKE '%' || ? || '%'")
+		args = append(args, filters.ProjectPath)
+	}
 
-	var rows *sql.Rows
-	var err error
+	// Timestamps are stored in UTC as "2006-01-02 15:04:05..." strings, so
+	// bounds in the same form compare correctly as text
+	if filters.AfterDate != "" {
+		after, err := parseDateFilter(filters.AfterDate)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid after date: %w", err)
+		}
+		conditions = append(conditions, "m.timestamp >= ?")
+		args = append(args, after.UTC().Format("2006-01-02 15:04:05"))
+	}
+	if filters.BeforeDate != "" {
+		before, err := parseDateFilter(filters.BeforeDate)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid before date: %w", err)
+		}
+		conditions = append(conditions, "m.timestamp < ?")
+		args = append(args, before.UTC().Format("2006-01-02 15:04:05"))
+	}
 
-	if hasSpecialChars {
-		// Use LIKE for exact substring matching with snippet extraction
-		rows, err = database.Query(fmt.Sprintf(`
-			SELECT
-				m.uuid,
-				s.session_id,
-				COALESCE(ss.one_line_summary, s.llm_summary, s.summary, ''),
-				m.text_content,
-				m.timestamp,
-				s.project_path
-			FROM messages m
-			JOIN sessions s ON s.id = m.session_id
-			LEFT JOIN session_summaries ss ON s.id = ss.session_id
-			WHERE m.text_content LIKE '%%' || ? || '%%'
-			ORDER BY %s
-			LIMIT ?
-		`, defaultOrderBy), query, limit)
-	} else {
-		// Use FTS5 with snippet for regular queries
-		escapedQuery := query
-
-		sql := fmt.Sprintf(`
-			SELECT
-				m.uuid,
-				s.session_id,
-				COALESCE(ss.one_line_summary, s.llm_summary, s.summary, ''),
-				snippet(%s, -1, '', '', '...', 64) as snippet,
-				m.timestamp,
-				s.project_path
-			FROM %s
-			JOIN messages m ON %s.rowid = m.id
-			JOIN sessions s ON s.id = m.session_id
-			LEFT JOIN session_summaries ss ON s.id = ss.session_id
-			WHERE %s MATCH ?
-			ORDER BY %s
-			LIMIT ?
-		`, ftsTable, ftsTable, ftsTable, ftsTable, defaultOrderBy)
+	return conditions, args, nil
+}
 
-		rows, err = database.Query(sql, escapedQuery, limit)
+// filterCondition compiles a field filter into a SQL condition on m (the
+// message) or s (its session). Session-level filters select from their own
+// tables once rather than per message.
+func filterCondition(f FieldFilter) (string, []interface{}, error) {
+	switch f.Field {
+	case "project":
+		return "s.project_path LIKE '%' || ? || '%'", []interface{}{f.Value}, nil
+	case "branch":
+		return "COALESCE(m.git_branch, s.git_branch, '') LIKE '%' || ? || '%'", []interface{}{f.Value}, nil
+	case "role":
+		return "m.type = ?", []interface{}{strings.ToLower(f.Value)}, nil
+	case "tool":
+		return `s.id IN (
+			SELECT tm.session_id FROM tool_uses t JOIN messages tm ON tm.id = t.message_id
+			WHERE t.tool_name = ? COLLATE NOCASE
+		)`, []interface{}{f.Value}, nil
+	case "file":
+		// Extracted paths (ccrider summarize) or the paths tools were given
+		return `s.id IN (
+			SELECT sf.session_id FROM session_files sf WHERE sf.file_path LIKE '%' || ? || '%'
+			UNION
+			SELECT tm.session_id FROM tool_uses t JOIN messages tm ON tm.id = t.message_id
+			WHERE ` + toolFilePath + ` LIKE '%' || ? || '%'
+		)`, []interface{}{f.Value, f.Value}, nil
+	case "issue":
+		// Extracted issue IDs (ccrider summarize) or a mention in any message
+		return `s.id IN (
+			SELECT si.session_id FROM session_issues si WHERE si.issue_id_lower = LOWER(?)
+			UNION
+			SELECT im.session_id FROM messages im WHERE im.text_content LIKE '%' || ? || '%'
+		)`, []interface{}{f.Value, f.Value}, nil
+	case "model":
+		return `s.id IN (
+			SELECT mm.session_id FROM messages mm WHERE mm.model LIKE '%' || ? || '%'
+		)`, []interface{}{f.Value}, nil
+	case "after", "date", "before":
+		t, ok := parseQueryDate(f.Value)
+		if !ok {
+			return "", nil, &QueryError{Msg: fmt.Sprintf("can't parse %s date %q", f.Field, f.Value)}
+		}
+		op := ">="
+		if f.Field == "before" {
+			op = "<"
+		}
+		return "m.timestamp " + op + " ?", []interface{}{t.UTC().Format("2006-01-02 15:04:05")}, nil
 	}
+	return "", nil, &QueryError{Msg: fmt.Sprintf("unknown filter %q", f.Field)}
+}
+
+// identifierPattern matches a single identifier, optionally qualified
+// (pkg.Func, Class::method)
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*((\.|::)[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// looksLikeIdentifier reports whether a query is a code identifier:
+// snake_case, camelCase or qualified, rather than a word someone might mean
+// in its stemmed sense
+func looksLikeIdentifier(query string) bool {
+	if !identifierPattern.MatchString(query) {
+		return false
+	}
+	if strings.Contains(query, "_") || strings.Contains(query, ".") || strings.Contains(query, "::") {
+		return true
+	}
+	// Mixed case with an uppercase letter after the first (handleAuth,
+	// HTTPServer), unlike a capitalized word or an acronym
+	hasLower := strings.ToUpper(query) != query
+	return hasLower && strings.ContainsAny(query[1:], "ABCDEFGHIJKLMNOPQRSTUVWXYZ")
+}
+
+// loadMatches fills in the matching messages of each session, most recent first
+func loadMatches(database *db.DB, q *filteredQuery, sessions []SessionSearchResult, matchLimit int) error {
+	bySession := make(map[string]*SessionSearchResult)
+	placeholders := make([]string, len(sessions))
+	var ids []interface{}
+	for i := range sessions {
+		sessions[i].Matches = []SearchResult{}
+		bySession[sessions[i].SessionID] = &sessions[i]
+		placeholders[i] = "?"
+		ids = append(ids, sessions[i].SessionID)
+	}
+
+	matches, args := q.unionAll(func(src matchSource) string {
+		return fmt.Sprintf(`
+			m.uuid,
+			s.session_id,
+			COALESCE(ss.one_line_summary, s.llm_summary, s.summary, ''),
+			%s,
+			m.timestamp AS match_time,
+			s.project_path,
+			%s`, src.textColumn, src.toolColumn)
+	}, "s.session_id IN ("+strings.Join(placeholders, ", ")+")", ids)
+	rows, err := database.Query(matches+`
+		ORDER BY match_time DESC
+	`, args...)
 	if err != nil {
-		return nil, fmt.Errorf("search query failed: %w", err)
+		return fmt.Errorf("search query failed: %w", err)
 	}
 	defer func() { _ = rows.Close() }()
 
-	var results []SearchResult
 	for rows.Next() {
 		var r SearchResult
 		if err := rows.Scan(
@@ -207,24 +580,54 @@ func search(database *db.DB, query string, ftsTable string, limit int) ([]Search
 			&r.MessageText,
 			&r.Timestamp,
 			&r.ProjectPath,
+			&r.ToolName,
 		); err != nil {
-			return nil, fmt.Errorf("failed to scan result: %w", err)
+			return fmt.Errorf("failed to scan result: %w", err)
 		}
 
-		// For LIKE searches, extract snippet around the match
-		// Keep it short (100 chars) so TUI can show full snippet with match visible
-		if hasSpecialChars {
-			r.MessageText = extractSnippet(r.MessageText, query, 100)
+		session := bySession[r.SessionID]
+		if matchLimit > 0 && len(session.Matches) >= matchLimit {
+			continue
 		}
-
-		results = append(results, r)
+		if q.useLike {
+			r.MessageText = markMatches(extractSnippet(r.MessageText, q.likeTerm, 100), q.likeTerm)
+		}
+		session.Matches = append(session.Matches, r)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating results: %w", err)
+		return fmt.Errorf("error iterating results: %w", err)
 	}
+	return nil
+}
 
-	return results, nil
+// parseDateFilter parses a date filter: a date (local midnight) or an
+// RFC 3339 timestamp
+func parseDateFilter(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02T15:04:05", s, time.Local); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", s, time.Local)
+}
+
+// parseTimestamp parses a stored message timestamp, which may be in Go's
+// time.String() format or SQLite's datetime format
+func parseTimestamp(s string) (time.Time, bool) {
+	formats := []string{
+		"2006-01-02 15:04:05.999999999 -0700 MST",
+		time.RFC3339Nano,
+		"2006-01-02 15:04:05",
+	}
+	for _, format := range formats {
+		if t, err := time.Parse(format, s); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
 }
 
 // extractSnippet extracts a snippet from text centered around the query match
@@ -398,35 +801,122 @@ func extractSnippet(text, query string, maxLen int) string {
 	return snippet
 }
 
-// calculateRelevanceScore computes a relevance score for a session based on:
-// - Number of matching messages (10 points each)
-// - Whether query appears in summary (50 point boost)
-// - Recency of the session (0-20 point boost, logarithmic decay)
-func calculateRelevanceScore(query string, session *SessionSearchResult, now time.Time) float64 {
-	score := 0.0
+// calculateRelevanceScore computes a relevance score for a session from:
+// - Its best-matching message (bm25), scaled up by log(number of matches)
+// - How well its summary matches (bm25, weighted by summaryWeight)
+// - Recency of the session (up to 1.5x, decaying over about a week)
+func calculateRelevanceScore(session *SessionSearchResult, bestMatch, summaryMatch float64, now time.Time) float64 {
+	score := bestMatch * (1 + math.Log(float64(max(session.MatchCount, 1))))
+	score += summaryWeight * summaryMatch
 
-	// Base score: 10 points per matching message
-	score += float64(len(session.Matches)) * 10.0
-
-	// Summary boost: 50 points if query appears in summary
-	queryLower := strings.ToLower(query)
-	summaryLower := strings.ToLower(session.SessionSummary)
-	if strings.Contains(summaryLower, queryLower) {
-		score += 50.0
-	}
-
-	// Recency boost: 0-20 points based on how recent the session is
-	// Uses logarithmic decay: sessions from today get ~20, last week ~10, last month ~5
 	updatedAt, err := time.Parse(time.RFC3339, session.UpdatedAt)
 	if err == nil {
 		ageHours := now.Sub(updatedAt).Hours()
 		if ageHours < 1 {
-			ageHours = 1 // Avoid log(0)
+			ageHours = 1
 		}
-		// Log scale: recent sessions get higher boost, old ones get minimal
-		recencyBoost := 20.0 * math.Exp(-ageHours/168.0) // 168 hours = 1 week decay constant
-		score += recencyBoost
+		score *= 1 + 0.5*math.Exp(-ageHours/168.0) // 168 hours = 1 week decay constant
 	}
 
 	return score
 }
+
+// summaryRelevance scores how well each session's summary matches the query,
+// keyed by session ID. LIKE queries score 1 for a substring match.
+func summaryRelevance(database *db.DB, q *filteredQuery) (map[string]float64, error) {
+	relevance := make(map[string]float64)
+	var rows *sql.Rows
+	var err error
+	switch {
+	case q.useLike && q.likeTerm == "":
+		return relevance, nil // Only filters, nothing to match
+	case q.useLike:
+		rows, err = database.Query(`
+			SELECT s.session_id, 1.0
+			FROM sessions s
+			LEFT JOIN session_summaries ss ON s.id = ss.session_id
+			WHERE COALESCE(ss.one_line_summary, s.llm_summary, s.summary, '') LIKE '%' || ? || '%'
+		`, q.likeTerm)
+	default:
+		rows, err = database.Query(`
+			SELECT s.session_id, -bm25(sessions_fts)
+			FROM sessions_fts
+			JOIN sessions s ON s.id = sessions_fts.rowid
+			WHERE sessions_fts MATCH ?
+		`, q.match)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("summary search failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var sessionID string
+		var score float64
+		if err := rows.Scan(&sessionID, &score); err != nil {
+			return nil, fmt.Errorf("failed to scan result: %w", err)
+		}
+		relevance[sessionID] = score
+	}
+	return relevance, rows.Err()
+}
+
+// Highlight replaces the match markers in a snippet with mark's rendering of
+// each matched span (e.g. a terminal style). A span cut off by truncation is
+// marked to the end of the snippet.
+func Highlight(snippet string, mark func(string) string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(snippet, HighlightStart)
+		if start == -1 {
+			b.WriteString(strings.ReplaceAll(snippet, HighlightEnd, ""))
+			return b.String()
+		}
+		b.WriteString(strings.ReplaceAll(snippet[:start], HighlightEnd, ""))
+		snippet = snippet[start+len(HighlightStart):]
+
+		end := strings.Index(snippet, HighlightEnd)
+		if end == -1 {
+			end = len(snippet)
+		}
+		if end > 0 {
+			b.WriteString(mark(strings.ReplaceAll(snippet[:end], HighlightStart, "")))
+		}
+		if end < len(snippet) {
+			end += len(HighlightEnd)
+		}
+		snippet = snippet[end:]
+	}
+}
+
+// StripHighlights removes the match markers from a snippet
+func StripHighlights(snippet string) string {
+	return Highlight(snippet, func(s string) string { return s })
+}
+
+// markMatches wraps case-insensitive occurrences of query in match markers
+func markMatches(text, query string) string {
+	if query == "" {
+		return text
+	}
+	lowerText := strings.ToLower(text)
+	lowerQuery := strings.ToLower(query)
+	if len(lowerText) != len(text) {
+		return text // Case folding changed byte offsets
+	}
+
+	var b strings.Builder
+	for {
+		idx := strings.Index(lowerText, lowerQuery)
+		if idx == -1 {
+			b.WriteString(text)
+			return b.String()
+		}
+		b.WriteString(text[:idx])
+		b.WriteString(HighlightStart)
+		b.WriteString(text[idx : idx+len(query)])
+		b.WriteString(HighlightEnd)
+		text = text[idx+len(query):]
+		lowerText = lowerText[idx+len(query):]
+	}
+}
@@ -1,10 +1,17 @@
 package search
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/neilberkman/ccrider/internal/core/db"
+	"github.com/neilberkman/ccrider/internal/core/importer"
+	"github.com/neilberkman/ccrider/pkg/ccsessions"
 )
 
 func TestSearch(t *testing.T) {
@@ -63,7 +70,7 @@ func TestSearch(t *testing.T) {
 
 	// Test basic search
 	t.Run("BasicSearch", func(t *testing.T) {
-		results, err := Search(database, "authentication")
+		results, err := searchMatches(database, SearchFilters{Query: "authentication"})
 		if err != nil {
 			t.Fatalf("Search failed: %v", err)
 		}
@@ -91,7 +98,7 @@ func TestSearch(t *testing.T) {
 
 	// Test phrase search
 	t.Run("PhraseSearch", func(t *testing.T) {
-		results, err := Search(database, "user authentication")
+		results, err := searchMatches(database, SearchFilters{Query: "user authentication"})
 		if err != nil {
 			t.Fatalf("Phrase search failed: %v", err)
 		}
@@ -103,18 +110,18 @@ func TestSearch(t *testing.T) {
 
 	// Test empty query
 	t.Run("EmptyQuery", func(t *testing.T) {
-		results, err := Search(database, "")
-		if err == nil {
-			t.Error("Expected error for empty query")
+		results, err := searchMatches(database, SearchFilters{Query: ""})
+		if err != nil {
+			t.Fatalf("Search failed: %v", err)
 		}
-		if results != nil {
-			t.Error("Expected nil results for empty query")
+		if len(results) != 0 {
+			t.Errorf("Expected no results for empty query, got %d", len(results))
 		}
 	})
 
 	// Test no results
 	t.Run("NoResults", func(t *testing.T) {
-		results, err := Search(database, "nonexistent")
+		results, err := searchMatches(database, SearchFilters{Query: "nonexistent"})
 		if err != nil {
 			t.Fatalf("Search failed: %v", err)
 		}
@@ -178,7 +185,7 @@ func TestSearchCode(t *testing.T) {
 
 	// Test code search with camelCase
 	t.Run("CamelCaseSearch", func(t *testing.T) {
-		results, err := SearchCode(database, "getUserById")
+		results, err := searchMatches(database, SearchFilters{Query: "getUserById", Mode: ModeCode})
 		if err != nil {
 			t.Fatalf("Code search failed: %v", err)
 		}
@@ -202,7 +209,7 @@ func TestSearchCode(t *testing.T) {
 
 	// Test wildcard search
 	t.Run("WildcardSearch", func(t *testing.T) {
-		results, err := SearchCode(database, "handle*")
+		results, err := searchMatches(database, SearchFilters{Query: "handle*", Mode: ModeCode})
 		if err != nil {
 			t.Fatalf("Wildcard search failed: %v", err)
 		}
@@ -265,7 +272,7 @@ func TestSearchOrdering(t *testing.T) {
 
 	// Test that results are ordered by relevance
 	t.Run("RelevanceOrdering", func(t *testing.T) {
-		results, err := Search(database, "test")
+		results, err := searchMatches(database, SearchFilters{Query: "test"})
 		if err != nil {
 			t.Fatalf("Search failed: %v", err)
 		}
@@ -281,3 +288,573 @@ func TestSearchOrdering(t *testing.T) {
 		}
 	})
 }
+
+func TestSearchWithFilters(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Remove(tmpfile.Name()) }()
+	_ = tmpfile.Close()
+
+	database, err := db.New(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	// One busy project and a quieter one, so an unfiltered top-N of matches
+	// would be all "/work/busy"
+	insertSession := func(sessionID, project string, day int, matches int) {
+		result, err := database.Exec(`
+			INSERT INTO sessions (session_id, project_path, summary, created_at, updated_at)
+			VALUES (?, ?, ?, datetime('now'), datetime('now'))
+		`, sessionID, project, "Session "+sessionID)
+		if err != nil {
+			t.Fatalf("Failed to insert session: %v", err)
+		}
+		id, _ := result.LastInsertId()
+
+		for i := 0; i < matches; i++ {
+			timestamp := time.Date(2025, 11, day, 10, i, 0, 0, time.UTC)
+			_, err := database.Exec(`
+				INSERT INTO messages (uuid, session_id, type, text_content, timestamp, sequence)
+				VALUES (?, ?, 'user', ?, ?, ?)
+			`, fmt.Sprintf("%s-%d", sessionID, i), id, "fix the deployment pipeline", timestamp, i+1)
+			if err != nil {
+				t.Fatalf("Failed to insert message: %v", err)
+			}
+		}
+	}
+	for i := 0; i < 5; i++ {
+		insertSession(fmt.Sprintf("busy-%d", i), "/work/busy", 10+i, 300)
+	}
+	insertSession("quiet-1", "/work/quiet", 1, 1)
+	insertSession("quiet-2", "/work/quiet", 20, 2)
+
+	t.Run("ProjectFilter", func(t *testing.T) {
+		page, err := SearchWithFilters(database, SearchFilters{Query: "deployment", ProjectPath: "quiet"})
+		if err != nil {
+			t.Fatalf("SearchWithFilters() error = %v", err)
+		}
+		if page.Total != 2 || len(page.Sessions) != 2 {
+			t.Fatalf("Expected both quiet sessions, got %d of %d", len(page.Sessions), page.Total)
+		}
+		if page.Sessions[0].SessionID != "quiet-2" || page.Sessions[0].MatchCount != 2 {
+			t.Errorf("Expected quiet-2 with 2 matches first, got %s with %d", page.Sessions[0].SessionID, page.Sessions[0].MatchCount)
+		}
+	})
+
+	t.Run("DateFilter", func(t *testing.T) {
+		page, err := SearchWithFilters(database, SearchFilters{
+			Query:      "deployment",
+			AfterDate:  "2025-11-11T00:00:00Z",
+			BeforeDate: "2025-11-13T00:00:00Z",
+		})
+		if err != nil {
+			t.Fatalf("SearchWithFilters() error = %v", err)
+		}
+		if page.Total != 2 {
+			t.Fatalf("Expected busy-1 and busy-2, got %d sessions", page.Total)
+		}
+
+		if _, err := SearchWithFilters(database, SearchFilters{Query: "deployment", AfterDate: "last tuesday"}); err == nil {
+			t.Error("Expected error for invalid date")
+		}
+	})
+
+	t.Run("Pagination", func(t *testing.T) {
+		seen := make(map[string]bool)
+		filters := SearchFilters{Query: "deployment", Limit: 3, MatchLimit: 2}
+		for pages := 0; pages < 10; pages++ {
+			page, err := SearchWithFilters(database, filters)
+			if err != nil {
+				t.Fatalf("SearchWithFilters() error = %v", err)
+			}
+			if page.Total != 7 {
+				t.Fatalf("Total = %d, want 7", page.Total)
+			}
+			for _, s := range page.Sessions {
+				if seen[s.SessionID] {
+					t.Errorf("Session %s returned on two pages", s.SessionID)
+				}
+				seen[s.SessionID] = true
+				if len(s.Matches) > 2 {
+					t.Errorf("Session %s has %d matches, want at most 2", s.SessionID, len(s.Matches))
+				}
+			}
+			if page.NextOffset == 0 {
+				break
+			}
+			filters.Offset = page.NextOffset
+		}
+		if len(seen) != 7 {
+			t.Errorf("Paged through %d sessions, want 7", len(seen))
+		}
+	})
+
+	t.Run("CurrentSession", func(t *testing.T) {
+		page, err := SearchWithFilters(database, SearchFilters{Query: "deployment", CurrentSessionID: "quiet-1"})
+		if err != nil {
+			t.Fatalf("SearchWithFilters() error = %v", err)
+		}
+		if page.Total != 1 || page.Sessions[0].SessionID != "quiet-1" {
+			t.Errorf("Expected only quiet-1, got %d sessions", page.Total)
+		}
+
+		page, err = SearchWithFilters(database, SearchFilters{Query: "deployment", CurrentSessionID: "quiet-1", ExcludeCurrent: true})
+		if err != nil {
+			t.Fatalf("SearchWithFilters() error = %v", err)
+		}
+		if page.Total != 6 {
+			t.Errorf("Expected 6 other sessions, got %d", page.Total)
+		}
+	})
+}
+
+func TestSearchWithFilters_Ranking(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Remove(tmpfile.Name()) }()
+	_ = tmpfile.Close()
+
+	database, err := db.New(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	insertSession := func(sessionID, summary string, messages ...[2]string) {
+		result, err := database.Exec(`
+			INSERT INTO sessions (session_id, project_path, summary, created_at, updated_at)
+			VALUES (?, '/test', ?, datetime('now'), datetime('now'))
+		`, sessionID, summary)
+		if err != nil {
+			t.Fatalf("Failed to insert session: %v", err)
+		}
+		id, _ := result.LastInsertId()
+		for i, msg := range messages {
+			_, err := database.Exec(`
+				INSERT INTO messages (uuid, session_id, type, text_content, timestamp, sequence)
+				VALUES (?, ?, ?, ?, '2025-01-01 10:00:00', ?)
+			`, fmt.Sprintf("%s-%d", sessionID, i), id, msg[0], msg[1], i+1)
+			if err != nil {
+				t.Fatalf("Failed to insert message: %v", err)
+			}
+		}
+	}
+
+	// Filler so "kubernetes" is a rare term and bm25 has something to weigh
+	for i := 0; i < 5; i++ {
+		insertSession(fmt.Sprintf("filler-%d", i), "Unrelated", [2]string{"user", "update the readme and docs"})
+	}
+	insertSession("assistant-only", "Cleanup", [2]string{"assistant", "You could deploy this on kubernetes later"})
+	insertSession("user-prompt", "Cleanup", [2]string{"user", "Help me deploy this on kubernetes"})
+	insertSession("summary", "Kubernetes cluster setup", [2]string{"assistant", "You could deploy this on kubernetes later"})
+
+	page, err := SearchWithFilters(database, SearchFilters{Query: "kubernetes"})
+	if err != nil {
+		t.Fatalf("SearchWithFilters() error = %v", err)
+	}
+	var order []string
+	for _, s := range page.Sessions {
+		order = append(order, s.SessionID)
+	}
+	if len(order) != 3 || order[0] != "summary" || order[1] != "user-prompt" || order[2] != "assistant-only" {
+		t.Errorf("Ranking = %v, want [summary user-prompt assistant-only]", order)
+	}
+
+	// Snippets mark the tokens that matched, including stemmed forms
+	page, err = SearchWithFilters(database, SearchFilters{Query: "deploying", CurrentSessionID: "user-prompt"})
+	if err != nil {
+		t.Fatalf("SearchWithFilters() error = %v", err)
+	}
+	if len(page.Sessions) != 1 || len(page.Sessions[0].Matches) != 1 {
+		t.Fatalf("Expected one match in user-prompt, got %+v", page.Sessions)
+	}
+	snippet := Highlight(page.Sessions[0].Matches[0].MessageText, func(s string) string { return "[" + s + "]" })
+	if snippet != "Help me [deploy] this on kubernetes" {
+		t.Errorf("Snippet = %q, want deploy highlighted", snippet)
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	mark := func(s string) string { return "<" + s + ">" }
+	tests := []struct {
+		snippet string
+		want    string
+	}{
+		{"no matches", "no matches"},
+		{"a " + HighlightStart + "match" + HighlightEnd + " here", "a <match> here"},
+		{HighlightStart + "cut off", "<cut off>"},
+		{"stray" + HighlightEnd + " end", "stray end"},
+	}
+	for _, tt := range tests {
+		if got := Highlight(tt.snippet, mark); got != tt.want {
+			t.Errorf("Highlight(%q) = %q, want %q", tt.snippet, got, tt.want)
+		}
+	}
+
+	if got := markMatches("Fix ENA-7030 and ena-7031", "ena-703"); StripHighlights(got) != "Fix ENA-7030 and ena-7031" || Highlight(got, mark) != "Fix <ENA-703>0 and <ena-703>1" {
+		t.Errorf("markMatches() = %q", got)
+	}
+}
+
+func TestSearchWithFilters_CodeMode(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Remove(tmpfile.Name()) }()
+	_ = tmpfile.Close()
+
+	database, err := db.New(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	messages := map[string]string{
+		"snake":   "Rename the user_id column",
+		"words":   "Look up the user by id",
+		"ids":     "Store user_ids in a set",
+		"camel":   "handleAuthCallback returns early",
+		"stemmed": "It handles auth callbacks",
+	}
+	for sessionID, text := range messages {
+		result, err := database.Exec(`
+			INSERT INTO sessions (session_id, project_path, summary, created_at, updated_at)
+			VALUES (?, '/test', '', datetime('now'), datetime('now'))
+		`, sessionID)
+		if err != nil {
+			t.Fatalf("Failed to insert session: %v", err)
+		}
+		id, _ := result.LastInsertId()
+		_, err = database.Exec(`
+			INSERT INTO messages (uuid, session_id, type, text_content, timestamp, sequence)
+			VALUES (?, ?, 'user', ?, '2025-01-01 10:00:00', 1)
+		`, sessionID+"-1", id, text)
+		if err != nil {
+			t.Fatalf("Failed to insert message: %v", err)
+		}
+	}
+
+	sessionIDs := func(filters SearchFilters) []string {
+		t.Helper()
+		page, err := SearchWithFilters(database, filters)
+		if err != nil {
+			t.Fatalf("SearchWithFilters(%+v) error = %v", filters, err)
+		}
+		var ids []string
+		for _, s := range page.Sessions {
+			ids = append(ids, s.SessionID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+
+	tests := []struct {
+		name    string
+		filters SearchFilters
+		want    []string
+	}{
+		{"CodeExact", SearchFilters{Query: "user_id", Mode: ModeCode}, []string{"snake"}},
+		{"CodePrefix", SearchFilters{Query: "user_id*", Mode: ModeCode}, []string{"ids", "snake"}},
+		{"CodePunctuation", SearchFilters{Query: "handleAuthCallback()", Mode: ModeCode}, []string{"camel"}},
+		{"TextSubstring", SearchFilters{Query: "user_id", Mode: ModeText}, []string{"ids", "snake"}},
+		{"AutoIdentifier", SearchFilters{Query: "handleAuthCallback"}, []string{"camel"}},
+		{"AutoWords", SearchFilters{Query: "callback"}, []string{"stemmed"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := sessionIDs(tt.filters)
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Sessions = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	page, err := SearchWithFilters(database, SearchFilters{Query: "user_id", Mode: ModeCode})
+	if err != nil {
+		t.Fatalf("SearchWithFilters() error = %v", err)
+	}
+	snippet := Highlight(page.Sessions[0].Matches[0].MessageText, func(s string) string { return "[" + s + "]" })
+	if snippet != "Rename the [user_id] column" {
+		t.Errorf("Snippet = %q, want user_id highlighted", snippet)
+	}
+}
+
+func TestLooksLikeIdentifier(t *testing.T) {
+	tests := map[string]bool{
+		"handleAuthCallback": true,
+		"user_id":            true,
+		"HTTPServer":         true,
+		"os.Getenv":          true,
+		"Repo::find":         true,
+		"authentication":     false,
+		"Authentication":     false,
+		"JSON":               false,
+		"error handling":     false,
+		"ENA-7030":           false,
+	}
+	for query, want := range tests {
+		if got := looksLikeIdentifier(query); got != want {
+			t.Errorf("looksLikeIdentifier(%q) = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestSearchWithFilters_QueryLanguage(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Remove(tmpfile.Name()) }()
+	_ = tmpfile.Close()
+
+	database, err := db.New(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	type message struct {
+		typ, text, model, tool, toolInput string
+	}
+	insertSession := func(sessionID, branch string, messages ...message) int64 {
+		result, err := database.Exec(`
+			INSERT INTO sessions (session_id, project_path, summary, git_branch, created_at, updated_at)
+			VALUES (?, '/test', '', ?, datetime('now'), datetime('now'))
+		`, sessionID, branch)
+		if err != nil {
+			t.Fatalf("Failed to insert session: %v", err)
+		}
+		id, _ := result.LastInsertId()
+		for i, msg := range messages {
+			result, err := database.Exec(`
+				INSERT INTO messages (uuid, session_id, type, text_content, model, timestamp, sequence)
+				VALUES (?, ?, ?, ?, NULLIF(?, ''), '2025-01-01 10:00:00', ?)
+			`, fmt.Sprintf("%s-%d", sessionID, i), id, msg.typ, msg.text, msg.model, i+1)
+			if err != nil {
+				t.Fatalf("Failed to insert message: %v", err)
+			}
+			if msg.tool != "" {
+				messageID, _ := result.LastInsertId()
+				_, err := database.Exec(`INSERT INTO tool_uses (message_id, tool_name, input) VALUES (?, ?, ?)`,
+					messageID, msg.tool, msg.toolInput)
+				if err != nil {
+					t.Fatalf("Failed to insert tool use: %v", err)
+				}
+			}
+		}
+		return id
+	}
+
+	insertSession("bash", "main",
+		message{typ: "user", text: "deploy the token refresh service"},
+		message{typ: "assistant", text: "Running the deploy script", model: "claude-opus-4-1", tool: "Bash", toolInput: `{"command":"./deploy.sh"}`},
+	)
+	insertSession("edit", "feature/auth",
+		message{typ: "user", text: "refresh the token cache"},
+		message{typ: "assistant", text: "Editing the cache to deploy later", model: "claude-sonnet-4-5", tool: "Edit", toolInput: `{"file_path":"/src/auth/cache.go"}`},
+	)
+	issueSession := insertSession("issue", "main",
+		message{typ: "user", text: "look at the oauth token bug"},
+		message{typ: "assistant", text: "Checking the token refresh", model: "claude-sonnet-4-5", tool: "Read", toolInput: `not json`},
+	)
+	if err := database.SaveSessionIssues(issueSession, []db.SessionIssue{{SessionID: issueSession, IssueID: "ENA-42", MentionCount: 1}}); err != nil {
+		t.Fatalf("SaveSessionIssues() error = %v", err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{`"token refresh"`, []string{"bash", "issue"}},
+		{`refresh token`, []string{"bash", "edit", "issue"}},
+		{`token -oauth role:user`, []string{"bash", "edit"}},
+		{`(oauth OR cache) token`, []string{"edit", "issue"}},
+		{`deploy role:user`, []string{"bash"}},
+		{`deploy role:assistant`, []string{"bash", "edit"}},
+		{`token tool:bash`, []string{"bash"}},
+		{`token file:auth/cache`, []string{"edit"}},
+		{`token issue:ena-42`, []string{"issue"}},
+		{`token model:opus`, []string{"bash"}},
+		{`token branch:feature`, []string{"edit"}},
+		{`tool:Edit`, []string{"edit"}},
+		{`token before:2024-06-01`, nil},
+		{`token after:2024-06-01 branch:main`, []string{"bash", "issue"}},
+	}
+	for _, tt := range tests {
+		page, err := SearchWithFilters(database, SearchFilters{Query: tt.query, MatchLimit: 1})
+		if err != nil {
+			t.Errorf("SearchWithFilters(%q) error = %v", tt.query, err)
+			continue
+		}
+		var got []string
+		for _, s := range page.Sessions {
+			got = append(got, s.SessionID)
+		}
+		sort.Strings(got)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("SearchWithFilters(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	_, err = SearchWithFilters(database, SearchFilters{Query: "token after:someday"})
+	var queryErr *QueryError
+	if !errors.As(err, &queryErr) {
+		t.Errorf("Expected a QueryError for an unparseable date, got %v", err)
+	}
+}
+
+func TestSearchWithFilters_ToolBlocks(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Remove(tmpfile.Name()) }()
+	_ = tmpfile.Close()
+
+	database, err := db.New(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	type toolUse struct {
+		tool, input, output string
+	}
+	insertSession := func(sessionID, text string, uses ...toolUse) {
+		result, err := database.Exec(`
+			INSERT INTO sessions (session_id, project_path, summary, created_at, updated_at)
+			VALUES (?, '/test', '', datetime('now'), datetime('now'))
+		`, sessionID)
+		if err != nil {
+			t.Fatalf("Failed to insert session: %v", err)
+		}
+		id, _ := result.LastInsertId()
+		result, err = database.Exec(`
+			INSERT INTO messages (uuid, session_id, type, text_content, timestamp, sequence)
+			VALUES (?, ?, 'assistant', ?, '2025-01-01 10:00:00', 1)
+		`, sessionID+"-0", id, text)
+		if err != nil {
+			t.Fatalf("Failed to insert message: %v", err)
+		}
+		messageID, _ := result.LastInsertId()
+		for _, u := range uses {
+			_, err := database.Exec(`INSERT INTO tool_uses (message_id, tool_name, input, output) VALUES (?, ?, ?, ?)`,
+				messageID, u.tool, u.input, u.output)
+			if err != nil {
+				t.Fatalf("Failed to insert tool use: %v", err)
+			}
+		}
+	}
+
+	insertSession("kubectl", "Applying the manifests",
+		toolUse{"Bash", `{"command":"kubectl apply -f deploy.yaml"}`, "deployment.apps/api configured"},
+	)
+	insertSession("compile", "Building",
+		toolUse{"Bash", `{"command":"go build ./..."}`, "auth.go:12:2: undefined: tokenStore"},
+		toolUse{"Edit", `{"file_path":"/src/auth.go","old_string":"a","new_string":"b"}`, ""},
+	)
+	insertSession("mention", "You could run kubectl apply yourself",
+		toolUse{"Read", `{"file_path":"/src/main.go"}`, "package main"},
+	)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{`"kubectl apply"`, []string{"kubectl", "mention"}}, // Tool input and message text
+		{`"kubectl apply" tool:Bash`, []string{"kubectl"}},
+		{`"undefined: tokenStore"`, []string{"compile"}}, // Tool output
+		{`tool:Edit file:auth.go`, []string{"compile"}},
+		{`tool:Edit file:main.go`, nil},
+		{`tool:Read`, []string{"mention"}},
+	}
+	for _, tt := range tests {
+		page, err := SearchWithFilters(database, SearchFilters{Query: tt.query})
+		if err != nil {
+			t.Errorf("SearchWithFilters(%q) error = %v", tt.query, err)
+			continue
+		}
+		var got []string
+		for _, s := range page.Sessions {
+			got = append(got, s.SessionID)
+		}
+		sort.Strings(got)
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("SearchWithFilters(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	// Matches say which tool they're in
+	page, err := SearchWithFilters(database, SearchFilters{Query: `"kubectl apply" tool:Bash`})
+	if err != nil {
+		t.Fatalf("SearchWithFilters() error = %v", err)
+	}
+	matches := page.Sessions[0].Matches
+	if len(matches) != 1 || matches[0].ToolName != "Bash" || !strings.Contains(matches[0].MessageText, HighlightStart+"kubectl apply"+HighlightEnd) {
+		t.Errorf("Expected one highlighted Bash match, got %+v", matches)
+	}
+
+	page, err = SearchWithFilters(database, SearchFilters{Query: `tool:Edit`})
+	if err != nil {
+		t.Fatalf("SearchWithFilters() error = %v", err)
+	}
+	matches = page.Sessions[0].Matches
+	if len(matches) != 1 || matches[0].ToolName != "Edit" || !strings.Contains(matches[0].MessageText, "/src/auth.go") {
+		t.Errorf("Expected the Edit call as the match, got %+v", matches)
+	}
+}
+
+func TestSearchWithFilters_ImportedToolOutput(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Remove(tmpfile.Name()) }()
+	_ = tmpfile.Close()
+
+	database, err := db.New(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	session, err := ccsessions.ParseFile("../../../pkg/ccsessions/testdata/tool-session.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := importer.New(database).ImportSession(session); err != nil {
+		t.Fatal(err)
+	}
+
+	// Array-form tool output is indexed once, as the Read call's output
+	page, err := SearchWithFilters(database, SearchFilters{Query: "defmodule"})
+	if err != nil {
+		t.Fatalf("SearchWithFilters() error = %v", err)
+	}
+	if len(page.Sessions) != 1 || page.Sessions[0].MatchCount != 1 {
+		t.Fatalf("Expected one session with one match, got %+v", page.Sessions)
+	}
+	if match := page.Sessions[0].Matches[0]; match.ToolName != "Read" {
+		t.Errorf("Expected the match in the Read call, got %+v", match)
+	}
+}
+
+// searchMatches runs SearchWithFilters and returns every match on the page
+func searchMatches(database *db.DB, filters SearchFilters) ([]SearchResult, error) {
+	page, err := SearchWithFilters(database, filters)
+	if err != nil {
+		return nil, err
+	}
+	var results []SearchResult
+	for _, session := range page.Sessions {
+		results = append(results, session.Matches...)
+	}
+	return results, nil
+}
@@ -0,0 +1,465 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/neilberkman/ccrider/internal/core/db"
+	"github.com/neilberkman/ccrider/internal/core/llm"
+)
+
+const (
+	embedChunkSize = 1000 // Characters per embedded chunk of a message
+	embedBatchSize = 32   // Messages embedded per call to the embedder
+
+	// semanticCandidates is how many of the most similar chunks are grouped
+	// into session results
+	semanticCandidates = 200
+
+	// hybridSemanticWeight is the share of a hybrid score that comes from
+	// semantic similarity; the rest is keyword relevance
+	hybridSemanticWeight = 0.5
+)
+
+// IndexEmbeddings embeds every message that has no embeddings from embedder
+// yet, in chunks of about embedChunkSize characters, and returns how many
+// messages it embedded. It's incremental: run it after each sync. progress,
+// if not nil, is called after each batch.
+func IndexEmbeddings(ctx context.Context, database *db.DB, embedder llm.Embedder, progress func(done, total int)) (int, error) {
+	name := embedder.Name()
+	total, err := database.CountUnembeddedMessages(name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count messages to embed: %w", err)
+	}
+
+	done := 0
+	var afterID int64
+	for done < total {
+		messages, err := database.ListUnembeddedMessages(name, afterID, embedBatchSize)
+		if err != nil {
+			return done, fmt.Errorf("failed to list messages to embed: %w", err)
+		}
+		if len(messages) == 0 {
+			break
+		}
+
+		var texts []string
+		var embeddings []db.MessageEmbedding
+		for _, m := range messages {
+			for i, c := range chunkText(m.Text, embedChunkSize) {
+				texts = append(texts, c.text)
+				embeddings = append(embeddings, db.MessageEmbedding{
+					MessageID:  m.ID,
+					ChunkIndex: i,
+					Start:      c.start,
+					Length:     c.length,
+				})
+			}
+		}
+
+		vectors, err := embedder.Embed(ctx, texts)
+		if err != nil {
+			return done, fmt.Errorf("failed to embed messages: %w", err)
+		}
+		for i := range embeddings {
+			embeddings[i].Vector = vectors[i]
+		}
+		if err := database.SaveMessageEmbeddings(name, embeddings); err != nil {
+			return done, fmt.Errorf("failed to save embeddings: %w", err)
+		}
+
+		afterID = messages[len(messages)-1].ID
+		done += len(messages)
+		if progress != nil {
+			progress(min(done, total), total)
+		}
+	}
+	return done, nil
+}
+
+// textChunk is a piece of a message's text; start and length are in runes
+type textChunk struct {
+	text          string
+	start, length int
+}
+
+// chunkText splits text into chunks of at most size runes, breaking at
+// whitespace where it can
+func chunkText(text string, size int) []textChunk {
+	runes := []rune(text)
+	var chunks []textChunk
+	for start := 0; start < len(runes); {
+		end := start + size
+		if end >= len(runes) {
+			end = len(runes)
+		} else {
+			// Break after the last whitespace in the second half of the chunk
+			for i := end; i > start+size/2; i-- {
+				if unicode.IsSpace(runes[i-1]) {
+					end = i
+					break
+				}
+			}
+		}
+		chunks = append(chunks, textChunk{text: string(runes[start:end]), start: start, length: end - start})
+		start = end
+	}
+	return chunks
+}
+
+// semanticHit is a chunk similar to the query
+type semanticHit struct {
+	messageID     int64
+	sessionID     string
+	timestamp     string
+	start, length int
+	similarity    float64
+}
+
+// SearchSemantic finds sessions whose messages mean something close to
+// filters.Query, using embeddings stored by IndexEmbeddings; messages not yet
+// embedded aren't searched. Field filters and the project, session and date
+// filters apply; boolean operators and exclusions in the query are ignored.
+//
+// With hybrid set, each session's semantic similarity is blended with its
+// keyword (bm25) relevance from SearchWithFilters, so sessions that match
+// both rank first, and keyword matches are shown where there are any.
+func SearchSemantic(ctx context.Context, database *db.DB, embedder llm.Embedder, filters SearchFilters, hybrid bool) (*SessionSearchPage, error) {
+	query := strings.TrimSpace(filters.Query)
+	if len(query) < 2 {
+		return &SessionSearchPage{}, nil
+	}
+	parsed, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	text := parsed.plainText()
+	if text == "" {
+		return nil, &QueryError{Msg: "semantic search needs words to match, not only filters"}
+	}
+
+	vectors, err := embedder.Embed(ctx, []string{text})
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+	hits, err := semanticHits(database, embedder.Name(), vectors[0], parsed, filters)
+	if err != nil {
+		return nil, err
+	}
+	sessions, hitsBySession := groupHits(hits)
+
+	var keyword *filteredQuery
+	var keywordSessions map[string]bool
+	if hybrid {
+		sessions, keyword, keywordSessions, err = blendKeywordResults(database, filters, sessions)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	page := paginate(sessions, filters.Limit, filters.Offset)
+	if err := loadKeywordMatches(database, keyword, page.Sessions, keywordSessions, filters.MatchLimit); err != nil {
+		return nil, err
+	}
+	if err := loadSemanticMatches(database, page.Sessions, hitsBySession, filters.MatchLimit); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// semanticHits scores every stored chunk within the filters against the query
+// vector and returns the semanticCandidates most similar, best first
+func semanticHits(database *db.DB, embedder string, queryVector []float32, parsed *Query, filters SearchFilters) ([]semanticHit, error) {
+	conditions := []string{"e.embedder = ?"}
+	args := []interface{}{embedder}
+
+	scope, scopeArgs, err := scopeConditions(filters)
+	if err != nil {
+		return nil, err
+	}
+	conditions = append(conditions, scope...)
+	args = append(args, scopeArgs...)
+	for _, f := range parsed.Filters {
+		condition, filterArgs, err := filterCondition(f)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, filterArgs...)
+	}
+
+	rows, err := database.Query(`
+		SELECT e.message_id, s.session_id, m.timestamp, e.chunk_start, e.chunk_length, e.vector
+		FROM message_embeddings e
+		JOIN messages m ON m.id = e.message_id
+		JOIN sessions s ON s.id = m.session_id
+		WHERE `+strings.Join(conditions, " AND "), args...)
+	if err != nil {
+		return nil, fmt.Errorf("semantic search query failed: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var hits []semanticHit
+	for rows.Next() {
+		var h semanticHit
+		var blob []byte
+		if err := rows.Scan(&h.messageID, &h.sessionID, &h.timestamp, &h.start, &h.length, &blob); err != nil {
+			return nil, fmt.Errorf("failed to scan result: %w", err)
+		}
+		vector, err := db.DecodeVector(blob)
+		if err != nil || len(vector) != len(queryVector) {
+			continue
+		}
+		// Vectors are normalized, so the dot product is cosine similarity
+		var dot float64
+		for i, v := range vector {
+			dot += float64(v) * float64(queryVector[i])
+		}
+		if dot <= 0 {
+			continue
+		}
+		h.similarity = dot
+		hits = append(hits, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating results: %w", err)
+	}
+
+	sort.Slice(hits, func(i, j int) bool { return hits[i].similarity > hits[j].similarity })
+	if len(hits) > semanticCandidates {
+		hits = hits[:semanticCandidates]
+	}
+	return hits, nil
+}
+
+// groupHits rolls hits up into sessions scored by their most similar chunk,
+// best first, and returns each session's hits
+func groupHits(hits []semanticHit) ([]SessionSearchResult, map[string][]semanticHit) {
+	bySession := make(map[string][]semanticHit)
+	var sessions []SessionSearchResult
+	index := make(map[string]int)
+	for _, h := range hits {
+		i, ok := index[h.sessionID]
+		if !ok {
+			i = len(sessions)
+			index[h.sessionID] = i
+			// Hits are best first, so the first sets the score
+			sessions = append(sessions, SessionSearchResult{SessionID: h.sessionID, Score: h.similarity})
+		}
+		session := &sessions[i]
+		// Count messages, not chunks
+		seen := false
+		for _, prev := range bySession[h.sessionID] {
+			seen = seen || prev.messageID == h.messageID
+		}
+		if !seen {
+			session.MatchCount++
+		}
+		if t, ok := parseTimestamp(h.timestamp); ok && t.Format(time.RFC3339) > session.UpdatedAt {
+			session.UpdatedAt = t.Format(time.RFC3339)
+		}
+		bySession[h.sessionID] = append(bySession[h.sessionID], h)
+	}
+	return sessions, bySession
+}
+
+// blendKeywordResults merges keyword results for the same filters into
+// semantic sessions. Both scores are scaled to 0-1 by the best of their kind
+// before blending. Only scores are blended; the returned query and the set of
+// sessions keyword search found are for loading keyword matches once the page
+// is known (see loadKeywordMatches).
+func blendKeywordResults(database *db.DB, filters SearchFilters, semantic []SessionSearchResult) ([]SessionSearchResult, *filteredQuery, map[string]bool, error) {
+	keyword, q, err := rankSessions(database, filters)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	maxSemantic, maxKeyword := 0.0, 0.0
+	for _, s := range semantic {
+		maxSemantic = max(maxSemantic, s.Score)
+	}
+	for _, s := range keyword {
+		maxKeyword = max(maxKeyword, s.Score)
+	}
+
+	blended := make(map[string]*SessionSearchResult)
+	found := make(map[string]bool)
+	var sessions []*SessionSearchResult
+	for i := range keyword {
+		s := keyword[i]
+		if maxKeyword > 0 {
+			s.Score = (1 - hybridSemanticWeight) * s.Score / maxKeyword
+		}
+		blended[s.SessionID] = &s
+		found[s.SessionID] = true
+		sessions = append(sessions, &s)
+	}
+	for _, s := range semantic {
+		score := 0.0
+		if maxSemantic > 0 {
+			score = hybridSemanticWeight * s.Score / maxSemantic
+		}
+		if existing, ok := blended[s.SessionID]; ok {
+			existing.Score += score
+			continue
+		}
+		s := s
+		s.Score = score
+		sessions = append(sessions, &s)
+	}
+
+	result := make([]SessionSearchResult, len(sessions))
+	for i, s := range sessions {
+		result[i] = *s
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.UpdatedAt > b.UpdatedAt
+	})
+	return result, q, found, nil
+}
+
+// loadKeywordMatches fills in keyword matches for the sessions keyword search
+// found (see blendKeywordResults)
+func loadKeywordMatches(database *db.DB, q *filteredQuery, sessions []SessionSearchResult, found map[string]bool, matchLimit int) error {
+	var matched []SessionSearchResult
+	for _, s := range sessions {
+		if found[s.SessionID] {
+			matched = append(matched, s)
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+	if err := loadMatches(database, q, matched, matchLimit); err != nil {
+		return err
+	}
+
+	matches := make(map[string][]SearchResult, len(matched))
+	for _, s := range matched {
+		matches[s.SessionID] = s.Matches
+	}
+	for i := range sessions {
+		if m, ok := matches[sessions[i].SessionID]; ok {
+			sessions[i].Matches = m
+		}
+	}
+	return nil
+}
+
+// loadSemanticMatches fills in session details and the most similar chunks
+// as matches for sessions that don't have matches yet
+func loadSemanticMatches(database *db.DB, sessions []SessionSearchResult, hitsBySession map[string][]semanticHit, matchLimit int) error {
+	var ids []interface{}
+	var placeholders []string
+	for _, s := range sessions {
+		if s.Matches != nil {
+			continue
+		}
+		hits := hitsBySession[s.SessionID]
+		if matchLimit > 0 && len(hits) > matchLimit {
+			hits = hits[:matchLimit]
+		}
+		for _, h := range hits {
+			ids = append(ids, h.messageID)
+			placeholders = append(placeholders, "?")
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	type message struct {
+		uuid, text, timestamp, summary, projectPath string
+	}
+	rows, err := database.Query(`
+		SELECT
+			m.id,
+			m.uuid,
+			m.text_content,
+			m.timestamp,
+			COALESCE(ss.one_line_summary, s.llm_summary, s.summary, ''),
+			s.project_path
+		FROM messages m
+		JOIN sessions s ON s.id = m.session_id
+		LEFT JOIN session_summaries ss ON s.id = ss.session_id
+		WHERE m.id IN (`+strings.Join(placeholders, ", ")+`)
+	`, ids...)
+	if err != nil {
+		return fmt.Errorf("semantic search query failed: %w", err)
+	}
+	messages := make(map[int64]message)
+	for rows.Next() {
+		var id int64
+		var m message
+		if err := rows.Scan(&id, &m.uuid, &m.text, &m.timestamp, &m.summary, &m.projectPath); err != nil {
+			_ = rows.Close()
+			return fmt.Errorf("failed to scan result: %w", err)
+		}
+		messages[id] = m
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return fmt.Errorf("error iterating results: %w", err)
+	}
+	_ = rows.Close()
+
+	for i := range sessions {
+		session := &sessions[i]
+		if session.Matches != nil {
+			continue
+		}
+		session.Matches = []SearchResult{}
+		shown := make(map[int64]bool)
+		for _, h := range hitsBySession[session.SessionID] {
+			m, ok := messages[h.messageID]
+			if !ok || shown[h.messageID] {
+				continue
+			}
+			if matchLimit > 0 && len(session.Matches) >= matchLimit {
+				break
+			}
+			shown[h.messageID] = true
+			session.SessionSummary = m.summary
+			session.ProjectPath = m.projectPath
+			session.Matches = append(session.Matches, SearchResult{
+				MessageUUID:    m.uuid,
+				SessionID:      session.SessionID,
+				SessionSummary: m.summary,
+				MessageText:    chunkSnippet(m.text, h.start, h.length, 200),
+				Timestamp:      m.timestamp,
+				ProjectPath:    m.projectPath,
+			})
+		}
+	}
+	return nil
+}
+
+// chunkSnippet returns up to maxLen characters from the start of a chunk of
+// text, on one line
+func chunkSnippet(text string, start, length, maxLen int) string {
+	runes := []rune(text)
+	if start > len(runes) {
+		start = len(runes)
+	}
+	end := min(start+length, len(runes))
+	truncated := end-start > maxLen
+	if truncated {
+		end = start + maxLen
+	}
+	snippet := strings.Join(strings.Fields(string(runes[start:end])), " ")
+	if start > 0 {
+		snippet = "..." + snippet
+	}
+	if truncated || end < len(runes) {
+		snippet += "..."
+	}
+	return snippet
+}
@@ -0,0 +1,168 @@
+package search
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/neilberkman/ccrider/internal/core/db"
+	"github.com/neilberkman/ccrider/internal/core/llm"
+)
+
+func TestChunkText(t *testing.T) {
+	text := strings.Repeat("word ", 50) // 250 characters
+	chunks := chunkText(text, 100)
+	if len(chunks) != 3 {
+		t.Fatalf("Expected 3 chunks, got %d", len(chunks))
+	}
+	var rebuilt string
+	for i, c := range chunks {
+		if c.length > 100 {
+			t.Errorf("Chunk %d is %d characters", i, c.length)
+		}
+		if i > 0 && !strings.HasSuffix(chunks[i-1].text, " ") {
+			t.Errorf("Chunk %d doesn't break at whitespace: %q", i-1, chunks[i-1].text)
+		}
+		rebuilt += c.text
+	}
+	if rebuilt != text {
+		t.Error("Chunks don't cover the text")
+	}
+
+	// Offsets count characters, for substr()
+	chunks = chunkText("héllo wörld", 6)
+	if chunks[1].start != 6 || chunks[1].text != "wörld" {
+		t.Errorf("Expected second chunk at character 6, got %+v", chunks[1])
+	}
+}
+
+func TestSearchSemantic(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Remove(tmpfile.Name()) }()
+	_ = tmpfile.Close()
+
+	database, err := db.New(tmpfile.Name())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	sessions := []struct {
+		id       string
+		messages []string
+	}{
+		{"deploy", []string{"The production deployment failed halfway", "Rolled back the deploy"}},
+		{"cake", []string{"Chocolate cake recipe with buttercream frosting"}},
+		{"exact", []string{"deploying to production tonight", "checklist ready"}},
+	}
+	for _, s := range sessions {
+		result, err := database.Exec(`
+			INSERT INTO sessions (session_id, project_path, summary, created_at, updated_at)
+			VALUES (?, '/test', '', '2025-01-01 10:00:00', '2025-01-01 10:00:00')
+		`, s.id)
+		if err != nil {
+			t.Fatalf("Failed to insert session: %v", err)
+		}
+		id, _ := result.LastInsertId()
+		for i, text := range s.messages {
+			_, err := database.Exec(`
+				INSERT INTO messages (uuid, session_id, type, text_content, timestamp, sequence)
+				VALUES (?, ?, 'user', ?, '2025-01-01 10:00:00', ?)
+			`, s.id+"-"+string(rune('a'+i)), id, text, i+1)
+			if err != nil {
+				t.Fatalf("Failed to insert message: %v", err)
+			}
+		}
+	}
+
+	ctx := context.Background()
+	embedder := llm.NewHashEmbedder(llm.DefaultHashDimensions)
+	n, err := IndexEmbeddings(ctx, database, embedder, nil)
+	if err != nil {
+		t.Fatalf("IndexEmbeddings() error = %v", err)
+	}
+	if n != 5 {
+		t.Errorf("Expected 5 messages embedded, got %d", n)
+	}
+	// Incremental: nothing left to do
+	if n, _ := IndexEmbeddings(ctx, database, embedder, nil); n != 0 {
+		t.Errorf("Expected nothing to embed the second time, got %d", n)
+	}
+
+	// "deploying" and "production" only match the exact session as keywords,
+	// but the deploy session is about the same thing
+	page, err := SearchSemantic(ctx, database, embedder, SearchFilters{Query: "deploying production"}, false)
+	if err != nil {
+		t.Fatalf("SearchSemantic() error = %v", err)
+	}
+	ids := sessionIDs(page.Sessions)
+	if len(ids) < 2 || !containsAll(ids[:2], "deploy", "exact") {
+		t.Fatalf("Expected deploy and exact first, got %v", ids)
+	}
+	for _, s := range page.Sessions {
+		if s.SessionID == "cake" && s.Score >= page.Sessions[1].Score {
+			t.Errorf("Expected cake to rank below both, got %v", ids)
+		}
+		if len(s.Matches) == 0 || s.ProjectPath != "/test" {
+			t.Errorf("Expected matches and session details for %s, got %+v", s.SessionID, s)
+		}
+	}
+
+	// Hybrid ranks the keyword match first and keeps its highlighted snippet
+	page, err = SearchSemantic(ctx, database, embedder, SearchFilters{Query: "deploying production", Limit: 1}, true)
+	if err != nil {
+		t.Fatalf("SearchSemantic(hybrid) error = %v", err)
+	}
+	if len(page.Sessions) != 1 || page.Sessions[0].SessionID != "exact" || page.NextOffset != 1 {
+		t.Fatalf("Expected exact first of several, got %+v", page)
+	}
+	if !strings.Contains(page.Sessions[0].Matches[0].MessageText, HighlightStart) {
+		t.Errorf("Expected a highlighted keyword snippet, got %q", page.Sessions[0].Matches[0].MessageText)
+	}
+
+	// Filters apply
+	page, err = SearchSemantic(ctx, database, embedder, SearchFilters{Query: "deploying production", CurrentSessionID: "cake"}, false)
+	if err != nil {
+		t.Fatalf("SearchSemantic() error = %v", err)
+	}
+	if ids := sessionIDs(page.Sessions); len(ids) > 1 || (len(ids) == 1 && ids[0] != "cake") {
+		t.Errorf("Expected at most the cake session, got %v", ids)
+	}
+
+	// Embeddings go with their message
+	if _, err := database.Exec(`DELETE FROM messages WHERE uuid = 'cake-a'`); err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	if err := database.QueryRow(`SELECT COUNT(*) FROM message_embeddings`).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 4 {
+		t.Errorf("Expected embeddings to be deleted with their message, got %d left", count)
+	}
+}
+
+func sessionIDs(sessions []SessionSearchResult) []string {
+	var ids []string
+	for _, s := range sessions {
+		ids = append(ids, s.SessionID)
+	}
+	return ids
+}
+
+func containsAll(ids []string, want ...string) bool {
+	for _, w := range want {
+		found := false
+		for _, id := range ids {
+			found = found || id == w
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,64 @@
+package usage
+
+import (
+	"strings"
+
+	"github.com/neilberkman/ccrider/internal/core/config"
+	"github.com/neilberkman/ccrider/internal/core/db"
+)
+
+// Pricing maps model ID prefixes to prices. The longest matching prefix wins,
+// so "claude-opus-4-5" can be priced differently from "claude-opus-4".
+type Pricing map[string]config.ModelPrice
+
+// DefaultPricing returns Anthropic's list prices (USD per million tokens,
+// cache writes at the 5-minute rate)
+func DefaultPricing() Pricing {
+	return Pricing{
+		"claude-opus-4-5":   {Input: 5, Output: 25, CacheRead: 0.50, CacheWrite: 6.25},
+		"claude-opus-4":     {Input: 15, Output: 75, CacheRead: 1.50, CacheWrite: 18.75},
+		"claude-sonnet-4":   {Input: 3, Output: 15, CacheRead: 0.30, CacheWrite: 3.75},
+		"claude-haiku-4-5":  {Input: 1, Output: 5, CacheRead: 0.10, CacheWrite: 1.25},
+		"claude-3-opus":     {Input: 15, Output: 75, CacheRead: 1.50, CacheWrite: 18.75},
+		"claude-3-7-sonnet": {Input: 3, Output: 15, CacheRead: 0.30, CacheWrite: 3.75},
+		"claude-3-5-sonnet": {Input: 3, Output: 15, CacheRead: 0.30, CacheWrite: 3.75},
+		"claude-3-5-haiku":  {Input: 0.80, Output: 4, CacheRead: 0.08, CacheWrite: 1},
+	}
+}
+
+// NewPricing returns the default prices with overrides (e.g. from
+// config.toml) applied on top
+func NewPricing(overrides map[string]config.ModelPrice) Pricing {
+	pricing := DefaultPricing()
+	for model, price := range overrides {
+		pricing[model] = price
+	}
+	return pricing
+}
+
+// Lookup returns the price for a model ID, matching by longest prefix
+func (p Pricing) Lookup(model string) (config.ModelPrice, bool) {
+	var best string
+	found := false
+	for prefix := range p {
+		if strings.HasPrefix(model, prefix) && (!found || len(prefix) > len(best)) {
+			best = prefix
+			found = true
+		}
+	}
+	return p[best], found
+}
+
+// Cost returns the estimated cost in USD of usage on a model, and whether
+// the model has a price at all
+func (p Pricing) Cost(model string, u db.TokenUsage) (float64, bool) {
+	price, ok := p.Lookup(model)
+	if !ok {
+		return 0, false
+	}
+	cost := float64(u.InputTokens)*price.Input +
+		float64(u.OutputTokens)*price.Output +
+		float64(u.CacheReadTokens)*price.CacheRead +
+		float64(u.CacheCreationTokens)*price.CacheWrite
+	return cost / 1_000_000, true
+}
@@ -0,0 +1,184 @@
+package usage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/neilberkman/ccrider/internal/core/db"
+)
+
+// GroupBy selects how usage is grouped into report rows
+type GroupBy string
+
+const (
+	ByDay     GroupBy = "day"
+	ByWeek    GroupBy = "week"
+	ByMonth   GroupBy = "month"
+	ByProject GroupBy = "project"
+	ByBranch  GroupBy = "branch"
+	ByModel   GroupBy = "model"
+)
+
+// GroupByValues lists the valid groupings, for help text and validation
+var GroupByValues = []GroupBy{ByDay, ByWeek, ByMonth, ByProject, ByBranch, ByModel}
+
+// ParseGroupBy validates a comma-separated list of groupings, e.g.
+// "month,project" for one row per project per month
+func ParseGroupBy(s string) ([]GroupBy, error) {
+	var groupBy []GroupBy
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		valid := false
+		for _, g := range GroupByValues {
+			if string(g) == name {
+				groupBy = append(groupBy, g)
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid grouping %q (want one of %v)", name, GroupByValues)
+		}
+	}
+	return groupBy, nil
+}
+
+func (g GroupBy) isTime() bool {
+	return g == ByDay || g == ByWeek || g == ByMonth
+}
+
+// Filters defines what goes into a usage report
+type Filters struct {
+	GroupBy     []GroupBy // One key per grouping (defaults to day)
+	ProjectPath string    // Filter by project path (substring match)
+	Since       time.Time // Only usage at or after this time (zero for no limit)
+	Until       time.Time // Only usage before this time (zero for no limit)
+}
+
+// Row is the usage for one group
+type Row struct {
+	Keys     []string // Group values, in the order of Filters.GroupBy
+	Sessions int
+	Messages int // Assistant responses
+	Usage    db.TokenUsage
+	Cost     float64 // Estimated USD; excludes models without a price
+}
+
+// Report is usage grouped into rows, with a total across all of them
+type Report struct {
+	GroupBy        []GroupBy
+	Rows           []Row
+	Total          Row
+	UnpricedModels []string // Models with usage but no price (not included in Cost)
+}
+
+// BuildReport groups recorded token usage and estimates its cost
+func BuildReport(database *db.DB, filters Filters, pricing Pricing) (*Report, error) {
+	if len(filters.GroupBy) == 0 {
+		filters.GroupBy = []GroupBy{ByDay}
+	}
+
+	messages, err := database.ListMessageUsage(filters.ProjectPath, filters.Since, filters.Until)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{GroupBy: filters.GroupBy}
+	rows := make(map[string]*Row)
+	sessions := make(map[string]map[string]bool)
+	allSessions := make(map[string]bool)
+	unpriced := make(map[string]bool)
+
+	for _, m := range messages {
+		keys := make([]string, len(filters.GroupBy))
+		for i, g := range filters.GroupBy {
+			keys[i] = groupKey(g, m)
+		}
+		key := strings.Join(keys, "\x00")
+		row, ok := rows[key]
+		if !ok {
+			row = &Row{Keys: keys}
+			rows[key] = row
+			sessions[key] = make(map[string]bool)
+		}
+
+		cost, priced := pricing.Cost(m.Model, m.Usage)
+		if !priced {
+			unpriced[modelName(m.Model)] = true
+		}
+
+		row.Messages++
+		row.Usage = row.Usage.Add(m.Usage)
+		row.Cost += cost
+		sessions[key][m.SessionID] = true
+
+		report.Total.Messages++
+		report.Total.Usage = report.Total.Usage.Add(m.Usage)
+		report.Total.Cost += cost
+		allSessions[m.SessionID] = true
+	}
+
+	for key, row := range rows {
+		row.Sessions = len(sessions[key])
+		report.Rows = append(report.Rows, *row)
+	}
+	report.Total.Sessions = len(allSessions)
+
+	// Chronological when grouped by time, otherwise most expensive first
+	chronological := false
+	for _, g := range filters.GroupBy {
+		chronological = chronological || g.isTime()
+	}
+	sort.Slice(report.Rows, func(i, j int) bool {
+		a, b := report.Rows[i], report.Rows[j]
+		if !chronological && a.Cost != b.Cost {
+			return a.Cost > b.Cost
+		}
+		for k := range a.Keys {
+			if a.Keys[k] != b.Keys[k] {
+				return a.Keys[k] < b.Keys[k]
+			}
+		}
+		return false
+	})
+
+	for model := range unpriced {
+		report.UnpricedModels = append(report.UnpricedModels, model)
+	}
+	sort.Strings(report.UnpricedModels)
+
+	return report, nil
+}
+
+// groupKey returns the row a message's usage belongs to. Dates are in local
+// time; weeks start on Monday and are keyed by that day.
+func groupKey(groupBy GroupBy, m db.MessageUsage) string {
+	t := m.Timestamp.Local()
+	switch groupBy {
+	case ByWeek:
+		offset := (int(t.Weekday()) + 6) % 7 // Days since Monday
+		return t.AddDate(0, 0, -offset).Format("2006-01-02")
+	case ByMonth:
+		return t.Format("2006-01")
+	case ByProject:
+		return m.ProjectPath
+	case ByBranch:
+		if m.GitBranch == "" {
+			return "(none)"
+		}
+		return m.GitBranch
+	case ByModel:
+		return modelName(m.Model)
+	default:
+		return t.Format("2006-01-02")
+	}
+}
+
+func modelName(model string) string {
+	if model == "" {
+		return "(unknown)"
+	}
+	return model
+}
@@ -0,0 +1,164 @@
+package usage
+
+import (
+	"math"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/ccrider/internal/core/config"
+	"github.com/neilberkman/ccrider/internal/core/db"
+	"github.com/neilberkman/ccrider/internal/core/importer"
+	"github.com/neilberkman/ccrider/pkg/ccsessions"
+)
+
+func setupTestDB(t *testing.T) *db.DB {
+	t.Helper()
+
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.Remove(tmpfile.Name()) })
+	_ = tmpfile.Close()
+
+	database, err := db.New(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = database.Close() })
+
+	session, err := ccsessions.ParseFile("../../../pkg/ccsessions/testdata/usage-session.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := importer.New(database).ImportSession(session); err != nil {
+		t.Fatal(err)
+	}
+
+	return database
+}
+
+func TestPricing_Lookup(t *testing.T) {
+	pricing := NewPricing(map[string]config.ModelPrice{
+		"claude-sonnet-4-5": {Input: 1},
+	})
+
+	tests := []struct {
+		model string
+		input float64
+		found bool
+	}{
+		{"claude-opus-4-1-20250805", 15, true},
+		{"claude-opus-4-5-20251101", 5, true},   // Longer prefix wins
+		{"claude-sonnet-4-5-20250929", 1, true}, // Override from config
+		{"claude-sonnet-4-20250514", 3, true},
+		{"gpt-4o", 0, false},
+	}
+	for _, tt := range tests {
+		price, found := pricing.Lookup(tt.model)
+		if found != tt.found || price.Input != tt.input {
+			t.Errorf("Lookup(%q) = %v/%v, want %v/%v", tt.model, price.Input, found, tt.input, tt.found)
+		}
+	}
+}
+
+func TestBuildReport_ByModel(t *testing.T) {
+	database := setupTestDB(t)
+
+	report, err := BuildReport(database, Filters{GroupBy: []GroupBy{ByModel}}, DefaultPricing())
+	if err != nil {
+		t.Fatalf("BuildReport() error = %v", err)
+	}
+
+	if len(report.Rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(report.Rows))
+	}
+
+	// Most expensive first
+	opus, sonnet := report.Rows[0], report.Rows[1]
+	if opus.Keys[0] != "claude-opus-4-1-20250805" || sonnet.Keys[0] != "claude-sonnet-4-5-20250929" {
+		t.Fatalf("Rows = %v, %v", opus.Keys, sonnet.Keys)
+	}
+	if math.Abs(opus.Cost-0.003225) > 1e-9 {
+		t.Errorf("Opus cost = %v, want 0.003225", opus.Cost)
+	}
+	// The split response counts once
+	if sonnet.Messages != 1 || sonnet.Usage.CacheReadTokens != 1000 {
+		t.Errorf("Sonnet row = %+v, want one response with 1000 cache read tokens", sonnet)
+	}
+	if math.Abs(sonnet.Cost-0.001155) > 1e-9 {
+		t.Errorf("Sonnet cost = %v, want 0.001155", sonnet.Cost)
+	}
+
+	if report.Total.Sessions != 1 || report.Total.Usage.Total() != opus.Usage.Total()+sonnet.Usage.Total() {
+		t.Errorf("Total = %+v", report.Total)
+	}
+	if len(report.UnpricedModels) != 0 {
+		t.Errorf("Expected no unpriced models, got %v", report.UnpricedModels)
+	}
+}
+
+func TestBuildReport_Filters(t *testing.T) {
+	database := setupTestDB(t)
+
+	// Both responses are on 2025-11-08 (UTC)
+	report, err := BuildReport(database, Filters{
+		GroupBy: []GroupBy{ByMonth, ByProject},
+		Since:   time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC),
+	}, Pricing{})
+	if err != nil {
+		t.Fatalf("BuildReport() error = %v", err)
+	}
+	if len(report.Rows) != 1 || len(report.Rows[0].Keys) != 2 {
+		t.Fatalf("Expected 1 row with 2 keys, got %+v", report.Rows)
+	}
+	if report.Rows[0].Cost != 0 || len(report.UnpricedModels) != 2 {
+		t.Errorf("Expected no cost and 2 unpriced models without prices, got %v and %v", report.Rows[0].Cost, report.UnpricedModels)
+	}
+
+	report, err = BuildReport(database, Filters{
+		Until: time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC),
+	}, DefaultPricing())
+	if err != nil {
+		t.Fatalf("BuildReport() error = %v", err)
+	}
+	if len(report.Rows) != 0 {
+		t.Errorf("Expected no rows before November, got %d", len(report.Rows))
+	}
+
+	// Bounds within the day: only the later (Opus) response
+	report, err = BuildReport(database, Filters{
+		GroupBy: []GroupBy{ByModel},
+		Since:   time.Date(2025, 11, 8, 12, 0, 3, 0, time.UTC),
+		Until:   time.Date(2025, 11, 8, 13, 0, 0, 0, time.UTC),
+	}, DefaultPricing())
+	if err != nil {
+		t.Fatalf("BuildReport() error = %v", err)
+	}
+	if len(report.Rows) != 1 || report.Rows[0].Keys[0] != "claude-opus-4-1-20250805" || report.Total.Messages != 1 {
+		t.Errorf("Expected only the Opus response, got %+v", report.Rows)
+	}
+
+	report, err = BuildReport(database, Filters{ProjectPath: "no-such-project"}, DefaultPricing())
+	if err != nil {
+		t.Fatalf("BuildReport() error = %v", err)
+	}
+	if len(report.Rows) != 0 {
+		t.Errorf("Expected no rows for another project, got %d", len(report.Rows))
+	}
+}
+
+func TestParseGroupBy(t *testing.T) {
+	groupBy, err := ParseGroupBy("month, project")
+	if err != nil {
+		t.Fatalf("ParseGroupBy() error = %v", err)
+	}
+	if len(groupBy) != 2 || groupBy[0] != ByMonth || groupBy[1] != ByProject {
+		t.Errorf("ParseGroupBy() = %v", groupBy)
+	}
+
+	if _, err := ParseGroupBy("year"); err == nil {
+		t.Error("Expected error for unknown grouping")
+	}
+}
@@ -0,0 +1,85 @@
+package watcher
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ErrAlreadyRunning is returned by AcquireLock when another live watcher
+// holds the lock for the same database
+var ErrAlreadyRunning = errors.New("a watcher is already running for this database")
+
+// Lock is the lock file a running watcher holds next to the database. Other
+// processes (the TUI, the MCP server) check it with IsRunning and skip their
+// own sync while a watcher keeps the database current.
+type Lock struct {
+	path string
+}
+
+// LockPath returns the watcher lock file path for a database
+func LockPath(dbPath string) string {
+	return dbPath + ".watch.lock"
+}
+
+// AcquireLock takes the watcher lock for dbPath, recording this process's PID.
+// A lock left behind by a process that no longer exists is replaced.
+func AcquireLock(dbPath string) (*Lock, error) {
+	path := LockPath(dbPath)
+
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			_, werr := fmt.Fprintf(file, "%d\n", os.Getpid())
+			cerr := file.Close()
+			if werr != nil || cerr != nil {
+				_ = os.Remove(path)
+				return nil, fmt.Errorf("failed to write lock file: %w", errors.Join(werr, cerr))
+			}
+			return &Lock{path: path}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		if pid, ok := readLockPID(path); ok && processAlive(pid) {
+			return nil, ErrAlreadyRunning
+		}
+
+		// Stale lock from a watcher that didn't shut down cleanly
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to remove stale lock file: %w", err)
+		}
+	}
+
+	return nil, ErrAlreadyRunning
+}
+
+// Release removes the lock file
+func (l *Lock) Release() error {
+	if err := os.Remove(l.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove lock file: %w", err)
+	}
+	return nil
+}
+
+// IsRunning reports whether a live watcher holds the lock for dbPath
+func IsRunning(dbPath string) bool {
+	pid, ok := readLockPID(LockPath(dbPath))
+	return ok && processAlive(pid)
+}
+
+// readLockPID reads the PID recorded in a lock file
+func readLockPID(path string) (int, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, false
+	}
+	return pid, true
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package watcher
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// processAlive checks whether a process exists by sending it signal 0
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	err = process.Signal(syscall.Signal(0))
+	// EPERM means the process exists but belongs to another user
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
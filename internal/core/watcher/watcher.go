@@ -0,0 +1,147 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/neilberkman/ccrider/internal/core/importer"
+)
+
+// DefaultDelay is how long the watcher collects file events before importing,
+// so a burst of writes to one session becomes a single incremental import
+const DefaultDelay = 300 * time.Millisecond
+
+// Watcher imports session files as Claude Code writes them
+type Watcher struct {
+	imp   *importer.Importer
+	root  string
+	delay time.Duration
+
+	// OnImport, if set, is called after each batch of changed files is imported
+	// (interface concern - lets the CLI report activity while core stays silent)
+	OnImport func(files []string, err error)
+}
+
+// New creates a watcher that imports changed .jsonl files under root
+func New(imp *importer.Importer, root string) *Watcher {
+	return &Watcher{
+		imp:   imp,
+		root:  root,
+		delay: DefaultDelay,
+	}
+}
+
+// Run watches until ctx is cancelled. Changed files are imported at most
+// delay after their first write event.
+func (w *Watcher) Run(ctx context.Context) error {
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	defer func() {
+		_ = fsw.Close()
+	}()
+
+	// fsnotify doesn't watch recursively - add every project directory
+	if err := w.addTree(fsw, w.root, nil); err != nil {
+		return err
+	}
+
+	pending := make(map[string]bool)
+	timer := time.NewTimer(0)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	timerRunning := false
+
+	queue := func(path string) {
+		pending[path] = true
+		// Not reset on later events: the batch goes out at most delay after
+		// the first change, even while a session is being written continuously
+		if !timerRunning {
+			timer.Reset(w.delay)
+			timerRunning = true
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+
+		case event, ok := <-fsw.Events:
+			if !ok {
+				return nil
+			}
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+				continue
+			}
+
+			info, err := os.Stat(event.Name)
+			if err != nil {
+				continue
+			}
+			if info.IsDir() {
+				// New project directory - watch it, and import anything
+				// written before the watch was in place
+				if err := w.addTree(fsw, event.Name, queue); err != nil {
+					w.report(nil, err)
+				}
+				continue
+			}
+			if filepath.Ext(event.Name) == ".jsonl" {
+				queue(event.Name)
+			}
+
+		case err, ok := <-fsw.Errors:
+			if !ok {
+				return nil
+			}
+			w.report(nil, fmt.Errorf("file watcher error: %w", err))
+
+		case <-timer.C:
+			timerRunning = false
+			files := make([]string, 0, len(pending))
+			for path := range pending {
+				files = append(files, path)
+			}
+			sort.Strings(files)
+			pending = make(map[string]bool)
+
+			w.report(files, w.imp.ImportFiles(files, nil))
+		}
+	}
+}
+
+// addTree watches dir and its subdirectories. If found is non-nil it is
+// called with each .jsonl file already present.
+func (w *Watcher) addTree(fsw *fsnotify.Watcher, dir string, found func(path string)) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if err := fsw.Add(path); err != nil {
+				return fmt.Errorf("failed to watch %s: %w", path, err)
+			}
+			return nil
+		}
+		if found != nil && filepath.Ext(path) == ".jsonl" {
+			found(path)
+		}
+		return nil
+	})
+}
+
+func (w *Watcher) report(files []string, err error) {
+	if w.OnImport != nil {
+		w.OnImport(files, err)
+	}
+}
@@ -0,0 +1,130 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/neilberkman/ccrider/internal/core/db"
+	"github.com/neilberkman/ccrider/internal/core/importer"
+)
+
+func TestLock(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "sessions.db")
+
+	if IsRunning(dbPath) {
+		t.Fatal("IsRunning() = true before any watcher started")
+	}
+
+	lock, err := AcquireLock(dbPath)
+	if err != nil {
+		t.Fatalf("AcquireLock() error = %v", err)
+	}
+	if !IsRunning(dbPath) {
+		t.Error("IsRunning() = false while the lock is held")
+	}
+
+	if _, err := AcquireLock(dbPath); !errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("second AcquireLock() error = %v, want ErrAlreadyRunning", err)
+	}
+
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	if IsRunning(dbPath) {
+		t.Error("IsRunning() = true after Release()")
+	}
+}
+
+func TestLock_Stale(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "sessions.db")
+
+	// A lock left by a process that is gone (PIDs this large aren't in use)
+	if err := os.WriteFile(LockPath(dbPath), []byte("999999999\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if IsRunning(dbPath) {
+		t.Error("IsRunning() = true for a stale lock")
+	}
+
+	lock, err := AcquireLock(dbPath)
+	if err != nil {
+		t.Fatalf("AcquireLock() over stale lock error = %v", err)
+	}
+	_ = lock.Release()
+}
+
+func TestWatcher_ImportsNewSessions(t *testing.T) {
+	// Setup test database
+	tmpfile, err := os.CreateTemp("", "test-*.db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = os.Remove(tmpfile.Name())
+	}()
+	_ = tmpfile.Close()
+
+	database, err := db.New(tmpfile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = database.Close()
+	}()
+
+	root := t.TempDir()
+	w := New(importer.New(database), root)
+	imported := make(chan []string, 10)
+	w.OnImport = func(files []string, err error) {
+		if err != nil {
+			t.Errorf("import error: %v", err)
+			return
+		}
+		imported <- files
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	// Give the watcher a moment to register the root directory
+	time.Sleep(100 * time.Millisecond)
+
+	data, err := os.ReadFile("../../../pkg/ccsessions/testdata/sample.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A session written into a project directory created after the watch started
+	projectDir := filepath.Join(root, "-Users-test-project")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(projectDir, "session-123.jsonl"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-imported:
+	case <-time.After(5 * time.Second):
+		t.Fatal("watcher did not import the new session")
+	}
+
+	var count int
+	if err := database.QueryRow("SELECT COUNT(*) FROM sessions WHERE session_id = 'session-123'").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("Expected the session to be imported, got %d sessions", count)
+	}
+}
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/neilberkman/ccrider/internal/core/db"
+	"github.com/spf13/cobra"
+)
+
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Database maintenance commands",
+}
+
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply pending schema migrations",
+	Long: `Bring the database schema up to date with this version of ccrider.
+
+Migrations also run automatically whenever ccrider opens the database; use
+--status to see where a database stands, or --dry-run to check that pending
+migrations apply cleanly without changing anything.`,
+	Args: cobra.NoArgs,
+	RunE: runDBMigrate,
+}
+
+var (
+	migrateStatus bool
+	migrateDryRun bool
+)
+
+func init() {
+	dbMigrateCmd.Flags().BoolVar(&migrateStatus, "status", false, "Show the schema version and pending migrations")
+	dbMigrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "Run pending migrations and roll them back")
+	dbCmd.AddCommand(dbMigrateCmd)
+	rootCmd.AddCommand(dbCmd)
+}
+
+func runDBMigrate(cmd *cobra.Command, args []string) error {
+	// Open without migrating so the current state can be inspected
+	database, err := db.Open(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		_ = database.Close()
+	}()
+
+	status, err := database.Status()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Database: %s\n", dbPath)
+	fmt.Printf("Schema version: %d (this ccrider supports up to %d)\n", status.CurrentVersion, status.LatestVersion)
+
+	if status.CurrentVersion > status.LatestVersion {
+		return fmt.Errorf("%w - upgrade ccrider to use this database", db.ErrSchemaTooNew)
+	}
+
+	if len(status.Pending) == 0 {
+		if status.CurrentVersion == status.LatestVersion {
+			fmt.Println("Up to date")
+			return nil
+		}
+
+		// New database - the schema is created at the latest version in one step
+		fmt.Printf("New database: schema will be created at version %d\n", status.LatestVersion)
+		if migrateStatus || migrateDryRun {
+			return nil
+		}
+		return database.Migrate()
+	}
+
+	fmt.Printf("\nPending migrations:\n")
+	for _, m := range status.Pending {
+		fmt.Printf("  %3d  %s\n", m.Version, m.Description)
+	}
+
+	switch {
+	case migrateStatus:
+		return nil
+	case migrateDryRun:
+		if err := database.DryRunMigrate(); err != nil {
+			return err
+		}
+		fmt.Printf("\nAll %d pending migration(s) apply cleanly (rolled back, no changes made)\n", len(status.Pending))
+		return nil
+	default:
+		if err := database.Migrate(); err != nil {
+			return err
+		}
+		fmt.Printf("\nApplied %d migration(s)\n", len(status.Pending))
+		return nil
+	}
+}
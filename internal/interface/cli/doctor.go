@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/neilberkman/ccrider/internal/core/db"
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Show session files with damaged lines",
+	Long: `List session files that were only partially imported because some of
+their lines could not be parsed (for example, a line truncated when Claude Code
+crashed mid-write), with the line number, byte offset and error for each.`,
+	Args: cobra.NoArgs,
+	RunE: runDoctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	// Open database
+	database, err := db.New(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		_ = database.Close()
+	}()
+
+	// Use core function to get diagnostics
+	diagnostics, err := database.ListImportDiagnostics()
+	if err != nil {
+		return fmt.Errorf("failed to get import diagnostics: %w", err)
+	}
+
+	// Interface concern: Format and display diagnostics
+	if len(diagnostics) == 0 {
+		fmt.Println("No damaged session files found")
+		return nil
+	}
+
+	files := 0
+	for i, d := range diagnostics {
+		if i == 0 || d.FilePath != diagnostics[i-1].FilePath {
+			files++
+			if i > 0 {
+				fmt.Println()
+			}
+			missing := ""
+			if _, err := os.Stat(d.FilePath); os.IsNotExist(err) {
+				missing = " (file no longer exists)"
+			}
+			fmt.Printf("%s%s\n", d.FilePath, missing)
+		}
+		fmt.Printf("  line %d (byte %d): %s\n", d.Line, d.ByteOffset, d.Error)
+	}
+
+	fmt.Printf("\n%d skipped line(s) in %d file(s)\n", len(diagnostics), files)
+	return nil
+}
@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/neilberkman/ccrider/internal/core/config"
+	"github.com/neilberkman/ccrider/internal/core/db"
+	"github.com/neilberkman/ccrider/internal/core/llm"
+	"github.com/neilberkman/ccrider/internal/core/search"
+	"github.com/spf13/cobra"
+)
+
+var (
+	embedRebuild  bool
+	embedProvider string
+	embedModel    string
+)
+
+var embedCmd = &cobra.Command{
+	Use:   "embed",
+	Short: "Build embeddings for semantic search",
+	Long: `Embed message text for semantic search (ccrider search --semantic).
+
+Only messages without embeddings are processed, so run it after each sync;
+search --semantic and --hybrid also embed new messages before searching.
+
+The default embedder hashes words and word fragments into vectors: it needs
+no model or network, and matches related wording ("deploying" and
+"deployment") that keyword search misses. For embeddings that also match
+synonyms, run a local Ollama embedding model and configure it in
+~/.config/ccrider/config.toml:
+
+  [embeddings]
+  provider = "ollama"
+  model = "nomic-embed-text"          # ollama pull nomic-embed-text
+  url = "http://localhost:11434"
+
+Embeddings from each embedder are stored separately, so switching back and
+forth doesn't discard any.
+
+Examples:
+  ccrider embed
+  ccrider embed --provider ollama --model mxbai-embed-large
+  ccrider embed --rebuild`,
+	RunE: runEmbed,
+}
+
+func init() {
+	embedCmd.Flags().BoolVar(&embedRebuild, "rebuild", false, "Delete this embedder's embeddings and embed everything again")
+	embedCmd.Flags().StringVar(&embedProvider, "provider", "", "Embeddings provider: hash or ollama (default from config, else hash)")
+	embedCmd.Flags().StringVar(&embedModel, "model", "", "Ollama embedding model (default from config, else nomic-embed-text)")
+
+	rootCmd.AddCommand(embedCmd)
+}
+
+func runEmbed(cmd *cobra.Command, args []string) error {
+	database, err := db.New(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() { _ = database.Close() }()
+
+	embedder, err := loadEmbedder(embedProvider, embedModel)
+	if err != nil {
+		return err
+	}
+
+	if embedRebuild {
+		if err := database.DeleteEmbeddings(embedder.Name()); err != nil {
+			return fmt.Errorf("failed to delete embeddings: %w", err)
+		}
+	}
+
+	fmt.Printf("Embedding messages with %s...\n", embedder.Name())
+	n, err := embedPending(context.Background(), database, embedder)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Embedded %d message(s)\n", n)
+	return nil
+}
+
+// loadEmbedder creates the configured embedder, with provider and model
+// overriding the config when set
+func loadEmbedder(provider, model string) (llm.Embedder, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	embeddings := cfg.Embeddings
+	if provider != "" {
+		embeddings.Provider = provider
+	}
+	if model != "" {
+		embeddings.Model = model
+	}
+	return llm.NewEmbedder(llm.EmbedderConfig{
+		Provider: embeddings.Provider,
+		Model:    embeddings.Model,
+		URL:      embeddings.URL,
+	})
+}
+
+// embedPending embeds messages that don't have embeddings yet, showing
+// progress on stderr
+func embedPending(ctx context.Context, database *db.DB, embedder llm.Embedder) (int, error) {
+	n, err := search.IndexEmbeddings(ctx, database, embedder, func(done, total int) {
+		fmt.Fprintf(os.Stderr, "\rEmbedding messages: %d/%d", done, total)
+	})
+	if n > 0 {
+		fmt.Fprintln(os.Stderr)
+	}
+	return n, err
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/neilberkman/ccrider/internal/core/db"
+	"github.com/neilberkman/ccrider/internal/interface/tui"
+	"github.com/spf13/cobra"
+)
+
+var (
+	pickProject string
+	pickFilter  bool
+)
+
+var pickCmd = &cobra.Command{
+	Use:   "pick [query]",
+	Short: "Fuzzy-find a session and print its ID",
+	Long: `Fuzzy-find a session by summary, project path, git branch or session ID
+and print the chosen session's ID to stdout, for use in shell scripts.
+
+Type any characters of what you remember, in order: "mgrbil" finds a
+"migration" session in ~/code/billing. Matches at word starts and in
+unbroken runs rank first. Space-separated words must all match; a word with
+an uppercase letter matches case.
+
+The picker draws on stderr, so it works inside $(...). Press esc to cancel
+(exit status 130).
+
+Examples:
+  ccrider resume $(ccrider pick)
+  ccrider export $(ccrider pick billing) -o notes.md
+  ccrider pick --filter "flaky test" | head -1`,
+	Args: cobra.ArbitraryArgs,
+	RunE: runPick,
+}
+
+func init() {
+	rootCmd.AddCommand(pickCmd)
+	pickCmd.Flags().StringVar(&pickProject, "project", "", "Only sessions whose project path contains this")
+	pickCmd.Flags().BoolVar(&pickFilter, "filter", false, "Print matching session IDs, best first, without the picker")
+}
+
+func runPick(cmd *cobra.Command, args []string) error {
+	query := strings.Join(args, " ")
+
+	database, err := db.New(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	sessions, err := database.ListSessions(pickProject)
+	_ = database.Close()
+	if err != nil {
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	if pickFilter {
+		picker := tui.NewPicker(sessions, query)
+		for _, id := range picker.Matches() {
+			fmt.Println(id)
+		}
+		return nil
+	}
+
+	// Style for the terminal the picker draws on, not the captured stdout
+	stderr := lipgloss.NewRenderer(os.Stderr)
+	lipgloss.SetColorProfile(stderr.ColorProfile())
+	lipgloss.SetHasDarkBackground(stderr.HasDarkBackground())
+
+	p := tea.NewProgram(tui.NewPicker(sessions, query), tea.WithOutput(os.Stderr), tea.WithAltScreen())
+	finalModel, err := p.Run()
+	if err != nil {
+		return fmt.Errorf("picker failed: %w", err)
+	}
+
+	picked := finalModel.(tui.Picker).Picked()
+	if picked == "" {
+		// Nothing picked - exit like fzf so $(ccrider pick) scripts can stop
+		os.Exit(130)
+	}
+	fmt.Println(picked)
+	return nil
+}
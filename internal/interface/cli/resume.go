@@ -0,0 +1,54 @@
+package cli
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/neilberkman/ccrider/internal/core/db"
+	"github.com/spf13/cobra"
+)
+
+var resumeFork bool
+
+var resumeCmd = &cobra.Command{
+	Use:   "resume <session-id>",
+	Short: "Resume a Claude Code session",
+	Long: `Resume a session with claude --resume, in the session's project directory.
+
+Examples:
+  ccrider resume 7f3c2a1e-...
+  ccrider resume $(ccrider pick)
+  ccrider resume --fork $(ccrider pick billing)`,
+	Args: cobra.ExactArgs(1),
+	RunE: runResume,
+}
+
+func init() {
+	rootCmd.AddCommand(resumeCmd)
+	resumeCmd.Flags().BoolVar(&resumeFork, "fork", false, "Resume as a new session (claude --fork-session)")
+}
+
+func runResume(cmd *cobra.Command, args []string) error {
+	database, err := db.New(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	session, lastCwd, err := database.GetSessionLaunchInfo(args[0])
+	_ = database.Close()
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("session not found: %s", args[0])
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load session: %w", err)
+	}
+
+	return execClaude(
+		session.SessionID,
+		session.ProjectPath,
+		lastCwd,
+		session.UpdatedAt.Format("2006-01-02 15:04:05"),
+		session.Summary,
+		resumeFork,
+	)
+}
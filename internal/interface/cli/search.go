@@ -1,43 +1,115 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"strings"
 
+	"github.com/charmbracelet/lipgloss"
 	"github.com/neilberkman/ccrider/internal/core/db"
 	"github.com/neilberkman/ccrider/internal/core/search"
 	"github.com/spf13/cobra"
 )
 
 var (
-	searchLimit int
+	searchLimit    int
+	searchOffset   int
+	searchProject  string
+	searchAfter    string
+	searchBefore   string
+	searchCode     bool
+	searchRegex    bool
+	searchSemantic bool
+	searchHybrid   bool
+
+	searchSave        string
+	searchListSaved   bool
+	searchDeleteSaved string
+	searchHistory     bool
 )
 
 var searchCmd = &cobra.Command{
-	Use:   "search <query>",
+	Use:   "search <query | @name>",
 	Short: "Search Claude Code sessions using full-text search",
 	Long: `Search through all imported Claude Code sessions.
 
 Uses FTS5 full-text search with porter stemming for natural language.
 Results are grouped by session and show matching message snippets.
 
+Query syntax:
+  auth token            Both terms (AND is implied)
+  '"token refresh"'     Exact phrase (quote it for the shell)
+  auth OR oauth         Either term; group with parentheses
+  auth -oauth           Exclude a term (also: auth NOT oauth)
+  migrat*               Prefix match
+
+Field filters narrow the matches: project:, branch:, role:user|assistant,
+tool:Bash, file:, issue:ENA-1234, model:opus, after:, before: (dates like
+2025-01-01 or yesterday). Quote values with spaces: project:"my app".
+
+Tool call inputs and outputs (commands, compiler errors, test output) are
+searched along with message text. tool: limits those matches to one tool's
+calls, e.g. tool:Bash "kubectl apply"; on its own it lists the calls, and
+file: picks calls on a file, e.g. tool:Edit file:auth.go.
+
+Use --code (or code:) to match identifiers exactly (no stemming), e.g.
+handleAuthCallback or user_id. Queries that look like an identifier use code
+mode automatically.
+
+Use --regex to match a Go regular expression against message text instead
+(case-sensitive; start the pattern with (?i) to ignore case). Sessions are
+printed as they are found, most recently updated first.
+
+Use --semantic to find messages that mean something similar rather than
+share words, or --hybrid to rank by both meaning and keyword relevance.
+New messages are embedded first (see ccrider embed).
+
+Use --save NAME to save the query (with its filters and mode) and rerun it
+later as @NAME; words after @NAME are added to the saved query. Searches are
+also kept in a history (--history), shared with the TUI.
+
 Examples:
   ccrider search "authentication implementation"
   ccrider search "ENA-7030"
-  ccrider search "error handling" --limit 10`,
-	Args: cobra.MinimumNArgs(1),
+  ccrider search --code user_id
+  ccrider search "deploy tool:Bash role:user"
+  ccrider search 'tool:Bash "kubectl apply"'
+  ccrider search 'tool:Edit file:auth.go'
+  ccrider search "(postgres OR sqlite) migration -rollback"
+  ccrider search --regex 'ENA-\d{4}.*rollback'
+  ccrider search --semantic "why did the deploy fail"
+  ccrider search --hybrid "flaky test retries"
+  ccrider search "error handling" --limit 10
+  ccrider search "error handling" --limit 10 --offset 10
+  ccrider search "migration" --project myapp --after 2025-01-01
+  ccrider search "migration rollback" --project billing --save rollback
+  ccrider search @rollback
+  ccrider search @rollback after:yesterday
+  ccrider search --list-saved`,
 	RunE: runSearch,
 }
 
 func init() {
 	rootCmd.AddCommand(searchCmd)
 	searchCmd.Flags().IntVar(&searchLimit, "limit", 50, "Maximum number of sessions to show")
+	searchCmd.Flags().IntVar(&searchOffset, "offset", 0, "Number of sessions to skip (for paging through results)")
+	searchCmd.Flags().StringVar(&searchProject, "project", "", "Filter by project path")
+	searchCmd.Flags().StringVar(&searchAfter, "after", "", "Only matches on or after this date (YYYY-MM-DD or RFC 3339)")
+	searchCmd.Flags().StringVar(&searchBefore, "before", "", "Only matches before this date (YYYY-MM-DD or RFC 3339)")
+	searchCmd.Flags().BoolVar(&searchCode, "code", false, "Match code identifiers exactly, without stemming")
+	searchCmd.Flags().BoolVar(&searchRegex, "regex", false, "Treat the query as a Go regular expression")
+	searchCmd.Flags().BoolVar(&searchSemantic, "semantic", false, "Match by meaning using embeddings")
+	searchCmd.Flags().BoolVar(&searchHybrid, "hybrid", false, "Rank by both meaning and keyword relevance")
+	searchCmd.MarkFlagsMutuallyExclusive("code", "regex", "semantic")
+	searchCmd.Flags().StringVar(&searchSave, "save", "", "Save the search under `NAME` (rerun it with @NAME)")
+	searchCmd.Flags().BoolVar(&searchListSaved, "list-saved", false, "List saved searches")
+	searchCmd.Flags().StringVar(&searchDeleteSaved, "delete-saved", "", "Delete the saved search `NAME`")
+	searchCmd.Flags().BoolVar(&searchHistory, "history", false, "List recent searches")
+	searchCmd.MarkFlagsMutuallyExclusive("regex", "semantic", "hybrid")
+	searchCmd.MarkFlagsMutuallyExclusive("save", "list-saved", "delete-saved", "history")
 }
 
 func runSearch(cmd *cobra.Command, args []string) error {
-	// Join all args as query
-	query := strings.Join(args, " ")
-
 	// Open database
 	database, err := db.New(dbPath)
 	if err != nil {
@@ -47,72 +119,261 @@ func runSearch(cmd *cobra.Command, args []string) error {
 		_ = database.Close()
 	}()
 
+	switch {
+	case searchListSaved:
+		return listSavedSearches(database)
+	case searchDeleteSaved != "":
+		deleted, err := database.DeleteSavedSearch(strings.TrimPrefix(searchDeleteSaved, "@"))
+		if err != nil {
+			return fmt.Errorf("failed to delete saved search: %w", err)
+		}
+		if !deleted {
+			return fmt.Errorf("no saved search named %q", searchDeleteSaved)
+		}
+		fmt.Printf("Deleted saved search @%s\n", strings.TrimPrefix(searchDeleteSaved, "@"))
+		return nil
+	case searchHistory:
+		return listSearchHistory(database)
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("requires a query or @name of a saved search")
+	}
+
+	// Join all args as query, expanding a saved search
+	query := strings.Join(args, " ")
+	if name, ok := strings.CutPrefix(args[0], "@"); ok && name != "" {
+		saved, err := database.GetSavedSearch(name)
+		if err != nil {
+			return fmt.Errorf("failed to load saved search: %w", err)
+		}
+		if saved == nil {
+			return fmt.Errorf("no saved search named %q (see ccrider search --list-saved)", name)
+		}
+		query = strings.TrimSpace(saved.Query + " " + strings.Join(args[1:], " "))
+		if !cmd.Flags().Changed("regex") && !cmd.Flags().Changed("semantic") && !cmd.Flags().Changed("hybrid") {
+			searchRegex = saved.Mode == "regex"
+			searchSemantic = saved.Mode == "semantic"
+			searchHybrid = saved.Mode == "hybrid"
+		}
+	}
+
+	mode := searchModeName()
+	fullQuery, err := queryWithFilters(query)
+	if err != nil {
+		return err
+	}
+	if searchSave != "" {
+		name := strings.TrimPrefix(searchSave, "@")
+		if err := database.SaveSearch(name, fullQuery, mode); err != nil {
+			return fmt.Errorf("failed to save search: %w", err)
+		}
+		fmt.Printf("Saved search @%s: %s\n\n", name, fullQuery)
+	}
+	_ = database.AddSearchHistory(fullQuery, mode)
+
 	// Use unified search backend (same as TUI/MCP)
 	filters := search.SearchFilters{
-		Query: query,
-		// CLI doesn't support project/date filters yet, but using unified backend
-		// means we can add them easily later
+		Query:       query,
+		ProjectPath: searchProject,
+		AfterDate:   searchAfter,
+		BeforeDate:  searchBefore,
+		Limit:       searchLimit,
+		Offset:      searchOffset,
+		MatchLimit:  3,
+	}
+	if searchCode {
+		filters.Mode = search.ModeCode
+	}
+	if searchRegex {
+		return runRegexSearch(database, filters)
 	}
 
-	sessionResults, err := search.SearchWithFilters(database, filters)
+	var page *search.SessionSearchPage
+	if searchSemantic || searchHybrid {
+		page, err = semanticSearch(database, filters, searchHybrid)
+	} else {
+		page, err = search.SearchWithFilters(database, filters)
+	}
 	if err != nil {
 		return fmt.Errorf("search failed: %w", err)
 	}
 
 	// Display results grouped by session
-	if len(sessionResults) == 0 {
-		fmt.Printf("No results found for: %s\n", query)
+	if len(page.Sessions) == 0 {
+		if page.Total > 0 {
+			fmt.Printf("No results past offset %d (%d session(s) found for: %s)\n", searchOffset, page.Total, query)
+		} else {
+			fmt.Printf("No results found for: %s\n", query)
+		}
 		return nil
 	}
 
-	// Count total matches across all sessions
-	totalMatches := 0
-	for _, s := range sessionResults {
-		totalMatches += len(s.Matches)
+	fmt.Printf("Found %d session(s) for: %s (showing %d-%d)\n", page.Total, query, searchOffset+1, searchOffset+len(page.Sessions))
+	fmt.Println()
+
+	for i, session := range page.Sessions {
+		printSessionResult(searchOffset+i+1, session)
+	}
+
+	if page.NextOffset > 0 {
+		fmt.Printf("... and %d more sessions (use --offset %d to see more)\n", page.Total-page.NextOffset, page.NextOffset)
 	}
 
-	fmt.Printf("Found %d session(s) with %d match(es) for: %s\n", len(sessionResults), totalMatches, query)
-	fmt.Println()
+	return nil
+}
 
-	sessionCount := 0
-	for _, session := range sessionResults {
-		// Limit to searchLimit sessions
-		if sessionCount >= searchLimit {
-			fmt.Printf("\n... and %d more sessions (use --limit to see more)\n", len(sessionResults)-searchLimit)
-			break
+// searchModeName names the search mode for saved searches and history
+func searchModeName() string {
+	switch {
+	case searchRegex:
+		return "regex"
+	case searchSemantic:
+		return "semantic"
+	case searchHybrid:
+		return "hybrid"
+	}
+	return ""
+}
+
+// queryWithFilters folds the filter flags into the query as field filters,
+// so a saved search or history entry carries them
+func queryWithFilters(query string) (string, error) {
+	var terms []string
+	if searchRegex {
+		// A regular expression has no field filters to carry them
+		if searchSave != "" && (searchProject != "" || searchAfter != "" || searchBefore != "") {
+			return "", fmt.Errorf("--project, --after and --before can't be saved with --regex")
 		}
-		sessionCount++
+		return query, nil
+	}
+	if searchCode {
+		terms = append(terms, "code:")
+	}
+	if searchProject != "" {
+		terms = append(terms, search.FilterTerm("project", searchProject))
+	}
+	if searchAfter != "" {
+		terms = append(terms, search.FilterTerm("after", searchAfter))
+	}
+	if searchBefore != "" {
+		terms = append(terms, search.FilterTerm("before", searchBefore))
+	}
+	return strings.TrimSpace(query + " " + strings.Join(terms, " ")), nil
+}
 
-		fmt.Printf("=== Session %d ===\n", sessionCount)
-		fmt.Printf("ID:      %s\n", session.SessionID)
-		if session.SessionSummary != "" {
-			fmt.Printf("Summary: %s\n", session.SessionSummary)
+// listSavedSearches prints the saved searches
+func listSavedSearches(database *db.DB) error {
+	searches, err := database.ListSavedSearches()
+	if err != nil {
+		return fmt.Errorf("failed to list saved searches: %w", err)
+	}
+	if len(searches) == 0 {
+		fmt.Println("No saved searches (save one with ccrider search <query> --save NAME)")
+		return nil
+	}
+	for _, s := range searches {
+		if s.Mode != "" {
+			fmt.Printf("@%-20s %s (%s)\n", s.Name, s.Query, s.Mode)
 		} else {
-			fmt.Printf("Summary: [No summary - showing first match]\n")
+			fmt.Printf("@%-20s %s\n", s.Name, s.Query)
 		}
-		fmt.Printf("Project: %s\n", session.ProjectPath)
-		fmt.Printf("Updated: %s\n", session.UpdatedAt)
-		fmt.Printf("Matches: %d\n", len(session.Matches))
-		fmt.Println()
+	}
+	return nil
+}
 
-		// Show up to 3 matches per session
-		matchLimit := 3
-		if len(session.Matches) > matchLimit {
-			fmt.Printf("Showing first %d of %d matches:\n", matchLimit, len(session.Matches))
-		}
-		for i, match := range session.Matches {
-			if i >= matchLimit {
-				break
-			}
-			fmt.Printf("  Match %d:\n", i+1)
-			fmt.Printf("  %s\n", truncateMessage(match.MessageText, 200))
-			fmt.Println()
+// listSearchHistory prints recent searches, most recent first
+func listSearchHistory(database *db.DB) error {
+	entries, err := database.ListSearchHistory(50)
+	if err != nil {
+		return fmt.Errorf("failed to list search history: %w", err)
+	}
+	if len(entries) == 0 {
+		fmt.Println("No searches yet")
+		return nil
+	}
+	for _, e := range entries {
+		if e.Mode != "" {
+			fmt.Printf("%s  %s (%s)\n", e.SearchedAt.Local().Format("2006-01-02 15:04"), e.Query, e.Mode)
+		} else {
+			fmt.Printf("%s  %s\n", e.SearchedAt.Local().Format("2006-01-02 15:04"), e.Query)
 		}
 	}
+	return nil
+}
+
+// semanticSearch embeds new messages, then searches by meaning
+func semanticSearch(database *db.DB, filters search.SearchFilters, hybrid bool) (*search.SessionSearchPage, error) {
+	ctx := context.Background()
+	embedder, err := loadEmbedder("", "")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := embedPending(ctx, database, embedder); err != nil {
+		return nil, err
+	}
+	return search.SearchSemantic(ctx, database, embedder, filters, hybrid)
+}
 
+// runRegexSearch prints each session as soon as the scan reaches it, since
+// a regex search reads every candidate message
+func runRegexSearch(database *db.DB, filters search.SearchFilters) error {
+	count := 0
+	err := search.SearchRegex(database, filters, func(session search.SessionSearchResult) error {
+		count++
+		printSessionResult(searchOffset+count, session)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("search failed: %w", err)
+	}
+
+	switch {
+	case count == 0:
+		fmt.Printf("No results found for: %s\n", filters.Query)
+	case count == searchLimit:
+		fmt.Printf("Showing %d sessions; there may be more (use --offset %d)\n", count, searchOffset+count)
+	default:
+		fmt.Printf("Found %d session(s) for: %s\n", searchOffset+count, filters.Query)
+	}
 	return nil
 }
 
+func printSessionResult(n int, session search.SessionSearchResult) {
+	fmt.Printf("=== Session %d ===\n", n)
+	fmt.Printf("ID:      %s\n", session.SessionID)
+	if session.SessionSummary != "" {
+		fmt.Printf("Summary: %s\n", session.SessionSummary)
+	} else {
+		fmt.Printf("Summary: [No summary - showing first match]\n")
+	}
+	fmt.Printf("Project: %s\n", session.ProjectPath)
+	fmt.Printf("Updated: %s\n", session.UpdatedAt)
+	fmt.Printf("Matches: %d\n", session.MatchCount)
+	fmt.Println()
+
+	// Show up to 3 matches per session
+	if session.MatchCount > len(session.Matches) {
+		fmt.Printf("Showing first %d of %d matches:\n", len(session.Matches), session.MatchCount)
+	}
+	for j, match := range session.Matches {
+		if match.ToolName != "" {
+			fmt.Printf("  Match %d (in %s tool call):\n", j+1, match.ToolName)
+		} else {
+			fmt.Printf("  Match %d:\n", j+1)
+		}
+		fmt.Printf("  %s\n", search.Highlight(truncateMessage(match.MessageText, 200), renderMatch))
+		fmt.Println()
+	}
+}
+
+// matchStyle highlights matched terms (plain text when not a terminal)
+var matchStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("11"))
+
+func renderMatch(s string) string {
+	return matchStyle.Render(s)
+}
+
 // truncateMessage truncates long messages for display
 func truncateMessage(msg string, maxLen int) string {
 	if len(msg) <= maxLen {
@@ -48,6 +48,15 @@ func runStats(cmd *cobra.Command, args []string) error {
 
 	fmt.Println()
 
+	if stats.Usage.Total() > 0 {
+		fmt.Printf("Token Usage:\n")
+		fmt.Printf("  Input:          %d\n", stats.Usage.InputTokens)
+		fmt.Printf("  Output:         %d\n", stats.Usage.OutputTokens)
+		fmt.Printf("  Cache Read:     %d\n", stats.Usage.CacheReadTokens)
+		fmt.Printf("  Cache Creation: %d\n", stats.Usage.CacheCreationTokens)
+		fmt.Println()
+	}
+
 	// Display date range if we have sessions
 	if stats.TotalSessions > 0 {
 		if !stats.OldestSession.IsZero() {
@@ -2,9 +2,12 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 
+	"github.com/neilberkman/ccrider/internal/core/config"
 	"github.com/neilberkman/ccrider/internal/core/db"
 	"github.com/neilberkman/ccrider/internal/core/llm"
 	"github.com/spf13/cobra"
@@ -17,15 +20,26 @@ import (
 // CCRIDER_AWS_PROFILE
 
 var (
-	summarizeLimit   int
-	summarizeForce   bool
-	summarizeModel   string
-	summarizeRegion  string
-	summarizeProfile string
-	summarizeVerbose bool
-	summarizeExtract bool
+	summarizeLimit    int
+	summarizeForce    bool
+	summarizeProvider string
+	summarizeModel    string
+	summarizeURL      string
+	summarizeRegion   string
+	summarizeProfile  string
+	summarizeVerbose  bool
+	summarizeExtract  bool
+
+	summarizeConcurrency int
+	summarizeRPM         int
+	summarizeRestart     bool
+	summarizeFixtures    string
 )
 
+// defaultSummarizeConcurrency is how many sessions are summarized at once
+// when neither --concurrency nor the config sets it
+const defaultSummarizeConcurrency = 4
+
 var summarizeCmd = &cobra.Command{
 	Use:   "summarize",
 	Short: "Generate LLM summaries for sessions",
@@ -35,13 +49,33 @@ Features:
 - Progressive chunk-based summarization for long sessions
 - Two-tier summaries: one-line (for lists) and full (detailed)
 - Metadata extraction: issue IDs (ENA-1234) and file paths
-- Incremental updates when sessions grow
-
-Currently supports AWS Bedrock with Claude models. Requires AWS credentials
-to be configured (via environment, profile, or IAM role).
+- Incremental updates when sessions grow: only new messages are
+  summarized, then combined with the stored chunk summaries
+- Several sessions at once (--concurrency), with an optional rate limit
+  (--rpm); throttled requests are retried with backoff
+- Resumable: an interrupted run (Ctrl-C, crash) picks up where it left off
+  when the same command is run again
+
+Providers (--provider, or [llm] in ~/.config/ccrider/config.toml):
+  bedrock    Claude on AWS Bedrock (default); AWS credentials from the
+             environment, a profile or an IAM role
+  anthropic  Anthropic Messages API; ANTHROPIC_API_KEY or api_key
+  openai     OpenAI-compatible chat completions: OpenAI (OPENAI_API_KEY), or
+             a local vLLM, LM Studio or llama.cpp server with --url
+  ollama     A local Ollama model, so nothing leaves the machine
+  replay     Responses recorded earlier (--fixtures), for running offline
+             and deterministically; with any other provider, --fixtures
+             records its responses there
+
+  [llm]
+  provider = "ollama"
+  model = "llama3.2"                  # ollama pull llama3.2
+  concurrency = 4                     # Sessions summarized at once
+  requests_per_minute = 50            # Stay under the provider's rate limit
 
 Examples:
-  # Summarize sessions without summaries (default: 10 at a time)
+  # Summarize new sessions and extend the summaries of grown ones
+  # (default: 10 at a time)
   ccrider summarize
 
   # Summarize more sessions
@@ -50,9 +84,25 @@ Examples:
   # Re-summarize all sessions (overwrite existing)
   ccrider summarize --force --limit 100
 
+  # A big run, 8 sessions at a time but at most 60 requests a minute.
+  # If it's interrupted, run the same command again to finish it.
+  ccrider summarize --limit 500 --concurrency 8 --rpm 60
+
+  # Start over instead of resuming an interrupted run
+  ccrider summarize --limit 500 --restart
+
   # Use a specific model
   ccrider summarize --model anthropic.claude-3-sonnet-20240229-v1:0
 
+  # Use the Anthropic API, or a local model
+  ccrider summarize --provider anthropic
+  ccrider summarize --provider ollama --model qwen2.5
+  ccrider summarize --provider openai --url http://localhost:1234/v1
+
+  # Record responses, then replay them with no LLM at all
+  ccrider summarize --provider ollama --fixtures ./llm-fixtures
+  ccrider summarize --provider replay --fixtures ./llm-fixtures --force
+
   # Extract metadata only (no LLM calls)
   ccrider summarize --extract-only`,
 	RunE: runSummarize,
@@ -60,17 +110,31 @@ Examples:
 
 func init() {
 	summarizeCmd.Flags().IntVarP(&summarizeLimit, "limit", "n", 10, "Number of sessions to summarize")
-	summarizeCmd.Flags().BoolVarP(&summarizeForce, "force", "f", false, "Re-summarize sessions that already have summaries")
-	summarizeCmd.Flags().StringVar(&summarizeModel, "model", "", "Bedrock model ID (default: claude-3-haiku)")
-	summarizeCmd.Flags().StringVar(&summarizeRegion, "region", "", "AWS region (default: us-east-1)")
+	summarizeCmd.Flags().BoolVarP(&summarizeForce, "force", "f", false, "Re-summarize sessions from scratch, including up-to-date ones")
+	summarizeCmd.Flags().StringVar(&summarizeProvider, "provider", "", "LLM provider: bedrock, anthropic, openai, ollama or replay (default from config, else bedrock)")
+	summarizeCmd.Flags().StringVar(&summarizeModel, "model", "", "Model ID (default from config, else the provider's default)")
+	summarizeCmd.Flags().StringVar(&summarizeURL, "url", "", "API base URL for an OpenAI-compatible server or Ollama")
+	summarizeCmd.Flags().StringVar(&summarizeRegion, "region", "", "AWS region for bedrock (default: us-east-1)")
+	summarizeCmd.Flags().StringVar(&summarizeProfile, "profile", "", "AWS profile for bedrock")
 	summarizeCmd.Flags().BoolVarP(&summarizeVerbose, "verbose", "v", false, "Show verbose output")
 	summarizeCmd.Flags().BoolVar(&summarizeExtract, "extract-only", false, "Only extract metadata (issue IDs, files), no LLM calls")
+	summarizeCmd.Flags().IntVarP(&summarizeConcurrency, "concurrency", "j", 0, "Sessions to summarize (and LLM requests to make) at once (default from config, else 4)")
+	summarizeCmd.Flags().IntVar(&summarizeRPM, "rpm", 0, "Maximum LLM requests per minute (default from config, else no limit)")
+	summarizeCmd.Flags().BoolVar(&summarizeRestart, "restart", false, "Start over instead of resuming an interrupted run")
+	summarizeCmd.Flags().StringVar(&summarizeFixtures, "fixtures", "", "Replay fixture directory: answers --provider replay, and records other providers' responses")
 
 	rootCmd.AddCommand(summarizeCmd)
 }
 
 func runSummarize(cmd *cobra.Command, args []string) error {
-	ctx := context.Background()
+	// Ctrl-C stops the run cleanly, leaving unfinished sessions for next time
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
 
 	// Open database
 	database, err := db.New(dbPath)
@@ -79,147 +143,208 @@ func runSummarize(cmd *cobra.Command, args []string) error {
 	}
 	defer func() { _ = database.Close() }()
 
-	// Get sessions that need processing
-	sessions, err := getSummarizableSessions(database, summarizeLimit, summarizeForce)
+	job, items, err := loadSummaryJob(database)
 	if err != nil {
-		return fmt.Errorf("failed to query sessions: %w", err)
+		return err
 	}
-
-	if len(sessions) == 0 {
+	if len(items) == 0 {
+		if job != nil {
+			_ = database.DeleteSummaryJob(job.ID)
+		}
 		fmt.Println("No sessions to summarize")
 		return nil
 	}
 
-	// Initialize components
-	extractor := llm.NewMetadataExtractor()
+	concurrency := summarizeConcurrency
+	if concurrency == 0 {
+		concurrency = cfg.LLM.Concurrency
+	}
+	if concurrency <= 0 {
+		concurrency = defaultSummarizeConcurrency
+	}
 
-	var summarizer *llm.HierarchicalSummarizer
+	// Initialize components
+	runner := &llm.JobRunner{
+		DB:          database,
+		Extractor:   llm.NewMetadataExtractor(),
+		Concurrency: concurrency,
+		Resummarize: summarizeForce,
+	}
 	if !summarizeExtract {
-		// Check env vars for AWS credentials
-		region := summarizeRegion
-		if region == "" {
-			region = os.Getenv("CCRIDER_AWS_REGION")
-		}
-		accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
-		if v := os.Getenv("CCRIDER_AWS_ACCESS_KEY_ID"); v != "" {
-			accessKey = v
-		}
-		secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
-		if v := os.Getenv("CCRIDER_AWS_SECRET_ACCESS_KEY"); v != "" {
-			secretKey = v
-		}
-		profile := summarizeProfile
-		if profile == "" {
-			profile = os.Getenv("CCRIDER_AWS_PROFILE")
-		}
-
-		provider, err := llm.NewBedrockProvider(ctx, llm.BedrockConfig{
-			Region:          region,
-			ModelID:         summarizeModel,
-			Profile:         profile,
-			AccessKeyID:     accessKey,
-			SecretAccessKey: secretKey,
-		})
+		provider, err := loadProvider(ctx, cfg.LLM)
 		if err != nil {
 			return fmt.Errorf("failed to create LLM provider: %w", err)
 		}
-		summarizer = llm.NewHierarchicalSummarizer(provider)
-		fmt.Printf("Summarizing %d sessions using %s...\n", len(sessions), provider.Name())
+		rpm := summarizeRPM
+		if rpm == 0 {
+			rpm = cfg.LLM.RequestsPerMinute
+		}
+		// Chunks of a long session are summarized in parallel too, but the
+		// shared provider caps LLM requests in flight at concurrency overall
+		summarizer := llm.NewHierarchicalSummarizer(llm.NewThrottledProvider(provider, llm.ThrottleConfig{
+			RequestsPerMinute: rpm,
+			MaxConcurrent:     concurrency,
+		}))
+		summarizer.ChunkConcurrency = concurrency
+		runner.Summarizer = summarizer
+		fmt.Printf("Summarizing %d sessions using %s (%d at a time)...\n", len(items), provider.Name(), concurrency)
 	} else {
-		fmt.Printf("Extracting metadata from %d sessions...\n", len(sessions))
+		fmt.Printf("Extracting metadata from %d sessions...\n", len(items))
 	}
 
-	// Process each session
+	// Report each session as it finishes
 	var successCount, skipCount, errorCount int
-	for i, s := range sessions {
-		// Get messages for this session
-		messages, err := getSessionMessages(database, s.sessionID)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to get messages for %s: %v\n", s.sessionID, err)
-			errorCount++
-			continue
-		}
-
-		if len(messages) == 0 {
+	runner.OnResult = func(r llm.SessionResult) {
+		n := successCount + skipCount + errorCount + 1
+		id := truncateID(r.Item.SessionUUID)
+		switch r.Status {
+		case db.JobItemSkipped:
+			skipCount++
 			if summarizeVerbose {
-				fmt.Printf("[%d/%d] %s: no messages, skipping\n", i+1, len(sessions), truncateID(s.sessionID))
+				fmt.Printf("[%d/%d] %s: no messages, skipping\n", n, len(items), id)
 			}
-			skipCount++
-			continue
+			return
+		case db.JobItemFailed:
+			errorCount++
+			fmt.Fprintf(os.Stderr, "Warning: failed to process %s: %v\n", r.Item.SessionUUID, r.Err)
+			return
 		}
 
-		// Extract metadata (always do this)
-		issues := extractor.ExtractIssues(messages)
-		files := extractor.ExtractFiles(messages)
-
-		// Save extracted metadata
-		if len(issues) > 0 {
-			for j := range issues {
-				issues[j].SessionID = s.id
-			}
-			if err := database.SaveSessionIssues(s.id, issues); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to save issues for %s: %v\n", s.sessionID, err)
-			}
-		}
-		if len(files) > 0 {
-			for j := range files {
-				files[j].SessionID = s.id
-			}
-			if err := database.SaveSessionFiles(s.id, files); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to save files for %s: %v\n", s.sessionID, err)
-			}
+		successCount++
+		switch {
+		case !summarizeVerbose:
+			fmt.Printf(".")
+		case r.Summary != nil:
+			fmt.Printf("[%d/%d] %s: %s (issues:%d files:%d chunks:%d)\n",
+				n, len(items), id, truncate(r.Summary.OneLine, 50),
+				r.Issues, r.Files, len(r.Summary.ChunkSummaries))
+		default:
+			fmt.Printf("[%d/%d] %s: extracted (issues:%d files:%d)\n",
+				n, len(items), id, r.Issues, r.Files)
 		}
+	}
 
-		// Generate summary (unless extract-only mode)
-		if !summarizeExtract && summarizer != nil {
-			req := llm.SummaryRequest{
-				SessionID:   s.sessionID,
-				ProjectPath: s.projectPath,
-				Messages:    messages,
-			}
+	err = runner.Run(ctx, job, items)
 
-			summary, err := summarizer.SummarizeSession(ctx, req)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to summarize %s: %v\n", s.sessionID, err)
-				errorCount++
-				continue
-			}
+	if !summarizeVerbose {
+		fmt.Println()
+	}
+	if errors.Is(err, context.Canceled) {
+		left := len(items) - successCount - skipCount
+		fmt.Printf("Interrupted! Processed: %d, Skipped: %d, Errors: %d\n", successCount, skipCount, errorCount)
+		fmt.Printf("%d sessions left - run the same command again to resume\n", left)
+		_ = database.Close()
+		os.Exit(130)
+	}
+	if err != nil {
+		return err
+	}
 
-			// Save summary
-			summary.SessionID = s.id
-			if err := database.SaveSessionSummary(*summary); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: failed to save summary for %s: %v\n", s.sessionID, err)
-				errorCount++
-				continue
-			}
+	// Finished: failed sessions get picked again by the next run anyway
+	if err := database.DeleteSummaryJob(job.ID); err != nil {
+		return fmt.Errorf("failed to finish summary job: %w", err)
+	}
+	fmt.Printf("Done! Processed: %d, Skipped: %d, Errors: %d\n", successCount, skipCount, errorCount)
+	return nil
+}
 
-			if summarizeVerbose {
-				fmt.Printf("[%d/%d] %s: %s (issues:%d files:%d chunks:%d)\n",
-					i+1, len(sessions), truncateID(s.sessionID),
-					truncate(summary.OneLine, 50),
-					len(issues), len(files), len(summary.ChunkSummaries))
-			} else {
-				fmt.Printf(".")
-			}
-		} else {
-			if summarizeVerbose {
-				fmt.Printf("[%d/%d] %s: extracted (issues:%d files:%d)\n",
-					i+1, len(sessions), truncateID(s.sessionID),
-					len(issues), len(files))
-			} else {
-				fmt.Printf(".")
+// loadSummaryJob resumes the interrupted run started with the same options,
+// or starts a new one. It returns the job and the sessions left to do.
+func loadSummaryJob(database *db.DB) (*db.SummaryJob, []db.SummaryJobItem, error) {
+	params := fmt.Sprintf("limit=%d force=%v extract=%v", summarizeLimit, summarizeForce, summarizeExtract)
+
+	if !summarizeRestart {
+		job, err := database.FindSummaryJob(params)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load summary job: %w", err)
+		}
+		if job != nil {
+			items, err := database.ListSummaryJobItems(job.ID)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to load summary job: %w", err)
 			}
+			fmt.Printf("Resuming interrupted run: %d of %d sessions left (--restart to start over)\n", job.Remaining, job.Total)
+			return job, items, nil
 		}
+	}
 
-		successCount++
+	// Get sessions that need processing
+	sessions, err := getSummarizableSessions(database, summarizeLimit, summarizeForce)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to query sessions: %w", err)
+	}
+	if len(sessions) == 0 {
+		return nil, nil, nil
 	}
 
-	if !summarizeVerbose {
-		fmt.Println()
+	ids := make([]int64, len(sessions))
+	for i, s := range sessions {
+		ids[i] = s.id
 	}
+	job, err := database.CreateSummaryJob(params, ids)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create summary job: %w", err)
+	}
+	items, err := database.ListSummaryJobItems(job.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load summary job: %w", err)
+	}
+	return job, items, nil
+}
 
-	fmt.Printf("Done! Processed: %d, Skipped: %d, Errors: %d\n", successCount, skipCount, errorCount)
-	return nil
+// loadProvider creates the configured LLM provider, with flags overriding
+// the [llm] config
+func loadProvider(ctx context.Context, c config.LLMConfig) (llm.Provider, error) {
+	if summarizeProvider != "" {
+		c.Provider = summarizeProvider
+	}
+	if summarizeModel != "" {
+		c.Model = summarizeModel
+	}
+	if summarizeURL != "" {
+		c.URL = summarizeURL
+	}
+	if summarizeRegion != "" {
+		c.Region = summarizeRegion
+	}
+	if summarizeProfile != "" {
+		c.Profile = summarizeProfile
+	}
+
+	return llm.NewProvider(ctx, llm.ProviderConfig{
+		Provider: c.Provider,
+		Model:    c.Model,
+		URL:      c.URL,
+		APIKey:   c.APIKey,
+		Bedrock:  bedrockConfig(c.Region, c.Profile),
+
+		FixtureDir: summarizeFixtures,
+	})
+}
+
+// bedrockConfig fills in AWS settings from the CCRIDER_AWS_* env vars (so
+// credentials stay off the command line), then the standard AWS ones
+func bedrockConfig(region, profile string) llm.BedrockConfig {
+	if region == "" {
+		region = os.Getenv("CCRIDER_AWS_REGION")
+	}
+	accessKey := os.Getenv("AWS_ACCESS_KEY_ID")
+	if v := os.Getenv("CCRIDER_AWS_ACCESS_KEY_ID"); v != "" {
+		accessKey = v
+	}
+	secretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if v := os.Getenv("CCRIDER_AWS_SECRET_ACCESS_KEY"); v != "" {
+		secretKey = v
+	}
+	if profile == "" {
+		profile = os.Getenv("CCRIDER_AWS_PROFILE")
+	}
+	return llm.BedrockConfig{
+		Region:          region,
+		Profile:         profile,
+		AccessKeyID:     accessKey,
+		SecretAccessKey: secretKey,
+	}
 }
 
 type summarizeSessionInfo struct {
@@ -266,40 +391,6 @@ func getSummarizableSessions(database *db.DB, limit int, force bool) ([]summariz
 	return sessions, nil
 }
 
-func getSessionMessages(database *db.DB, sessionID string) ([]llm.Message, error) {
-	rows, err := database.Query(`
-		SELECT m.sender, m.text_content
-		FROM messages m
-		JOIN sessions s ON m.session_id = s.id
-		WHERE s.session_id = ?
-		ORDER BY m.sequence
-	`, sessionID)
-	if err != nil {
-		return nil, err
-	}
-	defer func() { _ = rows.Close() }()
-
-	var messages []llm.Message
-	for rows.Next() {
-		var sender, content string
-		if err := rows.Scan(&sender, &content); err != nil {
-			return nil, err
-		}
-		if content != "" {
-			msgType := "user"
-			if sender == "assistant" {
-				msgType = "assistant"
-			}
-			messages = append(messages, llm.Message{
-				Type:    msgType,
-				Content: content,
-			})
-		}
-	}
-
-	return messages, nil
-}
-
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
@@ -15,12 +15,16 @@ var syncCmd = &cobra.Command{
 	Short: "Import/sync Claude Code sessions",
 	Long: `Import sessions from ~/.claude/projects/ or a specified directory.
 
-Performs incremental sync - only imports new or changed sessions.`,
+Performs incremental sync - only imports new or changed sessions, resuming
+each changed file where the last sync stopped. Use --full to reparse every file.`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: runSync,
 }
 
+var syncFull bool
+
 func init() {
+	syncCmd.Flags().BoolVar(&syncFull, "full", false, "Re-import everything, ignoring saved sync positions")
 	rootCmd.AddCommand(syncCmd)
 }
 
@@ -61,6 +65,7 @@ func runSync(cmd *cobra.Command, args []string) error {
 
 	// Create importer with progress
 	imp := importer.New(database)
+	imp.Full = syncFull
 	progress := importer.NewProgressReporter(os.Stdout, total)
 
 	// Import
@@ -0,0 +1,242 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/neilberkman/ccrider/internal/core/config"
+	"github.com/neilberkman/ccrider/internal/core/db"
+	"github.com/neilberkman/ccrider/internal/core/usage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	usageBy      string
+	usageFormat  string
+	usageProject string
+	usageSince   string
+	usageUntil   string
+)
+
+var usageCmd = &cobra.Command{
+	Use:   "usage",
+	Short: "Report token usage and estimated cost",
+	Long: `Report token usage and estimated cost from assistant responses.
+
+Usage is grouped by day, week, month, project, branch or model. Combine
+groupings with commas for a breakdown, e.g. --by month,project.
+
+Costs are estimates from list prices per million tokens. Override or add
+prices in ~/.config/ccrider/config.toml:
+
+  [pricing."claude-sonnet-4-5"]
+  input = 3.00
+  output = 15.00
+  cache_read = 0.30
+  cache_write = 3.75
+
+Examples:
+  ccrider usage
+  ccrider usage --by model --since 2025-11-01
+  ccrider usage --by month,project --format csv > spend.csv
+  ccrider usage --by branch --project myapp --format json`,
+	Args: cobra.NoArgs,
+	RunE: runUsage,
+}
+
+func init() {
+	rootCmd.AddCommand(usageCmd)
+	usageCmd.Flags().StringVar(&usageBy, "by", "day", "Group by day, week, month, project, branch or model (comma-separated)")
+	usageCmd.Flags().StringVar(&usageFormat, "format", "table", "Output format: table, csv or json")
+	usageCmd.Flags().StringVar(&usageProject, "project", "", "Filter by project path")
+	usageCmd.Flags().StringVar(&usageSince, "since", "", "Only usage on or after this date (YYYY-MM-DD)")
+	usageCmd.Flags().StringVar(&usageUntil, "until", "", "Only usage on or before this date (YYYY-MM-DD)")
+}
+
+func runUsage(cmd *cobra.Command, args []string) error {
+	groupBy, err := usage.ParseGroupBy(usageBy)
+	if err != nil {
+		return err
+	}
+	if usageFormat != "table" && usageFormat != "csv" && usageFormat != "json" {
+		return fmt.Errorf("invalid format %q (want table, csv or json)", usageFormat)
+	}
+
+	filters := usage.Filters{
+		GroupBy:     groupBy,
+		ProjectPath: usageProject,
+	}
+	// Dates are local days; --until includes the whole day
+	if usageSince != "" {
+		if filters.Since, err = time.ParseInLocation("2006-01-02", usageSince, time.Local); err != nil {
+			return fmt.Errorf("invalid --since date: %w", err)
+		}
+	}
+	if usageUntil != "" {
+		until, err := time.ParseInLocation("2006-01-02", usageUntil, time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid --until date: %w", err)
+		}
+		filters.Until = until.AddDate(0, 0, 1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	database, err := db.New(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		_ = database.Close()
+	}()
+
+	report, err := usage.BuildReport(database, filters, usage.NewPricing(cfg.Pricing))
+	if err != nil {
+		return fmt.Errorf("failed to build usage report: %w", err)
+	}
+
+	// Interface concern: Format and display the report
+	switch usageFormat {
+	case "csv":
+		return writeUsageCSV(report)
+	case "json":
+		return writeUsageJSON(report)
+	default:
+		return writeUsageTable(report)
+	}
+}
+
+func writeUsageTable(report *usage.Report) error {
+	if len(report.Rows) == 0 {
+		fmt.Println("No token usage found. Run 'ccrider sync' to import sessions.")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	var header []string
+	for _, g := range report.GroupBy {
+		header = append(header, strings.ToUpper(string(g)))
+	}
+	header = append(header, "SESSIONS", "INPUT", "OUTPUT", "CACHE READ", "CACHE WRITE", "COST")
+	_, _ = fmt.Fprintln(w, strings.Join(header, "\t"))
+
+	writeRow := func(keys []string, row usage.Row) {
+		cols := append(append([]string{}, keys...),
+			strconv.Itoa(row.Sessions),
+			humanize.Comma(int64(row.Usage.InputTokens)),
+			humanize.Comma(int64(row.Usage.OutputTokens)),
+			humanize.Comma(int64(row.Usage.CacheReadTokens)),
+			humanize.Comma(int64(row.Usage.CacheCreationTokens)),
+			fmt.Sprintf("$%.2f", row.Cost),
+		)
+		_, _ = fmt.Fprintln(w, strings.Join(cols, "\t"))
+	}
+	for _, row := range report.Rows {
+		writeRow(row.Keys, row)
+	}
+
+	totalKeys := make([]string, len(report.GroupBy))
+	totalKeys[0] = "Total"
+	writeRow(totalKeys, report.Total)
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if len(report.UnpricedModels) > 0 {
+		fmt.Printf("\nNo price for %s (not included in cost) - add it under [pricing] in config.toml\n",
+			strings.Join(report.UnpricedModels, ", "))
+	}
+	return nil
+}
+
+func writeUsageCSV(report *usage.Report) error {
+	w := csv.NewWriter(os.Stdout)
+	var header []string
+	for _, g := range report.GroupBy {
+		header = append(header, string(g))
+	}
+	header = append(header, "sessions", "messages", "input_tokens", "output_tokens", "cache_read_tokens", "cache_creation_tokens", "cost_usd")
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	for _, row := range report.Rows {
+		record := append(append([]string{}, row.Keys...),
+			strconv.Itoa(row.Sessions),
+			strconv.Itoa(row.Messages),
+			strconv.Itoa(row.Usage.InputTokens),
+			strconv.Itoa(row.Usage.OutputTokens),
+			strconv.Itoa(row.Usage.CacheReadTokens),
+			strconv.Itoa(row.Usage.CacheCreationTokens),
+			strconv.FormatFloat(row.Cost, 'f', 4, 64),
+		)
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+type usageJSONRow struct {
+	Group               map[string]string `json:"group,omitempty"`
+	Sessions            int               `json:"sessions"`
+	Messages            int               `json:"messages"`
+	InputTokens         int               `json:"input_tokens"`
+	OutputTokens        int               `json:"output_tokens"`
+	CacheReadTokens     int               `json:"cache_read_tokens"`
+	CacheCreationTokens int               `json:"cache_creation_tokens"`
+	CostUSD             float64           `json:"cost_usd"`
+}
+
+func writeUsageJSON(report *usage.Report) error {
+	toJSON := func(row usage.Row) usageJSONRow {
+		r := usageJSONRow{
+			Sessions:            row.Sessions,
+			Messages:            row.Messages,
+			InputTokens:         row.Usage.InputTokens,
+			OutputTokens:        row.Usage.OutputTokens,
+			CacheReadTokens:     row.Usage.CacheReadTokens,
+			CacheCreationTokens: row.Usage.CacheCreationTokens,
+			CostUSD:             row.Cost,
+		}
+		if len(row.Keys) > 0 {
+			r.Group = make(map[string]string)
+			for i, g := range report.GroupBy {
+				r.Group[string(g)] = row.Keys[i]
+			}
+		}
+		return r
+	}
+
+	out := struct {
+		GroupBy        []usage.GroupBy `json:"group_by"`
+		Rows           []usageJSONRow  `json:"rows"`
+		Total          usageJSONRow    `json:"total"`
+		UnpricedModels []string        `json:"unpriced_models,omitempty"`
+	}{
+		GroupBy:        report.GroupBy,
+		Rows:           []usageJSONRow{},
+		Total:          toJSON(report.Total),
+		UnpricedModels: report.UnpricedModels,
+	}
+	for _, row := range report.Rows {
+		out.Rows = append(out.Rows, toJSON(row))
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(out)
+}
@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/neilberkman/ccrider/internal/core/db"
+	"github.com/neilberkman/ccrider/internal/core/importer"
+	"github.com/neilberkman/ccrider/internal/core/watcher"
+	"github.com/spf13/cobra"
+)
+
+var watchCmd = &cobra.Command{
+	Use:   "watch [path]",
+	Short: "Keep the database in sync as sessions are written",
+	Long: `Watch ~/.claude/projects/ (or a specified directory) and import session
+files as soon as Claude Code writes them.
+
+While a watcher is running, the TUI and MCP server detect it and skip their
+own sync on startup.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runWatch,
+}
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	// Determine source path
+	sourcePath := getDefaultClaudeDir()
+	if len(args) > 0 {
+		sourcePath = args[0]
+	}
+
+	// Ensure database directory exists
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return fmt.Errorf("failed to create db directory: %w", err)
+	}
+
+	lock, err := watcher.AcquireLock(dbPath)
+	if errors.Is(err, watcher.ErrAlreadyRunning) {
+		return fmt.Errorf("another ccrider watch is already running for %s", dbPath)
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = lock.Release()
+	}()
+
+	// Open database
+	database, err := db.New(dbPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database: %w", err)
+	}
+	defer func() {
+		_ = database.Close()
+	}()
+
+	imp := importer.New(database)
+
+	// Catch up on anything written while no watcher was running
+	fmt.Printf("Syncing sessions from: %s\n", sourcePath)
+	if err := imp.ImportDirectory(sourcePath, nil); err != nil {
+		return fmt.Errorf("import failed: %w", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	w := watcher.New(imp, sourcePath)
+	w.OnImport = func(files []string, err error) {
+		timestamp := time.Now().Format("15:04:05")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s Warning: %v\n", timestamp, err)
+			return
+		}
+		fmt.Printf("%s Synced %d changed file(s)\n", timestamp, len(files))
+	}
+
+	fmt.Printf("Watching for changes (Ctrl+C to stop)\n")
+	return w.Run(ctx)
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/dustin/go-humanize"
 	"github.com/muesli/reflow/wordwrap"
 	"github.com/neilberkman/ccrider/internal/core/config"
+	"github.com/neilberkman/ccrider/internal/core/db"
 	"github.com/neilberkman/ccrider/internal/core/session"
 	"github.com/neilberkman/ccrider/internal/core/terminal"
 )
@@ -34,13 +35,33 @@ func renderConversation(detail sessionDetail, query string, matches []int, curre
 	var b strings.Builder
 
 	// Header
-	b.WriteString(titleStyle.Render("Session: "+detail.Session.Summary) + "\n")
-	b.WriteString(fmt.Sprintf("Project: %s\n", detail.Session.Project))
-	b.WriteString(fmt.Sprintf("Messages: %d\n", detail.Session.MessageCount))
-	b.WriteString(strings.Repeat("─", width) + "\n\n")
+	b.WriteString(renderDetailHeader(detail, width))
 
 	// Messages - render WITHOUT highlighting first
+	// Runs of tool-only messages collapse into a single summary line
+	var toolRun []messageItem
+	flushToolRun := func() {
+		if len(toolRun) > 0 {
+			b.WriteString(toolStyle.Render(collapsedToolLine(toolRun)))
+			b.WriteString("\n\n")
+			toolRun = nil
+		}
+	}
+
 	for _, msg := range detail.Messages {
+		if len(toolRun) > 0 && toolRun[0].Branch != msg.Branch {
+			flushToolRun()
+		}
+		if msg.IsToolOnly {
+			toolRun = append(toolRun, msg)
+			if len(msg.Subagents) > 0 {
+				flushToolRun()
+				renderSubagents(&b, msg.Subagents, detail.ShowSubagents, width)
+			}
+			continue
+		}
+		flushToolRun()
+
 		var style lipgloss.Style
 		var label string
 
@@ -63,6 +84,10 @@ func renderConversation(detail sessionDetail, query string, matches []int, curre
 		b.WriteString(style.Render(fmt.Sprintf("▸ %s", label)))
 		b.WriteString(" ")
 		b.WriteString(timestampStyle.Render(formatTime(msg.Timestamp)))
+		if msg.Branch > 0 {
+			b.WriteString(" ")
+			b.WriteString(branchStyle.Render(fmt.Sprintf("⎇ branch %d", msg.Branch)))
+		}
 		b.WriteString("\n")
 
 		// Word wrap content
@@ -76,8 +101,11 @@ func renderConversation(detail sessionDetail, query string, matches []int, curre
 		// Add content without highlighting yet
 		b.WriteString(wrappedContent)
 		b.WriteString("\n\n")
+		renderSubagents(&b, msg.Subagents, detail.ShowSubagents, width)
 		b.WriteString(strings.Repeat("─", width) + "\n\n")
 	}
+	flushToolRun()
+	renderSubagents(&b, detail.UnlinkedSubagents, detail.ShowSubagents, width)
 
 	baseContent := b.String()
 
@@ -114,6 +142,172 @@ func renderConversation(detail sessionDetail, query string, matches []int, curre
 	}
 }
 
+// renderDetailHeader renders the lines above the conversation, ending with
+// a separator and a blank line
+func renderDetailHeader(detail sessionDetail, width int) string {
+	var b strings.Builder
+	b.WriteString(titleStyle.Render("Session: "+detail.Session.Summary) + "\n")
+	b.WriteString(fmt.Sprintf("Project: %s\n", detail.Session.Project))
+	b.WriteString(fmt.Sprintf("Messages: %d\n", detail.Session.MessageCount))
+	renderSummaryDetails(&b, detail.SummaryDetails, width)
+	if branches := countBranches(detail.AllMessages); branches > 0 {
+		if len(detail.Messages) < len(detail.AllMessages) {
+			b.WriteString(branchStyle.Render(fmt.Sprintf("Active path only - %d abandoned branch(es) hidden (b to show)", branches)) + "\n")
+		} else {
+			b.WriteString(branchStyle.Render(fmt.Sprintf("All branches - %d abandoned branch(es) marked ⎇ (b for active path only)", branches)) + "\n")
+		}
+	}
+	b.WriteString(strings.Repeat("─", width) + "\n\n")
+	return b.String()
+}
+
+// maxDetailItems is how many of each structured summary list the header shows
+const maxDetailItems = 5
+
+// renderSummaryDetails renders the structured LLM summary: goal and outcome,
+// then what was left unfinished (what you want to know before resuming),
+// decisions, files changed and commands run
+func renderSummaryDetails(b *strings.Builder, d *db.SummaryDetails, width int) {
+	if d == nil {
+		return
+	}
+	line := func(label, text string) {
+		b.WriteString(wordwrap.String(detailLabelStyle.Render(label+":")+" "+text, width) + "\n")
+	}
+	bullets := func(label string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		b.WriteString(detailLabelStyle.Render(label+":") + "\n")
+		for i, item := range items {
+			if i == maxDetailItems {
+				b.WriteString(fmt.Sprintf("  … and %d more\n", len(items)-i))
+				break
+			}
+			b.WriteString(wordwrap.String("  • "+item, width) + "\n")
+		}
+	}
+
+	if d.Goal != "" {
+		line("Goal", d.Goal)
+	}
+	if d.Outcome != "" {
+		line("Outcome", d.Outcome)
+	}
+	bullets("Left unfinished", d.TODOs)
+	bullets("Decisions", d.Decisions)
+	if len(d.FilesChanged) > 0 {
+		line("Files changed", strings.Join(d.FilesChanged, ", "))
+	}
+	if len(d.Commands) > 0 {
+		line("Commands", strings.Join(d.Commands, "; "))
+	}
+}
+
+// collapsedToolLine summarizes a run of tool-only messages,
+// e.g. "⋯ 4 tool steps: Bash, Read"
+func collapsedToolLine(run []messageItem) string {
+	prefix := ""
+	if run[0].Branch > 0 {
+		prefix = fmt.Sprintf("⎇ branch %d ", run[0].Branch)
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, msg := range run {
+		for _, name := range msg.ToolNames {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+
+	line := fmt.Sprintf("%s⋯ %d tool step", prefix, len(run))
+	if len(run) != 1 {
+		line += "s"
+	}
+	if len(names) > 0 {
+		line += ": " + strings.Join(names, ", ")
+	}
+	return line
+}
+
+// renderSubagents renders subagent runs as a one-line summary each, or when
+// expanded, as transcripts nested under a "│ " gutter
+func renderSubagents(b *strings.Builder, subagents []subagentItem, expanded bool, width int) {
+	for _, sub := range subagents {
+		if !expanded {
+			b.WriteString(subagentStyle.Render("↳ " + sub.Title + " (a to expand)"))
+			b.WriteString("\n\n")
+			continue
+		}
+
+		gutter := subagentStyle.Render("│ ")
+		b.WriteString(subagentStyle.Render("↳ " + sub.Title))
+		b.WriteString("\n")
+
+		wrapWidth := width - 12
+		if wrapWidth < 40 {
+			wrapWidth = 40
+		}
+
+		var toolRun []messageItem
+		flushToolRun := func() {
+			if len(toolRun) > 0 {
+				b.WriteString(gutter + toolStyle.Render(collapsedToolLine(toolRun)) + "\n")
+				b.WriteString(gutter + "\n")
+				toolRun = nil
+			}
+		}
+		for _, msg := range sub.Messages {
+			if msg.IsToolOnly {
+				toolRun = append(toolRun, msg)
+				continue
+			}
+			flushToolRun()
+
+			style, label := assistantStyle, "ASSISTANT"
+			if msg.Type == "user" {
+				style, label = userStyle, "USER"
+			}
+			b.WriteString(gutter + style.Render("▸ "+label) + " " + timestampStyle.Render(formatTime(msg.Timestamp)) + "\n")
+			for _, line := range strings.Split(wordwrap.String(msg.Content, wrapWidth), "\n") {
+				b.WriteString(gutter + line + "\n")
+			}
+			b.WriteString(gutter + "\n")
+		}
+		flushToolRun()
+		b.WriteString("\n")
+	}
+}
+
+// branchMessages returns the messages to show: all of them, or only those on
+// the active path
+func branchMessages(messages []messageItem, all bool) []messageItem {
+	if all {
+		return messages
+	}
+	var active []messageItem
+	for _, msg := range messages {
+		if msg.Branch == 0 {
+			active = append(active, msg)
+		}
+	}
+	return active
+}
+
+// countBranches returns how many abandoned branches have messages to show
+func countBranches(messages []messageItem) int {
+	branches := make(map[int]bool)
+	for _, msg := range messages {
+		if msg.Branch > 0 {
+			branches[msg.Branch] = true
+		}
+	}
+	return len(branches)
+}
+
 func (m Model) updateDetail(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	// Handle in-session search mode
 	if m.inSessionSearchMode {
@@ -330,6 +524,28 @@ func (m Model) updateDetail(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		}
 		return m, nil
 
+	case "b":
+		// Toggle between the active path and all branches
+		if m.currentSession != nil {
+			m.showAllBranches = !m.showAllBranches
+			m.currentSession.Messages = branchMessages(m.currentSession.AllMessages, m.showAllBranches)
+			m.inSessionMatches = nil
+			m.matchOccurrences = nil
+			m.viewport = createViewport(*m.currentSession, m.width, m.height)
+		}
+		return m, nil
+
+	case "a":
+		// Expand or collapse subagent transcripts
+		if m.currentSession != nil {
+			m.showSubagents = !m.showSubagents
+			m.currentSession.ShowSubagents = m.showSubagents
+			m.inSessionMatches = nil
+			m.matchOccurrences = nil
+			m.viewport = createViewport(*m.currentSession, m.width, m.height)
+		}
+		return m, nil
+
 	case "ctrl+f", "/":
 		m.inSessionSearchMode = true
 		m.inSessionSearch.Focus()
@@ -445,9 +661,8 @@ func findMatchesInRenderedContent(detail sessionDetail, query string, width int)
 	var matchOccurrences []matchOccurrenceInfo
 	queryLower := strings.ToLower(query)
 
-	// Header is lines 0-4 (title, project, messages, separator, blank line)
-	// Skip header lines - only include matches in message content (line 5+)
-	const headerLines = 5
+	// Skip header lines - only include matches in message content
+	headerLines := strings.Count(renderDetailHeader(detail, width), "\n")
 
 	for lineNum, line := range lines {
 		if lineNum < headerLines {
@@ -770,7 +985,7 @@ func (m Model) viewDetail() string {
 		content += searchBox
 	} else {
 		footer := fmt.Sprintf("\n%3.f%%", m.viewport.ScrollPercent()*100)
-		footer += "\n\ne: export | r: resume | f: fork | o: open in new terminal | c: copy | /: search | j/k: scroll | esc: back | q: quit"
+		footer += "\n\ne: export | r: resume | f: fork | o: open in new terminal | c: copy | /: search | b: branches | a: subagents | j/k: scroll | esc: back | q: quit"
 		content += footer
 	}
 
@@ -39,6 +39,8 @@ SESSION DETAIL VIEW
   o            Open session in new terminal window
   c            Copy resume command to clipboard
   /            Search within session
+  b            Toggle abandoned branches (edited prompts)
+  a            Expand/collapse subagent transcripts
   j/k          Scroll line by line
   d/u          Scroll half page
   g/G          Jump to top/bottom
@@ -50,6 +52,9 @@ SEARCH VIEW
   Type         Enter search query (all keys work, min 2 chars)
   Ctrl+j/k     Navigate results (or use arrow keys ↑↓)
   Enter        Open selected session
+  Ctrl+r       Toggle regex mode (Go regular expression)
+  ↑            At the top of results: recall earlier searches
+  @name        Pick a saved search (↑↓ to select, Enter/Tab to run)
   esc          Back to session list
 
 Press any key to return to session list
@@ -126,7 +126,8 @@ func (m Model) updateList(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 
 	case "/":
 		m.mode = searchView
-		return m, nil
+		m.searchHistoryIdx = -1
+		return m, loadSavedSearches(m.db)
 
 	case "p":
 		// Toggle project filter
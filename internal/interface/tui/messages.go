@@ -1,6 +1,8 @@
 package tui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -8,9 +10,13 @@ import (
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/neilberkman/ccrider/internal/core/config"
 	"github.com/neilberkman/ccrider/internal/core/db"
+	"github.com/neilberkman/ccrider/internal/core/export"
 	"github.com/neilberkman/ccrider/internal/core/importer"
+	"github.com/neilberkman/ccrider/internal/core/llm"
 	"github.com/neilberkman/ccrider/internal/core/search"
+	"github.com/neilberkman/ccrider/internal/core/watcher"
 )
 
 type errMsg struct {
@@ -34,66 +40,86 @@ type sessionLaunchInfoMsg struct {
 }
 
 type searchResultsMsg struct {
-	results []searchResult
+	query      string
+	mode       string
+	results    []searchResult
+	offset     int // Offset of this page (0 for a new search)
+	total      int
+	nextOffset int
+	queryErr   error // The query couldn't be parsed (shown inline, not as a failure)
 }
 
+type savedSearchesLoadedMsg struct {
+	saved   []db.SavedSearch
+	history []db.SearchHistoryEntry
+}
+
+// searchHistorySize is how many recent searches up-arrow can recall
+const searchHistorySize = 50
+
+// searchPageSize is how many sessions each page of search results loads
+const searchPageSize = 50
+
 type exportCompletedMsg struct {
 	success  bool
 	filePath string
 	err      error
 }
 
-func performSearch(database *db.DB, query string) tea.Cmd {
+// performSearch runs a search in mode: "" for keyword search, "regex", or
+// "semantic" or "hybrid" (from saved searches and history)
+func performSearch(database *db.DB, query string, offset int, mode string) tea.Cmd {
 	return func() tea.Msg {
-		// Strip any quotes from the query (user shouldn't need to escape)
-		query = strings.Trim(query, "\"")
-		query = strings.ReplaceAll(query, "\\\"", "\"")
-
-		// Parse filters from query (interface concern - normalizing user input)
-		tuiFilters := ParseSearchQuery(query)
-		searchQuery := tuiFilters.Query
-		if searchQuery == "" {
-			searchQuery = query // Fallback if only filters
+		rawQuery := query
+
+		// Unescape quotes pasted from a shell (interface concern - normalizing user input)
+		if mode != "regex" {
+			query = strings.ReplaceAll(query, "\\\"", "\"")
 		}
 
-		// Convert TUI filters to core filters
+		// Phrases, operators and field filters are parsed by core search
 		coreFilters := search.SearchFilters{
-			Query:       searchQuery,
-			ProjectPath: tuiFilters.Project,
-		}
-		if tuiFilters.HasAfter {
-			coreFilters.AfterDate = tuiFilters.AfterDate.Format(time.RFC3339)
+			Query:      query,
+			Limit:      searchPageSize,
+			Offset:     offset,
+			MatchLimit: 3, // Limit to 3 matches per session for display
 		}
-		if tuiFilters.HasBefore {
-			coreFilters.BeforeDate = tuiFilters.BeforeDate.Format(time.RFC3339)
+		if mode == "regex" {
+			coreFilters.Mode = search.ModeRegex
 		}
 
 		// Call core search with filters (business logic in core)
-		coreResults, err := search.SearchWithFilters(database, coreFilters)
+		var page *search.SessionSearchPage
+		var err error
+		if mode == "semantic" || mode == "hybrid" {
+			page, err = semanticSearch(database, coreFilters, mode == "hybrid")
+		} else {
+			page, err = search.SearchWithFilters(database, coreFilters)
+		}
+		var queryErr *search.QueryError
+		if errors.As(err, &queryErr) {
+			return searchResultsMsg{query: rawQuery, mode: mode, results: []searchResult{}, offset: offset, queryErr: err}
+		}
 		if err != nil {
 			return errMsg{err}
 		}
 
 		// Convert core types to interface types (interface concern - presentation)
 		var results []searchResult
-		for _, coreSession := range coreResults {
+		for _, coreSession := range page.Sessions {
 			result := searchResult{
-				SessionID: coreSession.SessionID,
-				Summary:   coreSession.SessionSummary,
-				Project:   coreSession.ProjectPath,
-				UpdatedAt: coreSession.UpdatedAt,
-				Matches:   []matchInfo{},
-			}
-
-			// Limit to 3 matches per session for display (interface concern)
-			matchLimit := 3
-			if len(coreSession.Matches) > matchLimit {
-				coreSession.Matches = coreSession.Matches[:matchLimit]
+				SessionID:  coreSession.SessionID,
+				Summary:    coreSession.SessionSummary,
+				Project:    coreSession.ProjectPath,
+				UpdatedAt:  coreSession.UpdatedAt,
+				Matches:    []matchInfo{},
+				MatchCount: coreSession.MatchCount,
 			}
 
 			for _, match := range coreSession.Matches {
 				result.Matches = append(result.Matches, matchInfo{
 					MessageType: "message",
+					Tool:        match.ToolName,
 					Snippet:     match.MessageText,
 					Sequence:    0,
 				})
@@ -102,12 +128,60 @@ func performSearch(database *db.DB, query string) tea.Cmd {
 			results = append(results, result)
 		}
 
-		// Limit to 50 sessions for display (interface concern - pagination)
-		if len(results) > 50 {
-			results = results[:50]
+		return searchResultsMsg{
+			query:      rawQuery,
+			mode:       mode,
+			results:    results,
+			offset:     offset,
+			total:      page.Total,
+			nextOffset: page.NextOffset,
+		}
+	}
+}
+
+// loadSavedSearches loads saved searches and recent history for the search view
+func loadSavedSearches(database *db.DB) tea.Cmd {
+	return func() tea.Msg {
+		saved, err := database.ListSavedSearches()
+		if err != nil {
+			return errMsg{err}
+		}
+		history, err := database.ListSearchHistory(searchHistorySize)
+		if err != nil {
+			return errMsg{err}
 		}
+		return savedSearchesLoadedMsg{saved: saved, history: history}
+	}
+}
+
+// semanticSearch embeds messages added since the last search, then searches
+// by meaning with the configured embedder
+func semanticSearch(database *db.DB, filters search.SearchFilters, hybrid bool) (*search.SessionSearchPage, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+	embedder, err := llm.NewEmbedder(llm.EmbedderConfig{
+		Provider: cfg.Embeddings.Provider,
+		Model:    cfg.Embeddings.Model,
+		URL:      cfg.Embeddings.URL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	if _, err := search.IndexEmbeddings(ctx, database, embedder, nil); err != nil {
+		return nil, fmt.Errorf("embedding failed: %w", err)
+	}
+	return search.SearchSemantic(ctx, database, embedder, filters, hybrid)
+}
 
-		return searchResultsMsg{results: results}
+// recordSearch adds a search to the history shared with the CLI
+func recordSearch(database *db.DB, query, mode string) tea.Cmd {
+	return func() tea.Msg {
+		_ = database.AddSearchHistory(query, mode)
+		return nil
 	}
 }
 
@@ -208,26 +282,46 @@ func loadSessionDetail(database *db.DB, sessionID string) tea.Cmd {
 			CreatedAt:    coreDetail.UpdatedAt.Format("2006-01-02 15:04:05"), // Use UpdatedAt as fallback
 		}
 
-		var messages []messageItem
-		for _, coreMsg := range coreDetail.Messages {
-			messages = append(messages, messageItem{
-				Type:      coreMsg.Type,
-				Content:   coreMsg.Content,
-				Timestamp: coreMsg.Timestamp.Format(time.RFC3339),
-			})
-		}
-
 		return sessionDetailLoadedMsg{
 			detail: sessionDetail{
-				Session:   session,
-				Messages:  messages,
-				LastCwd:   coreDetail.LastCwd,
-				UpdatedAt: session.UpdatedAt,
+				Session:           session,
+				AllMessages:       toMessageItems(coreDetail.Messages),
+				LastCwd:           coreDetail.LastCwd,
+				UpdatedAt:         session.UpdatedAt,
+				SummaryDetails:    coreDetail.SummaryDetails,
+				UnlinkedSubagents: toSubagentItems(coreDetail.UnlinkedSubagents),
 			},
 		}
 	}
 }
 
+func toMessageItems(coreMessages []db.SessionMessage) []messageItem {
+	var messages []messageItem
+	for _, coreMsg := range coreMessages {
+		messages = append(messages, messageItem{
+			Type:       coreMsg.Type,
+			Content:    coreMsg.Content,
+			Timestamp:  coreMsg.Timestamp.Format(time.RFC3339),
+			IsToolOnly: coreMsg.IsToolOnly,
+			ToolNames:  coreMsg.ToolNames,
+			Branch:     coreMsg.Branch,
+			Subagents:  toSubagentItems(coreMsg.Subagents),
+		})
+	}
+	return messages
+}
+
+func toSubagentItems(runs []db.SubagentRun) []subagentItem {
+	var items []subagentItem
+	for _, run := range runs {
+		items = append(items, subagentItem{
+			Title:    export.SubagentTitle(run),
+			Messages: toMessageItems(run.Messages),
+		})
+	}
+	return items
+}
+
 type syncProgressMsg struct {
 	current         int
 	total           int
@@ -325,6 +419,10 @@ func syncSubscribe(progressCh chan syncProgressMsg, database *db.DB, filterByPro
 }
 
 func syncSessions(database *db.DB, filterByProject bool, projectPath string) tea.Cmd {
+	// A running `ccrider watch` keeps the database current - just reload
+	if watcher.IsRunning(database.Path()) {
+		return loadSessions(database, filterByProject, projectPath)
+	}
 	return startSyncWithProgress(database, filterByProject, projectPath)
 }
 
@@ -357,21 +455,7 @@ func exportSessionToPath(database *db.DB, sessionID string, filePath string) tea
 			filePath = filepath.Join(cwd, filePath)
 		}
 
-		// Query session data
-		var sessionInternalID int64
-		var summary, project, createdAt, updatedAt string
-		var messageCount int
-		err = database.QueryRow(`
-			SELECT
-				id,
-				COALESCE(summary, ''),
-				project_path,
-				created_at,
-				updated_at,
-				(SELECT COUNT(*) FROM messages WHERE session_id = sessions.id) as message_count
-			FROM sessions
-			WHERE session_id = ?
-		`, sessionID).Scan(&sessionInternalID, &summary, &project, &createdAt, &updatedAt, &messageCount)
+		markdown, err := export.Markdown(database, sessionID)
 		if err != nil {
 			return exportCompletedMsg{
 				success: false,
@@ -379,85 +463,8 @@ func exportSessionToPath(database *db.DB, sessionID string, filePath string) tea
 			}
 		}
 
-		// Get all messages
-		rows, err := database.Query(`
-			SELECT type, COALESCE(sender, ''), COALESCE(text_content, ''), timestamp, sequence
-			FROM messages
-			WHERE session_id = ?
-			ORDER BY sequence ASC
-		`, sessionInternalID)
-		if err != nil {
-			return exportCompletedMsg{
-				success: false,
-				err:     err,
-			}
-		}
-		defer func() { _ = rows.Close() }()
-
-		// Build markdown content
-		var b strings.Builder
-
-		// Header
-		b.WriteString("# ")
-		b.WriteString(summary)
-		b.WriteString("\n\n")
-
-		// Metadata
-		b.WriteString("**Session ID:** `")
-		b.WriteString(sessionID)
-		b.WriteString("`  \n")
-		b.WriteString("**Project:** `")
-		b.WriteString(project)
-		b.WriteString("`  \n")
-		b.WriteString("**Created:** ")
-		b.WriteString(formatTimestampForExport(createdAt))
-		b.WriteString("  \n")
-		b.WriteString("**Updated:** ")
-		b.WriteString(formatTimestampForExport(updatedAt))
-		b.WriteString("  \n")
-		b.WriteString("**Messages:** ")
-		b.WriteString(fmt.Sprintf("%d", messageCount))
-		b.WriteString("\n\n")
-		b.WriteString("---\n\n")
-
-		// Messages
-		for rows.Next() {
-			var msgType, sender, content, timestamp string
-			var sequence int
-			if err := rows.Scan(&msgType, &sender, &content, &timestamp, &sequence); err != nil {
-				continue
-			}
-
-			// Skip summary entries
-			if msgType == "summary" {
-				continue
-			}
-
-			// Determine label
-			label := strings.ToUpper(msgType)
-			if sender != "" {
-				label = strings.ToUpper(sender)
-			}
-
-			// Message header
-			b.WriteString("**")
-			b.WriteString(label)
-			b.WriteString("**")
-			b.WriteString(" _")
-			b.WriteString(formatTimestampForExport(timestamp))
-			b.WriteString("_\n\n")
-
-			// Content (no truncation)
-			if content != "" {
-				b.WriteString(content)
-				b.WriteString("\n\n")
-			}
-
-			b.WriteString("---\n\n")
-		}
-
 		// Write to file
-		if err := os.WriteFile(filePath, []byte(b.String()), 0644); err != nil {
+		if err := os.WriteFile(filePath, []byte(markdown), 0644); err != nil {
 			return exportCompletedMsg{
 				success: false,
 				err:     err,
@@ -479,21 +486,3 @@ func generateDefaultFilename(sessionID string) string {
 	}
 	return fmt.Sprintf("session-%s.md", shortID)
 }
-
-func formatTimestampForExport(ts string) string {
-	// Try parsing various formats
-	formats := []string{
-		"2006-01-02T15:04:05.999Z07:00",
-		"2006-01-02 15:04:05",
-		"2006-01-02T15:04:05",
-	}
-
-	for _, format := range formats {
-		if t, err := time.Parse(format, ts); err == nil {
-			return t.Format("Jan 02, 2006 15:04:05")
-		}
-	}
-
-	// If parsing fails, return as-is
-	return ts
-}
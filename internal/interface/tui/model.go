@@ -33,8 +33,10 @@ type Model struct {
 	err      error
 
 	// Current session data
-	sessions       []sessionItem
-	currentSession *sessionDetail
+	sessions        []sessionItem
+	currentSession  *sessionDetail
+	showAllBranches bool // Show abandoned branches in the detail view, not just the active path
+	showSubagents   bool // Expand subagent transcripts in the detail view
 
 	// Project filter state
 	projectFilterEnabled bool
@@ -45,6 +47,15 @@ type Model struct {
 	searchResults     []searchResult
 	searchSelectedIdx int
 	searchViewOffset  int // First visible result index (for scrolling)
+	searchTotal       int // Sessions matching the query, across all pages
+	searchNextOffset  int // Offset of the next page of results (0 once all are loaded)
+	searchLoadingMore bool
+	searchErr         error  // Why the current query can't be searched, if it can't
+	searchMode        string // "", "regex" (ctrl+r), or "semantic"/"hybrid" from a saved search
+	savedSearches     []db.SavedSearch
+	searchHistory     []db.SearchHistoryEntry // Most recent first
+	searchHistoryIdx  int                     // History entry shown in the input (-1 for none)
+	searchPickerIdx   int                     // Selected saved search while typing @name
 
 	// In-session search state
 	inSessionSearch         textinput.Model
@@ -89,16 +100,31 @@ type sessionItem struct {
 }
 
 type sessionDetail struct {
-	Session   sessionItem
-	Messages  []messageItem
-	LastCwd   string // Last working directory from messages
-	UpdatedAt string // When session was last active
+	Session     sessionItem
+	Messages    []messageItem // Messages shown (active path only, or all branches)
+	AllMessages []messageItem // Every message, including abandoned branches
+	LastCwd     string        // Last working directory from messages
+	UpdatedAt   string        // When session was last active
+
+	SummaryDetails *db.SummaryDetails // Structured LLM summary, if any
+
+	UnlinkedSubagents []subagentItem // Subagent runs whose Task call wasn't found
+	ShowSubagents     bool           // Render subagent transcripts expanded
 }
 
 type messageItem struct {
-	Type      string
-	Content   string
-	Timestamp string
+	Type       string
+	Content    string
+	Timestamp  string
+	IsToolOnly bool           // Rendered collapsed, not as a full message
+	ToolNames  []string       // Tools invoked by this message
+	Branch     int            // 0 on the active path, otherwise which abandoned branch
+	Subagents  []subagentItem // Subagent runs launched by this message
+}
+
+type subagentItem struct {
+	Title    string
+	Messages []messageItem
 }
 
 type searchResult struct {
@@ -107,10 +133,13 @@ type searchResult struct {
 	Project   string
 	UpdatedAt string
 	Matches   []matchInfo
+	// Total matching messages (Matches holds the first few)
+	MatchCount int
 }
 
 type matchInfo struct {
 	MessageType string
+	Tool        string // Tool whose input or output matched, if any
 	Snippet     string
 	Sequence    int
 }
@@ -144,6 +173,7 @@ func New(database *db.DB) Model {
 		mode:                 listView,
 		list:                 emptyList,
 		searchInput:          ti,
+		searchHistoryIdx:     -1,
 		inSessionSearch:      inSessionTi,
 		projectFilterEnabled: false, // Disabled by default
 		currentDirectory:     currentDir,
@@ -192,7 +222,7 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.Action == tea.MouseActionPress && (msg.Button == tea.MouseButtonWheelDown || msg.Button == tea.MouseButtonWheelUp) {
 			switch m.mode {
 			case searchView:
-				return handleSearchMouseWheel(m, msg.Button == tea.MouseButtonWheelDown), nil
+				return handleSearchMouseWheel(m, msg.Button == tea.MouseButtonWheelDown).loadMoreSearchResults()
 			case listView:
 				// Pass mouse events to the list
 				var cmd tea.Cmd
@@ -292,6 +322,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, nil
 
 	case sessionDetailLoadedMsg:
+		msg.detail.Messages = branchMessages(msg.detail.AllMessages, m.showAllBranches)
+		msg.detail.ShowSubagents = m.showSubagents
 		m.currentSession = &msg.detail
 		m.viewport = createViewport(msg.detail, m.width, m.height)
 		m.mode = detailView
@@ -307,8 +339,26 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			msg.summary,
 		)
 
+	case savedSearchesLoadedMsg:
+		m.savedSearches = msg.saved
+		m.searchHistory = msg.history
+		return m, nil
+
 	case searchResultsMsg:
-		m.searchResults = msg.results
+		if msg.offset > 0 {
+			// Next page - drop it if the query or mode changed while it loaded
+			if msg.query != m.searchInput.Value() || msg.mode != m.searchMode {
+				m.searchLoadingMore = false
+				return m, nil
+			}
+			m.searchResults = append(m.searchResults, msg.results...)
+		} else {
+			m.searchResults = msg.results
+		}
+		m.searchTotal = msg.total
+		m.searchNextOffset = msg.nextOffset
+		m.searchLoadingMore = false
+		m.searchErr = msg.queryErr
 		return m, nil
 
 	case sessionLaunchedMsg:
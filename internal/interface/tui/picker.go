@@ -0,0 +1,246 @@
+package tui
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/neilberkman/ccrider/internal/core/db"
+	"github.com/neilberkman/ccrider/internal/core/fuzzy"
+)
+
+// pickSummaryWidth is how much of a summary a picker row shows
+const pickSummaryWidth = 60
+
+// pickFieldSep separates a candidate's fields, so each field starts a word
+const pickFieldSep = "  "
+
+// pickCandidate is a session as the picker matches and shows it
+type pickCandidate struct {
+	sessionID string
+	fields    []string // Summary, project, branch, session ID
+	text      string   // Fields joined with pickFieldSep, for matching
+}
+
+// Picker is a fuzzy finder over sessions (ccrider pick). Typing ranks
+// sessions by summary, project path, branch and session ID; enter picks one.
+type Picker struct {
+	input      textinput.Model
+	candidates []pickCandidate
+	results    []fuzzy.Result
+	selected   int
+	offset     int // First visible result
+	width      int
+	height     int
+	picked     string
+}
+
+// NewPicker creates a picker over sessions, starting with query typed in.
+// Sessions that match equally well keep their order.
+func NewPicker(sessions []db.Session, query string) Picker {
+	ti := textinput.New()
+	ti.Placeholder = "summary, project, branch or session ID"
+	ti.Prompt = ""
+	ti.Focus()
+	ti.SetValue(query)
+
+	home, _ := os.UserHomeDir()
+	var candidates []pickCandidate
+	for _, s := range sessions {
+		project := s.ProjectPath
+		if home != "" && strings.HasPrefix(project, home) {
+			project = "~" + strings.TrimPrefix(project, home)
+		}
+		fields := []string{strings.Join(strings.Fields(s.Summary), " "), project, s.Branch, s.SessionID}
+		candidates = append(candidates, pickCandidate{
+			sessionID: s.SessionID,
+			fields:    fields,
+			text:      strings.Join(fields, pickFieldSep),
+		})
+	}
+
+	p := Picker{input: ti, candidates: candidates}
+	p.rank()
+	return p
+}
+
+// Picked returns the session ID chosen, or "" if the picker was cancelled
+func (p Picker) Picked() string {
+	return p.picked
+}
+
+// Matches returns the IDs of the sessions matching the query, best first
+func (p Picker) Matches() []string {
+	ids := make([]string, len(p.results))
+	for i, r := range p.results {
+		ids[i] = p.candidates[r.Index].sessionID
+	}
+	return ids
+}
+
+func (p Picker) Init() tea.Cmd {
+	return textinput.Blink
+}
+
+func (p Picker) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		p.width = msg.Width
+		p.height = msg.Height
+		return p.scrollToSelected(), nil
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "esc":
+			return p, tea.Quit
+		case "enter":
+			if p.selected < len(p.results) {
+				p.picked = p.candidates[p.results[p.selected].Index].sessionID
+			}
+			return p, tea.Quit
+		case "ctrl+n", "ctrl+j", "down":
+			if p.selected < len(p.results)-1 {
+				p.selected++
+			}
+			return p.scrollToSelected(), nil
+		case "ctrl+p", "up":
+			if p.selected > 0 {
+				p.selected--
+			}
+			return p.scrollToSelected(), nil
+		}
+	}
+
+	var cmd tea.Cmd
+	query := p.input.Value()
+	p.input, cmd = p.input.Update(msg)
+	if p.input.Value() != query {
+		p.rank()
+	}
+	return p, cmd
+}
+
+// rank reranks the sessions for the current query
+func (p *Picker) rank() {
+	texts := make([]string, len(p.candidates))
+	for i, c := range p.candidates {
+		texts[i] = c.text
+	}
+	p.results = fuzzy.Rank(p.input.Value(), texts)
+	p.selected = 0
+	p.offset = 0
+}
+
+// visibleRows is how many results fit below the prompt
+func (p Picker) visibleRows() int {
+	if p.height <= 3 {
+		return 10
+	}
+	return p.height - 3
+}
+
+func (p Picker) scrollToSelected() Picker {
+	rows := p.visibleRows()
+	if p.selected < p.offset {
+		p.offset = p.selected
+	}
+	if p.selected >= p.offset+rows {
+		p.offset = p.selected - rows + 1
+	}
+	return p
+}
+
+func (p Picker) View() string {
+	var b strings.Builder
+	b.WriteString(searchHeaderStyle.Render("Pick: "))
+	b.WriteString(p.input.View())
+	b.WriteString("  ")
+	b.WriteString(searchMetaStyle.Render(fmt.Sprintf("%d/%d", len(p.results), len(p.candidates))))
+	b.WriteString("\n")
+
+	end := p.offset + p.visibleRows()
+	if end > len(p.results) {
+		end = len(p.results)
+	}
+	for i := p.offset; i < end; i++ {
+		b.WriteString(p.viewRow(p.results[i], i == p.selected))
+		b.WriteString("\n")
+	}
+
+	b.WriteString(searchMetaStyle.Render("↑↓: select | Enter: print session ID | esc: cancel"))
+	return b.String()
+}
+
+// viewRow renders a result on one line, highlighting the matched characters
+func (p Picker) viewRow(r fuzzy.Result, selected bool) string {
+	c := p.candidates[r.Index]
+	matched := make(map[int]bool, len(r.Positions))
+	for _, pos := range r.Positions {
+		matched[pos] = true
+	}
+
+	prefix := "  "
+	summaryStyle := lipgloss.NewStyle()
+	if selected {
+		prefix = "► "
+		summaryStyle = searchSelectedStyle
+	}
+
+	var parts []string
+	offset := 0
+	for i, field := range c.fields {
+		style, width := searchMetaStyle, 0
+		switch i {
+		case 0:
+			style, width = summaryStyle, pickSummaryWidth
+		case 3:
+			width = 8 // Enough of the ID to recognize it
+		}
+		if field != "" {
+			parts = append(parts, highlightRunes(field, offset, width, matched, style))
+		} else if i == 0 {
+			parts = append(parts, searchMetaStyle.Render("[No summary]"))
+		}
+		offset += len([]rune(field)) + len([]rune(pickFieldSep))
+	}
+
+	line := prefix + strings.Join(parts, pickFieldSep)
+	if p.width > 0 {
+		line = lipgloss.NewStyle().MaxWidth(p.width).Render(line)
+	}
+	return line
+}
+
+// highlightRunes renders up to width runes of s (all of it if width is 0),
+// styling the runes whose offset in the candidate text is matched
+func highlightRunes(s string, offset, width int, matched map[int]bool, style lipgloss.Style) string {
+	runes := []rune(s)
+	truncated := width > 0 && len(runes) > width
+	if truncated {
+		runes = runes[:width-1]
+	}
+
+	var b strings.Builder
+	start := 0
+	for start < len(runes) {
+		// Render each run of matched or unmatched runes in one piece
+		isMatch := matched[offset+start]
+		end := start + 1
+		for end < len(runes) && matched[offset+end] == isMatch {
+			end++
+		}
+		if isMatch {
+			b.WriteString(searchMatchStyle.Render(string(runes[start:end])))
+		} else {
+			b.WriteString(style.Render(string(runes[start:end])))
+		}
+		start = end
+	}
+	if truncated {
+		b.WriteString(style.Render("…"))
+	}
+	return b.String()
+}
@@ -5,25 +5,50 @@ import (
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/neilberkman/ccrider/internal/core/db"
+	"github.com/neilberkman/ccrider/internal/core/search"
 )
 
 func (m Model) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
+	// Typing @name picks a saved search
+	if picks := m.savedSearchPicks(); len(picks) > 0 {
+		switch msg.String() {
+		case "ctrl+j", "ctrl+n", "down":
+			if m.searchPickerIdx < len(picks)-1 {
+				m.searchPickerIdx++
+			}
+			return m, nil
+		case "ctrl+p", "up":
+			if m.searchPickerIdx > 0 {
+				m.searchPickerIdx--
+			}
+			return m, nil
+		case "enter", "tab":
+			return m.runSavedSearch(picks[m.searchPickerIdx])
+		}
+	}
+
 	switch msg.String() {
 	case "esc":
 		m.mode = listView
 		m.searchInput.SetValue("")
 		m.searchResults = nil
+		m.searchErr = nil
 		m.searchSelectedIdx = 0
 		m.searchViewOffset = 0
+		m.searchHistoryIdx = -1
 		return m, nil
 
 	case "enter":
 		// Open selected session
 		if len(m.searchResults) > 0 && m.searchSelectedIdx < len(m.searchResults) {
 			sessionID := m.searchResults[m.searchSelectedIdx].SessionID
-			return m, loadSessionDetail(m.db, sessionID)
+			return m, tea.Batch(
+				recordSearch(m.db, m.searchInput.Value(), m.searchMode),
+				loadSessionDetail(m.db, sessionID),
+			)
 		}
 		return m, nil
 
@@ -35,48 +60,150 @@ func (m Model) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			if m.searchSelectedIdx >= len(m.searchResults) {
 				m.searchSelectedIdx = len(m.searchResults) - 1
 			}
-			return adjustSearchViewport(m), nil
+			return adjustSearchViewport(m).loadMoreSearchResults()
+		}
+		// No results to move through - step forward in history
+		if m.searchHistoryIdx > 0 {
+			return m.recallSearch(m.searchHistoryIdx - 1)
 		}
 		return m, nil
 
+	case "ctrl+r":
+		// Toggle regex mode (or leave a saved search's semantic mode) and
+		// rerun the query
+		if m.searchMode == "" {
+			m.searchMode = "regex"
+		} else {
+			m.searchMode = ""
+		}
+		m.searchSelectedIdx = 0
+		m.searchViewOffset = 0
+		return m, performSearch(m.db, m.searchInput.Value(), 0, m.searchMode)
+
 	case "ctrl+p", "up":
-		if len(m.searchResults) > 0 {
+		if len(m.searchResults) > 0 && m.searchSelectedIdx > 0 {
 			m.searchSelectedIdx--
-			if m.searchSelectedIdx < 0 {
-				m.searchSelectedIdx = 0
-			}
 			return adjustSearchViewport(m), nil
 		}
+		// At the top - step back to an earlier search
+		if m.searchHistoryIdx < len(m.searchHistory)-1 {
+			return m.recallSearch(m.searchHistoryIdx + 1)
+		}
 		return m, nil
 	}
 
 	// Update text input (all other keys including j/k/q go here)
 	m.searchInput, cmd = m.searchInput.Update(msg)
+	m.searchHistoryIdx = -1
+	m.searchPickerIdx = 0
 
 	// Perform live search on every keystroke
 	query := m.searchInput.Value()
 	m.searchSelectedIdx = 0
 	m.searchViewOffset = 0 // Reset scroll on new search
-	return m, tea.Batch(cmd, performSearch(m.db, query))
+	if m.pickingSavedSearch() {
+		// Show the picker rather than searching for "@name"
+		m.searchResults = nil
+		m.searchErr = nil
+		return m, cmd
+	}
+	return m, tea.Batch(cmd, performSearch(m.db, query, 0, m.searchMode))
+}
+
+// pickingSavedSearch reports whether the input is an @name being typed
+func (m Model) pickingSavedSearch() bool {
+	query := m.searchInput.Value()
+	return strings.HasPrefix(query, "@") && !strings.ContainsAny(query, " \t")
+}
+
+// savedSearchPicks returns the saved searches whose names start with the
+// @name being typed
+func (m Model) savedSearchPicks() []db.SavedSearch {
+	if !m.pickingSavedSearch() {
+		return nil
+	}
+	prefix := strings.ToLower(strings.TrimPrefix(m.searchInput.Value(), "@"))
+	var picks []db.SavedSearch
+	for _, s := range m.savedSearches {
+		if strings.HasPrefix(strings.ToLower(s.Name), prefix) {
+			picks = append(picks, s)
+		}
+	}
+	return picks
+}
+
+// runSavedSearch replaces the input with a saved search and runs it in its
+// mode
+func (m Model) runSavedSearch(saved db.SavedSearch) (tea.Model, tea.Cmd) {
+	m.searchPickerIdx = 0
+	return m.runSearch(saved.Query, saved.Mode)
+}
+
+// recallSearch shows a history entry (0 is the most recent) and runs it
+func (m Model) recallSearch(idx int) (tea.Model, tea.Cmd) {
+	entry := m.searchHistory[idx]
+	updated, cmd := m.runSearch(entry.Query, entry.Mode)
+	model := updated.(Model)
+	model.searchHistoryIdx = idx
+	return model, cmd
+}
+
+// runSearch puts a query in the input and searches for it
+func (m Model) runSearch(query, mode string) (tea.Model, tea.Cmd) {
+	m.searchInput.SetValue(query)
+	m.searchInput.CursorEnd()
+	m.searchMode = mode
+	m.searchHistoryIdx = -1
+	m.searchSelectedIdx = 0
+	m.searchViewOffset = 0
+	return m, performSearch(m.db, query, 0, mode)
+}
+
+// loadMoreSearchResults fetches the next page of results once the selection
+// reaches the last loaded one
+func (m Model) loadMoreSearchResults() (Model, tea.Cmd) {
+	if m.searchNextOffset == 0 || m.searchLoadingMore || m.searchSelectedIdx < len(m.searchResults)-1 {
+		return m, nil
+	}
+	m.searchLoadingMore = true
+	return m, performSearch(m.db, m.searchInput.Value(), m.searchNextOffset, m.searchMode)
+}
+
+// renderMatch styles a matched term in a search snippet
+func renderMatch(s string) string {
+	return searchMatchStyle.Render(s)
 }
 
 func (m Model) viewSearch() string {
 	var b strings.Builder
 
 	// Header with search input - ALWAYS at top
-	b.WriteString(searchHeaderStyle.Render("Search: "))
+	switch m.searchMode {
+	case "regex":
+		b.WriteString(searchHeaderStyle.Render("Regex: "))
+	case "semantic":
+		b.WriteString(searchHeaderStyle.Render("Semantic: "))
+	case "hybrid":
+		b.WriteString(searchHeaderStyle.Render("Hybrid: "))
+	default:
+		b.WriteString(searchHeaderStyle.Render("Search: "))
+	}
 	b.WriteString(m.searchInput.View())
 	b.WriteString("\n")
 	b.WriteString(strings.Repeat("─", 80))
 	b.WriteString("\n\n")
 
 	// Results
-	if m.searchResults == nil {
+	if m.pickingSavedSearch() {
+		b.WriteString(m.viewSavedSearchPicker())
+	} else if m.searchErr != nil {
+		b.WriteString(searchMetaStyle.Render(m.searchErr.Error()))
+	} else if m.searchResults == nil {
 		b.WriteString(searchMetaStyle.Render("Type to search (minimum 2 characters)"))
 	} else if len(m.searchResults) == 0 {
 		b.WriteString(searchMetaStyle.Render("No results found"))
 	} else {
-		b.WriteString(fmt.Sprintf(searchMetaStyle.Render("Found %d sessions:"), len(m.searchResults)))
+		b.WriteString(fmt.Sprintf(searchMetaStyle.Render("Found %d sessions:"), m.searchTotal))
 		b.WriteString("\n\n")
 
 		// Calculate max results based on screen height
@@ -104,7 +231,7 @@ func (m Model) viewSearch() string {
 			// Session header
 			summary := result.Summary
 			if summary == "" && len(result.Matches) > 0 {
-				summary = firstLine(result.Matches[0].Snippet, 60)
+				summary = firstLine(search.StripHighlights(result.Matches[0].Snippet), 60)
 			}
 			if summary == "" {
 				summary = "[No summary]"
@@ -120,19 +247,22 @@ func (m Model) viewSearch() string {
 			}
 
 			// Session header with match count and updated time
-			matchCount := fmt.Sprintf("(%d %s)", len(result.Matches),
-				map[bool]string{true: "match", false: "matches"}[len(result.Matches) == 1])
+			matchCount := fmt.Sprintf("(%d %s)", result.MatchCount,
+				map[bool]string{true: "match", false: "matches"}[result.MatchCount == 1])
 			updatedTime := formatTime(result.UpdatedAt)
 			b.WriteString(fmt.Sprintf("%s%s %s | %s\n", prefix, summary,
 				searchMetaStyle.Render(matchCount), searchMetaStyle.Render(updatedTime)))
 			b.WriteString(fmt.Sprintf("  %s\n", searchMetaStyle.Render(result.Project)))
 
-			// Show each match with clear separation
-			query := m.searchInput.Value()
+			// Show each match with clear separation, highlighting the
+			// tokens that matched
 			for j, match := range result.Matches {
-				snippet := highlightQuery(match.Snippet, query)
 				// Show full snippet (100 chars max to match core extraction)
-				snippetLine := firstLine(snippet, 100)
+				snippetLine := search.Highlight(firstLine(match.Snippet, 100), renderMatch)
+				if match.Tool != "" {
+					// Matched in a tool call's input or output
+					snippetLine = searchMetaStyle.Render("["+match.Tool+"]") + " " + snippetLine
+				}
 				b.WriteString(fmt.Sprintf("    %s", snippetLine))
 
 				if j < len(result.Matches)-1 {
@@ -146,42 +276,62 @@ func (m Model) viewSearch() string {
 		if startIdx > 0 {
 			b.WriteString(searchMetaStyle.Render(fmt.Sprintf("... %d results above\n", startIdx)))
 		}
-		if endIdx < len(m.searchResults) {
-			b.WriteString(searchMetaStyle.Render(fmt.Sprintf("... %d results below\n", len(m.searchResults)-endIdx)))
+		if endIdx < m.searchTotal {
+			b.WriteString(searchMetaStyle.Render(fmt.Sprintf("... %d results below\n", m.searchTotal-endIdx)))
 		}
 	}
 
 	// Footer with comprehensive help
 	b.WriteString("\n\n")
-	if len(m.searchResults) > 0 {
-		b.WriteString("Ctrl+j/k or ↑↓: navigate | Enter: open session | esc: back to list | ?: help")
+	if m.pickingSavedSearch() {
+		b.WriteString("↑↓: select | Enter/Tab: run saved search | esc: back to list | ?: help")
+	} else if len(m.searchResults) > 0 {
+		b.WriteString("Ctrl+j/k or ↑↓: navigate (↑ at top: history) | Enter: open session | ctrl+r: regex | esc: back to list | ?: help")
 	} else {
-		b.WriteString("Type to search (min 2 chars) | esc: back to list | ?: help")
+		b.WriteString("Type to search (min 2 chars) | ↑: history | @: saved searches | ctrl+r: regex | esc: back to list | ?: help")
 	}
 	b.WriteString("\n")
-	b.WriteString(searchMetaStyle.Render("Filters: project:path | after:yesterday | after:3-days-ago | before:2024-11-01"))
+	switch m.searchMode {
+	case "regex":
+		b.WriteString(searchMetaStyle.Render("Go regular expression, case-sensitive: (?i) to ignore case | ctrl+r: back to query syntax"))
+	case "semantic", "hybrid":
+		b.WriteString(searchMetaStyle.Render("Matching by meaning, with field filters (project: branch: ...) | ctrl+r: back to keyword search"))
+	default:
+		b.WriteString(searchMetaStyle.Render(`Syntax: "exact phrase" | a OR b | a -b | prefix* | (a OR b) c`))
+		b.WriteString("\n")
+		b.WriteString(searchMetaStyle.Render("Filters: project: branch: role:user tool:Bash file: issue: model: code: after:yesterday before:2024-11-01"))
+	}
 
 	return b.String()
 }
 
-func highlightQuery(text, query string) string {
-	if query == "" {
-		return text
+// viewSavedSearchPicker lists the saved searches matching the @name typed
+func (m Model) viewSavedSearchPicker() string {
+	picks := m.savedSearchPicks()
+	if len(picks) == 0 {
+		if len(m.savedSearches) == 0 {
+			return searchMetaStyle.Render("No saved searches (save one with ccrider search <query> --save NAME)")
+		}
+		return searchMetaStyle.Render("No saved search starts with " + m.searchInput.Value())
 	}
 
-	// Simple case-insensitive highlighting
-	lower := strings.ToLower(text)
-	lowerQuery := strings.ToLower(query)
-
-	idx := strings.Index(lower, lowerQuery)
-	if idx == -1 {
-		return text
+	var b strings.Builder
+	b.WriteString(searchMetaStyle.Render("Saved searches:"))
+	b.WriteString("\n\n")
+	for i, s := range picks {
+		name := "@" + s.Name
+		prefix := "  "
+		if i == m.searchPickerIdx {
+			prefix = "► "
+			name = searchSelectedStyle.Render(name)
+		} else {
+			name = searchMatchStyle.Render(name)
+		}
+		query := s.Query
+		if s.Mode != "" {
+			query += " (" + s.Mode + ")"
+		}
+		b.WriteString(fmt.Sprintf("%s%s  %s\n", prefix, name, searchMetaStyle.Render(query)))
 	}
-
-	// Highlight the match
-	before := text[:idx]
-	match := text[idx : idx+len(query)]
-	after := text[idx+len(query):]
-
-	return before + searchMatchStyle.Render(match) + after
+	return b.String()
 }
@@ -34,9 +34,23 @@ var (
 			Foreground(lipgloss.Color("yellow")).
 			Bold(true)
 
+	toolStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("240")).
+			Italic(true)
+
 	timestampStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("246")) // Lighter gray that works better in dark terminals
 
+	branchStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("208")) // Orange marker for abandoned branches
+
+	subagentStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("105")) // Purple gutter for subagent transcripts
+
+	detailLabelStyle = lipgloss.NewStyle().
+				Bold(true).
+				Foreground(lipgloss.Color("75")) // Blue labels for the structured summary
+
 	// Search view styles
 	searchHeaderStyle = lipgloss.NewStyle().
 				Bold(true).
@@ -2,10 +2,13 @@ package ccsessions
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 )
 
@@ -18,6 +21,28 @@ type ParsedSession struct {
 	FilePath  string
 	FileSize  int64
 	FileMtime time.Time
+
+	// Byte range of the file covered by this parse. For a full parse
+	// StartOffset is 0; EndOffset is where the next incremental parse should
+	// resume, and LineCount the number of lines before it.
+	StartOffset int64
+	EndOffset   int64
+	LineCount   int
+
+	// Lines that were skipped because they couldn't be parsed
+	Diagnostics []ParseDiagnostic
+}
+
+// ParseDiagnostic describes a malformed line the parser skipped, e.g. one
+// truncated when Claude Code crashed mid-write
+type ParseDiagnostic struct {
+	Line   int   // 1-based line number
+	Offset int64 // Byte offset of the start of the line
+	Err    error
+}
+
+func (d ParseDiagnostic) String() string {
+	return fmt.Sprintf("line %d (byte %d): %v", d.Line, d.Offset, d.Err)
 }
 
 // ParsedMessage represents a parsed JSONL message entry
@@ -31,29 +56,88 @@ type ParsedMessage struct {
 	Timestamp   time.Time
 	Sequence    int
 	IsSidechain bool
+	AgentID     string // Sidechain messages: the subagent run they belong to
 	CWD         string
 	GitBranch   string
 	Version     string
+	ToolUses    []ParsedToolUse    // tool_use blocks (assistant messages)
+	ToolResults []ParsedToolResult // tool_result blocks (user messages)
+
+	// Assistant messages only. Claude Code splits one API response into an
+	// entry per content block, each repeating the response's ID and usage.
+	Model        string
+	StopReason   string
+	APIMessageID string
+	Usage        TokenUsage
+}
+
+// TokenUsage is the API token usage reported with an assistant response
+type TokenUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+}
+
+// IsToolOnly reports whether the message consists solely of tool_use or
+// tool_result blocks, with no text of its own
+func (m *ParsedMessage) IsToolOnly() bool {
+	if strings.TrimSpace(m.TextContent) != "" {
+		return false
+	}
+	return len(m.ToolUses) > 0 || len(m.ToolResults) > 0
+}
+
+// ParsedToolUse represents a single tool invocation from a tool_use block
+type ParsedToolUse struct {
+	ID     string
+	Name   string
+	Input  json.RawMessage
+	Output string // Filled from the matching tool_result, if one was found
+}
+
+// ParsedToolResult represents a tool_result block answering a tool_use
+type ParsedToolResult struct {
+	ToolUseID string
+	Output    string
+	AgentID   string // For Task calls, the subagent run that produced the result
 }
 
 // rawEntry represents a raw JSONL line
 type rawEntry struct {
-	Type        string          `json:"type"`
-	Summary     string          `json:"summary,omitempty"`
-	LeafUUID    string          `json:"leafUuid,omitempty"`
-	UUID        string          `json:"uuid,omitempty"`
-	ParentUUID  string          `json:"parentUuid,omitempty"`
-	SessionID   string          `json:"sessionId,omitempty"`
-	Message     json.RawMessage `json:"message,omitempty"`
-	Timestamp   string          `json:"timestamp,omitempty"`
-	IsSidechain bool            `json:"isSidechain,omitempty"`
-	CWD         string          `json:"cwd,omitempty"`
-	GitBranch   string          `json:"gitBranch,omitempty"`
-	Version     string          `json:"version,omitempty"`
+	Type              string          `json:"type"`
+	Summary           string          `json:"summary,omitempty"`
+	LeafUUID          string          `json:"leafUuid,omitempty"`
+	UUID              string          `json:"uuid,omitempty"`
+	ParentUUID        string          `json:"parentUuid,omitempty"`
+	LogicalParentUUID string          `json:"logicalParentUuid,omitempty"`
+	SessionID         string          `json:"sessionId,omitempty"`
+	Message           json.RawMessage `json:"message,omitempty"`
+	Timestamp         string          `json:"timestamp,omitempty"`
+	IsSidechain       bool            `json:"isSidechain,omitempty"`
+	CWD               string          `json:"cwd,omitempty"`
+	GitBranch         string          `json:"gitBranch,omitempty"`
+	Version           string          `json:"version,omitempty"`
+	AgentID           string          `json:"agentId,omitempty"`
+	ToolUseResult     json.RawMessage `json:"toolUseResult,omitempty"`
 }
 
 // ParseFile parses a Claude Code session JSONL file
-func ParseFile(path string) (session *ParsedSession, err error) {
+func ParseFile(path string) (*ParsedSession, error) {
+	return ParseFileFrom(path, 0, 0)
+}
+
+// ParseFileFrom parses a session file starting at a byte offset, so that an
+// incremental sync only reads what was appended since the last import.
+// offset and startLine should come from a previous parse's EndOffset and
+// LineCount; sequence numbers continue from startLine.
+//
+// Malformed lines are skipped and reported in Diagnostics rather than failing
+// the whole file. Only newline-terminated lines are counted as consumed: a
+// trailing line without a newline may still be in the middle of being written,
+// so it is parsed if it is valid, but EndOffset stops before it so the next
+// sync reads it again.
+func ParseFileFrom(path string, offset int64, startLine int) (session *ParsedSession, err error) {
 	file, ferr := os.Open(path)
 	if ferr != nil {
 		return nil, fmt.Errorf("failed to open file: %w", ferr)
@@ -70,65 +154,161 @@ func ParseFile(path string) (session *ParsedSession, err error) {
 		return nil, fmt.Errorf("failed to stat file: %w", err)
 	}
 
+	if offset < 0 || offset > info.Size() {
+		return nil, fmt.Errorf("offset %d out of range for file of size %d", offset, info.Size())
+	}
+	if offset > 0 {
+		if _, err := file.Seek(offset, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("failed to seek to offset %d: %w", offset, err)
+		}
+	}
+
 	// Initialize session with filename-based ID
 	sessionID := filepath.Base(path)
 	sessionID = sessionID[:len(sessionID)-len(filepath.Ext(sessionID))]
 
 	session = &ParsedSession{
-		SessionID: sessionID,
-		FilePath:  path,
-		FileSize:  info.Size(),
-		FileMtime: info.ModTime(),
-		Messages:  make([]ParsedMessage, 0),
+		SessionID:   sessionID,
+		FilePath:    path,
+		FileSize:    info.Size(),
+		FileMtime:   info.ModTime(),
+		Messages:    make([]ParsedMessage, 0),
+		StartOffset: offset,
+		EndOffset:   offset,
+		LineCount:   startLine,
 	}
 
-	// Configure scanner with larger buffer for long lines (10MB max)
-	scanner := bufio.NewScanner(file)
-	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+	reader := bufio.NewReaderSize(file, 64*1024)
+	lineNum := startLine
 
-	lineNum := 0
+	for {
+		line, n, complete, readErr := readLine(reader)
+		if readErr != nil && readErr != io.EOF {
+			return nil, fmt.Errorf("error reading file: %w", readErr)
+		}
+		if n == 0 {
+			break
+		}
 
-	for scanner.Scan() {
 		lineNum++
-		line := scanner.Bytes()
+		lineStart := session.EndOffset
 
-		var raw rawEntry
-		if err := json.Unmarshal(line, &raw); err != nil {
-			return nil, fmt.Errorf("line %d: failed to parse JSON: %w", lineNum, err)
+		if complete {
+			session.EndOffset += n
+			session.LineCount = lineNum
 		}
 
-		// Handle summary if present (may not be first line, or may not exist)
-		if raw.Type == "summary" {
-			session.Summary = raw.Summary
-			session.LeafUUID = raw.LeafUUID
-			// Extract sessionId from summary if available
-			if raw.SessionID != "" {
-				session.SessionID = raw.SessionID
+		err := errLineTooLong
+		if line != nil {
+			err = parseLine(session, sessionID, line, lineNum)
+		}
+		if err != nil {
+			if !complete {
+				// Partially written last line; pick it up on the next sync
+				break
 			}
-			continue
+			session.Diagnostics = append(session.Diagnostics, ParseDiagnostic{
+				Line:   lineNum,
+				Offset: lineStart,
+				Err:    err,
+			})
 		}
 
-		// Extract sessionId from messages if we haven't found it yet
-		if raw.SessionID != "" && session.SessionID == sessionID {
-			session.SessionID = raw.SessionID
+		if readErr == io.EOF {
+			break
 		}
+	}
 
-		// Parse message entries
-		msg, err := parseMessage(&raw, lineNum)
-		if err != nil {
-			// Log warning but don't fail - some message types we may not support yet
-			fmt.Fprintf(os.Stderr, "Warning: %s line %d: %v\n", path, lineNum, err)
+	pairToolResults(session.Messages)
+
+	return session, nil
+}
+
+// maxLineSize is the longest JSONL line parsed; longer ones are skipped.
+const maxLineSize = 10 * 1024 * 1024
+
+var errLineTooLong = fmt.Errorf("line longer than %d bytes", maxLineSize)
+
+// readLine reads the next line from r, including its newline. n is the number
+// of bytes consumed and complete reports whether the line ended in a newline.
+// A line over maxLineSize is still consumed but returned as nil.
+func readLine(r *bufio.Reader) (line []byte, n int64, complete bool, err error) {
+	for {
+		chunk, err := r.ReadSlice('\n')
+		n += int64(len(chunk))
+		if n <= maxLineSize {
+			line = append(line, chunk...)
+		} else {
+			line = nil
+		}
+		if err == bufio.ErrBufferFull {
 			continue
 		}
+		complete = len(chunk) > 0 && chunk[len(chunk)-1] == '\n'
+		return line, n, complete, err
+	}
+}
 
-		session.Messages = append(session.Messages, *msg)
+// parseLine parses a single JSONL line into session. defaultID is the
+// filename-based session ID, which the first sessionId seen replaces. An error
+// means the line was skipped.
+func parseLine(session *ParsedSession, defaultID string, line []byte, lineNum int) error {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return nil
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading file: %w", err)
+	var raw rawEntry
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return fmt.Errorf("failed to parse JSON: %w", err)
 	}
 
-	return session, nil
+	// Handle summary if present (may not be first line, or may not exist)
+	if raw.Type == "summary" {
+		session.Summary = raw.Summary
+		session.LeafUUID = raw.LeafUUID
+		// Extract sessionId from summary if available
+		if raw.SessionID != "" {
+			session.SessionID = raw.SessionID
+		}
+		return nil
+	}
+
+	// Extract sessionId from messages if we haven't found it yet
+	if raw.SessionID != "" && session.SessionID == defaultID {
+		session.SessionID = raw.SessionID
+	}
+
+	// Parse message entries
+	msg, err := parseMessage(&raw, lineNum)
+	if err != nil {
+		return err
+	}
+
+	session.Messages = append(session.Messages, *msg)
+	return nil
+}
+
+// pairToolResults fills in ParsedToolUse.Output from the tool_result blocks
+// that reference each tool_use by ID
+func pairToolResults(messages []ParsedMessage) {
+	outputs := make(map[string]string)
+	for _, msg := range messages {
+		for _, result := range msg.ToolResults {
+			outputs[result.ToolUseID] = result.Output
+		}
+	}
+	if len(outputs) == 0 {
+		return
+	}
+
+	for i := range messages {
+		for j := range messages[i].ToolUses {
+			if output, ok := outputs[messages[i].ToolUses[j].ID]; ok {
+				messages[i].ToolUses[j].Output = output
+			}
+		}
+	}
 }
 
 func parseMessage(raw *rawEntry, sequence int) (*ParsedMessage, error) {
@@ -138,12 +318,19 @@ func parseMessage(raw *rawEntry, sequence int) (*ParsedMessage, error) {
 		Type:        raw.Type,
 		Sequence:    sequence,
 		IsSidechain: raw.IsSidechain,
+		AgentID:     raw.AgentID,
 		CWD:         raw.CWD,
 		GitBranch:   raw.GitBranch,
 		Version:     raw.Version,
 		Content:     raw.Message,
 	}
 
+	// Compact boundaries start a new chain but point back at the
+	// conversation they continue from
+	if msg.ParentUUID == "" {
+		msg.ParentUUID = raw.LogicalParentUUID
+	}
+
 	// Parse timestamp
 	if raw.Timestamp != "" {
 		t, err := time.Parse(time.RFC3339, raw.Timestamp)
@@ -160,30 +347,52 @@ func parseMessage(raw *rawEntry, sequence int) (*ParsedMessage, error) {
 		var userMsgArray struct {
 			Role    string `json:"role"`
 			Content []struct {
-				Type    string `json:"type"`
-				Text    string `json:"text,omitempty"`
-				Content []struct {
-					Type string `json:"type"`
-					Text string `json:"text,omitempty"`
-				} `json:"content,omitempty"` // For tool_result blocks
+				Type      string          `json:"type"`
+				Text      string          `json:"text,omitempty"`
+				ToolUseID string          `json:"tool_use_id,omitempty"` // For tool_result blocks
+				Content   json.RawMessage `json:"content,omitempty"`     // For tool_result blocks (string or array)
 			} `json:"content"`
 		}
 		if err := json.Unmarshal(raw.Message, &userMsgArray); err == nil {
-			// Extract text from text blocks and tool_result blocks
+			// Extract text from text blocks. Tool results are kept as tool
+			// output only (indexed and shown with the tool that produced them).
 			for _, block := range userMsgArray.Content {
 				switch block.Type {
 				case "text":
 					msg.TextContent += block.Text + "\n"
 				case "tool_result":
-					// Extract text from nested content in tool_result
-					for _, nested := range block.Content {
-						if nested.Type == "text" && nested.Text != "" {
-							msg.TextContent += nested.Text + "\n"
+					// Nested content is an array of blocks or a plain string
+					var nestedBlocks []struct {
+						Type string `json:"type"`
+						Text string `json:"text,omitempty"`
+					}
+					var output string
+					if err := json.Unmarshal(block.Content, &nestedBlocks); err == nil {
+						for _, nested := range nestedBlocks {
+							if nested.Type == "text" && nested.Text != "" {
+								output += nested.Text + "\n"
+							}
 						}
+					} else {
+						_ = json.Unmarshal(block.Content, &output)
 					}
+					msg.ToolResults = append(msg.ToolResults, ParsedToolResult{
+						ToolUseID: block.ToolUseID,
+						Output:    output,
+					})
 				}
 			}
 			msg.Sender = "human"
+
+			// A Task call's result names the subagent run it came from
+			if len(msg.ToolResults) == 1 {
+				var toolUseResult struct {
+					AgentID string `json:"agentId"`
+				}
+				if err := json.Unmarshal(raw.ToolUseResult, &toolUseResult); err == nil {
+					msg.ToolResults[0].AgentID = toolUseResult.AgentID
+				}
+			}
 		} else {
 			// Fall back to string format (older format)
 			var userMsgString struct {
@@ -198,18 +407,36 @@ func parseMessage(raw *rawEntry, sequence int) (*ParsedMessage, error) {
 
 	case "assistant":
 		var assistantMsg struct {
-			Content []struct {
-				Type string `json:"type"`
-				Text string `json:"text,omitempty"`
+			ID         string     `json:"id"`
+			Model      string     `json:"model"`
+			StopReason string     `json:"stop_reason"`
+			Usage      TokenUsage `json:"usage"`
+			Content    []struct {
+				Type  string          `json:"type"`
+				Text  string          `json:"text,omitempty"`
+				ID    string          `json:"id,omitempty"`    // For tool_use blocks
+				Name  string          `json:"name,omitempty"`  // For tool_use blocks
+				Input json.RawMessage `json:"input,omitempty"` // For tool_use blocks
 			} `json:"content"`
 		}
 		if err := json.Unmarshal(raw.Message, &assistantMsg); err == nil {
 			for _, block := range assistantMsg.Content {
-				if block.Type == "text" {
+				switch block.Type {
+				case "text":
 					msg.TextContent += block.Text + "\n"
+				case "tool_use":
+					msg.ToolUses = append(msg.ToolUses, ParsedToolUse{
+						ID:    block.ID,
+						Name:  block.Name,
+						Input: block.Input,
+					})
 				}
 			}
 			msg.Sender = "assistant"
+			msg.Model = assistantMsg.Model
+			msg.StopReason = assistantMsg.StopReason
+			msg.APIMessageID = assistantMsg.ID
+			msg.Usage = assistantMsg.Usage
 		}
 
 	case "system", "file-history-snapshot", "queue-operation":
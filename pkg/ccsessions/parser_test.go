@@ -1,6 +1,9 @@
 package ccsessions
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -76,3 +79,274 @@ func TestParseFile_NoSummary(t *testing.T) {
 		t.Errorf("Message count = %v, want 2", len(session.Messages))
 	}
 }
+
+func TestParseFile_ToolUses(t *testing.T) {
+	session, err := ParseFile("testdata/tool-session.jsonl")
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	if len(session.Messages) != 7 {
+		t.Fatalf("Message count = %v, want 7", len(session.Messages))
+	}
+
+	// Assistant message with text + tool_use
+	bash := session.Messages[1]
+	if len(bash.ToolUses) != 1 {
+		t.Fatalf("Expected 1 tool use on message 2, got %d", len(bash.ToolUses))
+	}
+	if bash.ToolUses[0].Name != "Bash" || bash.ToolUses[0].ID != "toolu_01" {
+		t.Errorf("Tool use = %s/%s, want Bash/toolu_01", bash.ToolUses[0].Name, bash.ToolUses[0].ID)
+	}
+	if !strings.Contains(string(bash.ToolUses[0].Input), "mix ecto.migrate") {
+		t.Errorf("Tool input = %s, want it to contain the command", bash.ToolUses[0].Input)
+	}
+
+	// String-form tool_result is paired by tool_use_id
+	if bash.ToolUses[0].Output != "Migrated 20251108_add_accounts in 0.1s" {
+		t.Errorf("Tool output = %q", bash.ToolUses[0].Output)
+	}
+
+	// Array-form tool_result is paired too
+	read := session.Messages[3]
+	if len(read.ToolUses) != 1 {
+		t.Fatalf("Expected 1 tool use on message 4, got %d", len(read.ToolUses))
+	}
+	if !strings.Contains(read.ToolUses[0].Output, "defmodule Repo.Migrations.AddAccounts") {
+		t.Errorf("Tool output = %q", read.ToolUses[0].Output)
+	}
+	if text := session.Messages[4].TextContent; text != "" {
+		t.Errorf("Array-form tool output copied into message text: %q", text)
+	}
+
+	// Tool result messages record which tool_use they answer
+	if len(session.Messages[2].ToolResults) != 1 || session.Messages[2].ToolResults[0].ToolUseID != "toolu_01" {
+		t.Errorf("Expected tool result for toolu_01 on message 3, got %+v", session.Messages[2].ToolResults)
+	}
+
+	// Tool-only detection: tool results (string or array form) and bare
+	// tool_use have no text
+	wantToolOnly := []bool{false, false, true, true, true, false, false}
+	for i, msg := range session.Messages {
+		if msg.IsToolOnly() != wantToolOnly[i] {
+			t.Errorf("Message %d IsToolOnly() = %v, want %v", i+1, msg.IsToolOnly(), wantToolOnly[i])
+		}
+	}
+}
+
+func TestParseFileFrom(t *testing.T) {
+	data, err := os.ReadFile("testdata/tool-session.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+
+	// First three lines complete, fourth still being written
+	path := filepath.Join(t.TempDir(), "tool-session-789.jsonl")
+	head := strings.Join(lines[:3], "")
+	partial := lines[3][:40]
+	if err := os.WriteFile(path, []byte(head+partial), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	if len(first.Messages) != 2 {
+		t.Errorf("first parse messages = %d, want 2", len(first.Messages))
+	}
+	if first.EndOffset != int64(len(head)) {
+		t.Errorf("EndOffset = %d, want %d (before the partial line)", first.EndOffset, len(head))
+	}
+	if first.LineCount != 3 {
+		t.Errorf("LineCount = %d, want 3", first.LineCount)
+	}
+
+	// Finish writing the file and resume where the first parse stopped
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rest, err := ParseFileFrom(path, first.EndOffset, first.LineCount)
+	if err != nil {
+		t.Fatalf("ParseFileFrom() error = %v", err)
+	}
+	if len(rest.Messages) != 5 {
+		t.Fatalf("resumed parse messages = %d, want 5", len(rest.Messages))
+	}
+	if rest.Messages[0].UUID != "msg-3" || rest.Messages[0].Sequence != 4 {
+		t.Errorf("first resumed message = %s seq %d, want msg-3 seq 4",
+			rest.Messages[0].UUID, rest.Messages[0].Sequence)
+	}
+	if rest.SessionID != "tool-session-789" {
+		t.Errorf("SessionID = %s, want tool-session-789", rest.SessionID)
+	}
+	if rest.EndOffset != int64(len(data)) {
+		t.Errorf("EndOffset = %d, want %d", rest.EndOffset, len(data))
+	}
+
+	// Sequence numbers match a full parse
+	full, err := ParseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, msg := range rest.Messages {
+		want := full.Messages[i+2]
+		if msg.UUID != want.UUID || msg.Sequence != want.Sequence {
+			t.Errorf("resumed message %d = %s/%d, full parse has %s/%d",
+				i, msg.UUID, msg.Sequence, want.UUID, want.Sequence)
+		}
+	}
+
+	if _, err := ParseFileFrom(path, int64(len(data))+1, 0); err == nil {
+		t.Error("ParseFileFrom() should fail for an offset past the end of the file")
+	}
+}
+
+func TestParseFile_Corrupt(t *testing.T) {
+	session, err := ParseFile("testdata/corrupt-session.jsonl")
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	// The truncated line and the bad timestamp are skipped, the rest kept
+	if len(session.Messages) != 2 {
+		t.Fatalf("Message count = %d, want 2", len(session.Messages))
+	}
+	if session.Messages[1].UUID != "msg-4" {
+		t.Errorf("Last message = %s, want msg-4", session.Messages[1].UUID)
+	}
+
+	if len(session.Diagnostics) != 2 {
+		t.Fatalf("Diagnostics = %d, want 2", len(session.Diagnostics))
+	}
+
+	data, err := os.ReadFile("testdata/corrupt-session.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	wantOffset := int64(len(lines[0]) + len(lines[1]))
+
+	truncated := session.Diagnostics[0]
+	if truncated.Line != 3 || truncated.Offset != wantOffset {
+		t.Errorf("First diagnostic at line %d byte %d, want line 3 byte %d",
+			truncated.Line, truncated.Offset, wantOffset)
+	}
+	if !strings.Contains(truncated.Err.Error(), "JSON") {
+		t.Errorf("First diagnostic error = %v, want a JSON error", truncated.Err)
+	}
+	if session.Diagnostics[1].Line != 4 {
+		t.Errorf("Second diagnostic at line %d, want 4", session.Diagnostics[1].Line)
+	}
+}
+
+func TestParseFile_LongLine(t *testing.T) {
+	data, err := os.ReadFile("testdata/sample.jsonl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	long := `{"type":"user","message":{"role":"user","content":"` + strings.Repeat("x", maxLineSize) + `"}}` + "\n"
+
+	// A line over the limit between two good ones is skipped
+	path := filepath.Join(t.TempDir(), "long.jsonl")
+	content := lines[0] + lines[1] + long + strings.Join(lines[2:], "")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	if len(session.Messages) != 2 {
+		t.Errorf("Message count = %d, want 2", len(session.Messages))
+	}
+	if len(session.Diagnostics) != 1 {
+		t.Fatalf("Diagnostics = %d, want 1", len(session.Diagnostics))
+	}
+	if diag := session.Diagnostics[0]; diag.Line != 3 || diag.Offset != int64(len(lines[0])+len(lines[1])) {
+		t.Errorf("Diagnostic at line %d byte %d, want line 3 byte %d",
+			diag.Line, diag.Offset, len(lines[0])+len(lines[1]))
+	}
+	if session.EndOffset != int64(len(content)) {
+		t.Errorf("EndOffset = %d, want %d", session.EndOffset, len(content))
+	}
+}
+
+func TestParseFile_Usage(t *testing.T) {
+	session, err := ParseFile("testdata/usage-session.jsonl")
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	if len(session.Messages) != 5 {
+		t.Fatalf("Message count = %v, want 5", len(session.Messages))
+	}
+
+	msg := session.Messages[2]
+	if msg.Model != "claude-sonnet-4-5-20250929" || msg.APIMessageID != "resp-1" || msg.StopReason != "tool_use" {
+		t.Errorf("Model/ID/StopReason = %q/%q/%q", msg.Model, msg.APIMessageID, msg.StopReason)
+	}
+	want := TokenUsage{InputTokens: 10, OutputTokens: 5, CacheReadInputTokens: 1000, CacheCreationInputTokens: 200}
+	if msg.Usage != want {
+		t.Errorf("Usage = %+v, want %+v", msg.Usage, want)
+	}
+
+	// User messages carry no usage
+	if session.Messages[0].Model != "" || session.Messages[0].Usage != (TokenUsage{}) {
+		t.Errorf("Expected no model or usage on user message, got %q %+v", session.Messages[0].Model, session.Messages[0].Usage)
+	}
+}
+
+func TestParseFile_CompactBoundary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "compacted.jsonl")
+	data := `{"parentUuid":null,"type":"user","message":{"role":"user","content":"before"},"uuid":"u-1","timestamp":"2025-11-08T12:00:00Z","sessionId":"compacted"}
+{"parentUuid":null,"logicalParentUuid":"u-1","type":"system","subtype":"compact_boundary","content":"Conversation compacted","uuid":"c-1","timestamp":"2025-11-08T12:01:00Z","sessionId":"compacted"}
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := ParseFile(path)
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	if len(session.Messages) != 2 {
+		t.Fatalf("Message count = %v, want 2", len(session.Messages))
+	}
+
+	// The boundary links back to the conversation it continues
+	if session.Messages[1].ParentUUID != "u-1" {
+		t.Errorf("ParentUUID = %q, want u-1", session.Messages[1].ParentUUID)
+	}
+}
+
+func TestParseFile_TaskResult(t *testing.T) {
+	session, err := ParseFile("testdata/task-session.jsonl")
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+
+	// u-2 answers the first Task call, which names its subagent run;
+	// u-3 answers the second in the older format, which doesn't
+	var agentIDs []string
+	for _, msg := range session.Messages {
+		for _, result := range msg.ToolResults {
+			agentIDs = append(agentIDs, result.ToolUseID+"="+result.AgentID)
+		}
+	}
+	if len(agentIDs) != 2 || agentIDs[0] != "toolu_task1=agent01" || agentIDs[1] != "toolu_task2=" {
+		t.Errorf("Tool results = %v, want [toolu_task1=agent01 toolu_task2=]", agentIDs)
+	}
+
+	agent, err := ParseFile("testdata/agent-task.jsonl")
+	if err != nil {
+		t.Fatalf("ParseFile() error = %v", err)
+	}
+	if agent.Messages[0].AgentID != "agent01" || !agent.Messages[0].IsSidechain {
+		t.Errorf("First agent message = %+v, want sidechain of agent01", agent.Messages[0])
+	}
+}